@@ -0,0 +1,117 @@
+package appstore
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/DotNetAge/appstore/models"
+)
+
+// AdvancedCommerceClient manages purchases made through the Advanced Commerce API, for apps with catalogs too large
+// for App Store Connect products. Its server-side operations authenticate, retry, rate limit and report errors like
+// the AppStoreServerAPIClient it is built on. One-time charges, new subscriptions and subscription modifications
+// start in your app instead: sign them with SignInAppRequest and pass the result to StoreKit.
+// https://developer.apple.com/documentation/advancedcommerceapi
+type AdvancedCommerceClient struct {
+	client *AppStoreServerAPIClient
+}
+
+// NewAdvancedCommerceClient creates an AdvancedCommerceClient with the same credentials and options as an AppStoreServerAPIClient
+func NewAdvancedCommerceClient(signingKey []byte, keyID, issuerID, bundleID string, environment models.Environment, options ...ClientOption) (*AdvancedCommerceClient, error) {
+	client, err := NewAppStoreServerAPIClientWithOptions(signingKey, keyID, issuerID, bundleID, environment, options...)
+	if err != nil {
+		return nil, err
+	}
+	return client.AdvancedCommerce(), nil
+}
+
+// AdvancedCommerce returns an AdvancedCommerceClient that shares the client's keys, rate limiter and interceptors
+func (c *AppStoreServerAPIClient) AdvancedCommerce() *AdvancedCommerceClient {
+	return &AdvancedCommerceClient{client: c}
+}
+
+// SignInAppRequest validates a one-time charge, subscription creation or subscription modification and signs it
+// with the client's active key, for your app to pass to StoreKit.
+// https://developer.apple.com/documentation/advancedcommerceapi/generating-jws-to-sign-app-store-requests
+func (c *AdvancedCommerceClient) SignInAppRequest(request models.AdvancedCommerceInAppRequest) (string, error) {
+	key, _, _, err := c.client.keys.current()
+	if err != nil {
+		return "", err
+	}
+	return signAdvancedCommerceInAppRequest(key, c.client.issuerID, c.client.bundleID, request)
+}
+
+// CancelSubscription turns off the automatic renewal of a subscription; it stays active until the period ends.
+// https://developer.apple.com/documentation/advancedcommerceapi/cancel-a-subscription
+func (c *AdvancedCommerceClient) CancelSubscription(ctx context.Context, transactionID string, request *models.SubscriptionCancelRequest) (*models.SubscriptionCancelResponse, error) {
+	path, err := transactionPath("/advancedCommerce/v1/subscription/cancel/", transactionID)
+	if err != nil {
+		return nil, err
+	}
+	var response models.SubscriptionCancelResponse
+	if err := c.client.makeRequest(ctx, EndpointCancelSubscription, path, "POST", url.Values{}, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// ChangeSubscriptionMetadata changes the names, descriptions, SKUs or tax code of a subscription without changing its price.
+// https://developer.apple.com/documentation/advancedcommerceapi/change-subscription-metadata
+func (c *AdvancedCommerceClient) ChangeSubscriptionMetadata(ctx context.Context, transactionID string, request *models.SubscriptionChangeMetadataRequest) (*models.SubscriptionChangeMetadataResponse, error) {
+	path, err := transactionPath("/advancedCommerce/v1/subscription/changeMetadata/", transactionID)
+	if err != nil {
+		return nil, err
+	}
+	var response models.SubscriptionChangeMetadataResponse
+	if err := c.client.makeRequest(ctx, EndpointChangeSubscriptionMetadata, path, "POST", url.Values{}, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// ChangeSubscriptionPrice changes the prices of the items of a subscription from its next renewal.
+// https://developer.apple.com/documentation/advancedcommerceapi/change-subscription-price
+func (c *AdvancedCommerceClient) ChangeSubscriptionPrice(ctx context.Context, transactionID string, request *models.SubscriptionPriceChangeRequest) (*models.SubscriptionPriceChangeResponse, error) {
+	path, err := transactionPath("/advancedCommerce/v1/subscription/changePrice/", transactionID)
+	if err != nil {
+		return nil, err
+	}
+	var response models.SubscriptionPriceChangeResponse
+	if err := c.client.makeRequest(ctx, EndpointChangeSubscriptionPrice, path, "POST", url.Values{}, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// MigrateSubscription moves a subscription from an auto-renewable subscription product onto the Advanced Commerce API.
+// https://developer.apple.com/documentation/advancedcommerceapi/migrate-a-subscription-to-advanced-commerce-api
+func (c *AdvancedCommerceClient) MigrateSubscription(ctx context.Context, transactionID string, request *models.SubscriptionMigrateRequest) (*models.SubscriptionMigrateResponse, error) {
+	path, err := transactionPath("/advancedCommerce/v1/subscription/migrate/", transactionID)
+	if err != nil {
+		return nil, err
+	}
+	var response models.SubscriptionMigrateResponse
+	if err := c.client.makeRequest(ctx, EndpointMigrateSubscription, path, "POST", url.Values{}, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// RequestRefund refunds all or part of a one-time charge or subscription transaction.
+// https://developer.apple.com/documentation/advancedcommerceapi/request-a-refund
+func (c *AdvancedCommerceClient) RequestRefund(ctx context.Context, transactionID string, request *models.RequestRefundRequest) (*models.RequestRefundResponse, error) {
+	path, err := transactionPath("/advancedCommerce/v1/transaction/requestRefund/", transactionID)
+	if err != nil {
+		return nil, err
+	}
+	var response models.RequestRefundResponse
+	if err := c.client.makeRequest(ctx, EndpointRequestRefund, path, "POST", url.Values{}, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// AdvancedCommerce returns an AdvancedCommerceClient that shares the client's keys, rate limiter and interceptors
+func (c *AppStoreServerClient) AdvancedCommerce() *AdvancedCommerceClient {
+	return c.client.AdvancedCommerce()
+}
@@ -0,0 +1,103 @@
+package appstore
+
+import (
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/DotNetAge/appstore/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// advancedCommerceAudience is the audience of signed Advanced Commerce API in-app requests
+	advancedCommerceAudience = "advanced-commerce-api"
+	// advancedCommerceRequestVersion is the version of the in-app request format
+	advancedCommerceRequestVersion = "1"
+)
+
+// AdvancedCommerceInAppSignatureCreator signs the Advanced Commerce API requests your app passes to StoreKit to
+// charge once, create a subscription or modify one
+// https://developer.apple.com/documentation/advancedcommerceapi/generating-jws-to-sign-app-store-requests
+type AdvancedCommerceInAppSignatureCreator struct {
+	signer   crypto.Signer
+	keyID    string
+	issuerID string
+	bundleID string
+}
+
+// NewAdvancedCommerceInAppSignatureCreator creates a new AdvancedCommerceInAppSignatureCreator
+func NewAdvancedCommerceInAppSignatureCreator(signingKey []byte, keyID, issuerID, bundleID string) (*AdvancedCommerceInAppSignatureCreator, error) {
+	// Parse the PKCS#8 or SEC1 private key
+	privKey, err := LoadSigningKey(signingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAdvancedCommerceInAppSignatureCreatorWithSigner(privKey, keyID, issuerID, bundleID)
+}
+
+// NewAdvancedCommerceInAppSignatureCreatorWithSigner creates a new AdvancedCommerceInAppSignatureCreator that signs with a
+// crypto.Signer, so the private key can stay in a KMS, an HSM or an agent. The signer must hold a P-256 ECDSA key.
+func NewAdvancedCommerceInAppSignatureCreatorWithSigner(signer crypto.Signer, keyID, issuerID, bundleID string) (*AdvancedCommerceInAppSignatureCreator, error) {
+	if err := checkSigner(signer); err != nil {
+		return nil, err
+	}
+
+	return &AdvancedCommerceInAppSignatureCreator{
+		signer:   signer,
+		keyID:    keyID,
+		issuerID: issuerID,
+		bundleID: bundleID,
+	}, nil
+}
+
+// CreateSignature validates the request and returns it as a signed JWS for your app to pass to StoreKit
+func (c *AdvancedCommerceInAppSignatureCreator) CreateSignature(request models.AdvancedCommerceInAppRequest) (string, error) {
+	return signAdvancedCommerceInAppRequest(SigningKey{KeyID: c.keyID, Signer: c.signer}, c.issuerID, c.bundleID, request)
+}
+
+// signAdvancedCommerceInAppRequest signs request with key, tagging it with its operation and the request format version
+func signAdvancedCommerceInAppRequest(key SigningKey, issuerID, bundleID string, request models.AdvancedCommerceInAppRequest) (string, error) {
+	if err := request.Validate(); err != nil {
+		return "", err
+	}
+
+	// The operation and version travel inside the request next to its own fields
+	content, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode %s request: %w", request.Operation(), err)
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(content, &fields); err != nil {
+		return "", fmt.Errorf("failed to encode %s request: %w", request.Operation(), err)
+	}
+	fields["operation"], _ = json.Marshal(request.Operation())
+	fields["version"], _ = json.Marshal(advancedCommerceRequestVersion)
+	content, err = json.Marshal(fields)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode %s request: %w", request.Operation(), err)
+	}
+
+	nonce, err := newRequestIdentifier()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"bid":     bundleID,
+		"iss":     issuerID,
+		"aud":     advancedCommerceAudience,
+		"iat":     time.Now().Unix(),
+		"nonce":   nonce,
+		"request": base64.StdEncoding.EncodeToString(content),
+	})
+	token.Header["kid"] = key.KeyID
+
+	signature, err := signJWS(token, key.Signer)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign %s request: %w", request.Operation(), err)
+	}
+	return signature, nil
+}
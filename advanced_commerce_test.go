@@ -0,0 +1,244 @@
+package appstore
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DotNetAge/appstore/models"
+)
+
+// requestReferenceID is the request reference of the Advanced Commerce requests in these tests
+const requestReferenceID = "11111111-1111-4111-8111-111111111111"
+
+// newOneTimeCharge returns a valid one-time charge request
+func newOneTimeCharge() *models.OneTimeChargeCreateRequest {
+	return &models.OneTimeChargeCreateRequest{
+		RequestInfo: &models.AdvancedCommerceRequestInfo{RequestReferenceId: ptr(requestReferenceID)},
+		Currency:    ptr("USD"),
+		Item:        &models.OneTimeChargeItem{SKU: ptr("sku.gems.100"), Description: ptr("100 gems"), DisplayName: ptr("Gems"), Price: ptr(int64(1990))},
+		TaxCode:     ptr("C003-00-1"),
+	}
+}
+
+func TestAdvancedCommerceInAppRequestClaims(t *testing.T) {
+	signer := newTestSigner(t)
+	creator, err := NewAdvancedCommerceInAppSignatureCreatorWithSigner(signer, "KEY_ID", "ISSUER_ID", "com.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now().Unix()
+	signed, err := creator.CreateSignature(newOneTimeCharge())
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, claims := parseES256(t, signed, &signer.key.PublicKey)
+
+	if parsed.Header["kid"] != "KEY_ID" {
+		t.Errorf("kid = %v, want KEY_ID", parsed.Header["kid"])
+	}
+	for claim, want := range map[string]string{"bid": "com.example", "iss": "ISSUER_ID", "aud": advancedCommerceAudience} {
+		if claims[claim] != want {
+			t.Errorf("%s = %v, want %s", claim, claims[claim], want)
+		}
+	}
+	if iat, ok := claims["iat"].(float64); !ok || int64(iat) < before || int64(iat) > time.Now().Unix() {
+		t.Errorf("iat = %v, want the signing time", claims["iat"])
+	}
+	nonce, _ := claims["nonce"].(string)
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(nonce) {
+		t.Errorf("nonce = %q, want a random UUID", nonce)
+	}
+
+	encoded, _ := claims["request"].(string)
+	content, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("request claim isn't standard base64: %v", err)
+	}
+	var request struct {
+		models.OneTimeChargeCreateRequest
+		Operation string `json:"operation"`
+		Version   string `json:"version"`
+	}
+	if err := json.Unmarshal(content, &request); err != nil {
+		t.Fatal(err)
+	}
+	if request.Operation != "CREATE_ONE_TIME_CHARGE" || request.Version != "1" {
+		t.Errorf("operation %q version %q, want CREATE_ONE_TIME_CHARGE version 1", request.Operation, request.Version)
+	}
+	if *request.RequestInfo.RequestReferenceId != requestReferenceID || *request.Item.SKU != "sku.gems.100" || *request.Item.Price != 1990 {
+		t.Errorf("request fields weren't kept: %s", content)
+	}
+
+	// Every signature gets its own nonce
+	again, err := creator.CreateSignature(newOneTimeCharge())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, claims := parseES256(t, again, &signer.key.PublicKey); claims["nonce"] == nonce {
+		t.Error("two signatures share a nonce")
+	}
+}
+
+func TestAdvancedCommerceInAppRequestOperations(t *testing.T) {
+	tests := []struct {
+		request models.AdvancedCommerceInAppRequest
+		want    string
+	}{
+		{request: &models.OneTimeChargeCreateRequest{}, want: "CREATE_ONE_TIME_CHARGE"},
+		{request: &models.SubscriptionCreateRequest{}, want: "CREATE_SUBSCRIPTION"},
+		{request: &models.SubscriptionModifyInAppRequest{}, want: "MODIFY_SUBSCRIPTION"},
+	}
+	for _, tt := range tests {
+		if got := tt.request.Operation(); got != tt.want {
+			t.Errorf("%T operation = %s, want %s", tt.request, got, tt.want)
+		}
+	}
+}
+
+func TestAdvancedCommerceInAppRequestValidated(t *testing.T) {
+	creator, err := NewAdvancedCommerceInAppSignatureCreatorWithSigner(newTestSigner(t), "KEY_ID", "ISSUER_ID", "com.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := newOneTimeCharge()
+	request.Currency = ptr("usd")
+	if _, err := creator.CreateSignature(request); !errors.Is(err, models.ErrInvalidRequest) {
+		t.Errorf("got %v, want a validation error", err)
+	}
+}
+
+func TestAdvancedCommerceEndpoints(t *testing.T) {
+	requestInfo := &models.AdvancedCommerceRequestInfo{RequestReferenceId: ptr(requestReferenceID)}
+	tests := []struct {
+		name string
+		path string
+		call func(*AdvancedCommerceClient) error
+	}{
+		{
+			name: "CancelSubscription",
+			path: "/advancedCommerce/v1/subscription/cancel/2000000000000001",
+			call: func(c *AdvancedCommerceClient) error {
+				_, err := c.CancelSubscription(context.Background(), "2000000000000001", &models.SubscriptionCancelRequest{RequestInfo: requestInfo})
+				return err
+			},
+		},
+		{
+			name: "ChangeSubscriptionMetadata",
+			path: "/advancedCommerce/v1/subscription/changeMetadata/2000000000000001",
+			call: func(c *AdvancedCommerceClient) error {
+				_, err := c.ChangeSubscriptionMetadata(context.Background(), "2000000000000001", &models.SubscriptionChangeMetadataRequest{
+					RequestInfo: requestInfo,
+					TaxCode:     ptr("C003-00-1"),
+				})
+				return err
+			},
+		},
+		{
+			name: "ChangeSubscriptionPrice",
+			path: "/advancedCommerce/v1/subscription/changePrice/2000000000000001",
+			call: func(c *AdvancedCommerceClient) error {
+				_, err := c.ChangeSubscriptionPrice(context.Background(), "2000000000000001", &models.SubscriptionPriceChangeRequest{
+					RequestInfo: requestInfo,
+					Items:       []models.SubscriptionPriceChangeItem{{SKU: ptr("sku.monthly"), Price: ptr(int64(4990))}},
+				})
+				return err
+			},
+		},
+		{
+			name: "MigrateSubscription",
+			path: "/advancedCommerce/v1/subscription/migrate/2000000000000001",
+			call: func(c *AdvancedCommerceClient) error {
+				_, err := c.MigrateSubscription(context.Background(), "2000000000000001", &models.SubscriptionMigrateRequest{
+					RequestInfo:     requestInfo,
+					Descriptors:     &models.AdvancedCommerceDescriptors{Description: ptr("All the news"), DisplayName: ptr("News")},
+					Items:           []models.SubscriptionMigrateItem{{SKU: ptr("sku.monthly"), Description: ptr("Monthly news"), DisplayName: ptr("Monthly")}},
+					TargetProductId: ptr("com.example.news"),
+					TaxCode:         ptr("C003-00-1"),
+				})
+				return err
+			},
+		},
+		{
+			name: "RequestRefund",
+			path: "/advancedCommerce/v1/transaction/requestRefund/2000000000000001",
+			call: func(c *AdvancedCommerceClient) error {
+				_, err := c.RequestRefund(context.Background(), "2000000000000001", &models.RequestRefundRequest{
+					RequestInfo: requestInfo,
+					Items: []models.RequestRefundItem{{
+						SKU:          ptr("sku.gems.100"),
+						RefundReason: ptr(models.AdvancedCommerceRefundReasonUnintendedPurchase),
+						RefundType:   ptr(models.AdvancedCommerceRefundTypeFull),
+						Revoke:       ptr(true),
+					}},
+					RefundRiskingPreference: ptr(false),
+				})
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var method, path string
+			var body map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				method, path = r.Method, r.URL.Path
+				content, _ := io.ReadAll(r.Body)
+				_ = json.Unmarshal(content, &body)
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"signedTransactionInfo":"signed"}`))
+			}))
+			defer server.Close()
+
+			_, key := newTestSigningKey(t)
+			client, err := NewAdvancedCommerceClient(key, "KEY_ID", "ISSUER_ID", "com.example", models.EnvironmentSandbox,
+				WithBaseURL(server.URL), WithRateLimiter(nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.call(client); err != nil {
+				t.Fatal(err)
+			}
+			if method != http.MethodPost || path != tt.path {
+				t.Errorf("sent %s %s, want POST %s", method, path, tt.path)
+			}
+			if info, _ := body["requestInfo"].(map[string]interface{}); info["requestReferenceId"] != requestReferenceID {
+				t.Errorf("body %v doesn't carry the request info", body)
+			}
+		})
+	}
+}
+
+func TestAdvancedCommerceEndpointsRejectInvalidInput(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	_, key := newTestSigningKey(t)
+	client, err := NewAdvancedCommerceClient(key, "KEY_ID", "ISSUER_ID", "com.example", models.EnvironmentSandbox,
+		WithBaseURL(server.URL), WithRateLimiter(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := &models.SubscriptionCancelRequest{RequestInfo: &models.AdvancedCommerceRequestInfo{RequestReferenceId: ptr(requestReferenceID)}}
+	if _, err := client.CancelSubscription(context.Background(), "../1", request); err == nil {
+		t.Error("accepted a malformed transaction identifier")
+	}
+	if _, err := client.CancelSubscription(context.Background(), "2000000000000001", &models.SubscriptionCancelRequest{}); !errors.Is(err, models.ErrInvalidRequest) {
+		t.Errorf("got %v, want a validation error", err)
+	}
+	if requests != 0 {
+		t.Errorf("sent %d invalid requests", requests)
+	}
+}
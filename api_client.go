@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"time"
@@ -24,68 +25,119 @@ const (
 	GetTransactionHistoryVersionV2 GetTransactionHistoryVersion = "v2"
 )
 
+// SendConsumptionDataVersion represents the version of the Send Consumption Information endpoint to use
+type SendConsumptionDataVersion string
+
+const (
+	// SendConsumptionDataVersionV1 represents version 1 of the Send Consumption Information endpoint, which takes a *models.ConsumptionRequest
+	SendConsumptionDataVersionV1 SendConsumptionDataVersion = "v1"
+	// SendConsumptionDataVersionV2 represents version 2 of the Send Consumption Information endpoint, which takes a *models.ConsumptionRequestV2
+	SendConsumptionDataVersionV2 SendConsumptionDataVersion = "v2"
+)
+
 // BaseAppStoreServerAPIClient represents the base API client for the App Store Server API
 type BaseAppStoreServerAPIClient struct {
-	baseURL     string
-	signingKey  []byte
-	keyID       string
-	issuerID    string
-	bundleID    string
-	environment models.Environment
+	baseURL       string
+	userAgent     string
+	keys          *keyRing
+	issuerID      string
+	bundleID      string
+	environment   models.Environment
+	tokenLifetime time.Duration
 }
 
-// NewBaseAppStoreServerAPIClient creates a new BaseAppStoreServerAPIClient
+// NewBaseAppStoreServerAPIClient creates a new BaseAppStoreServerAPIClient.
+// LocalTesting uses a placeholder base URL and environments other than Production use the sandbox.
 func NewBaseAppStoreServerAPIClient(signingKey []byte, keyID, issuerID, bundleID string, environment models.Environment) (*BaseAppStoreServerAPIClient, error) {
+	return newBaseAppStoreServerAPIClient(signingKey, keyID, issuerID, bundleID, environment, newLegacyClientOptions(nil))
+}
+
+// newBaseAppStoreServerAPIClient creates a new BaseAppStoreServerAPIClient using the given options
+func newBaseAppStoreServerAPIClient(signingKey []byte, keyID, issuerID, bundleID string, environment models.Environment, opts *clientOptions) (*BaseAppStoreServerAPIClient, error) {
 	if environment == models.EnvironmentXcode {
 		return nil, fmt.Errorf("Xcode is not a supported environment for an AppStoreServerAPIClient")
 	}
 
-	baseURL := "https://api.storekit-sandbox.itunes.apple.com"
-	if environment == models.EnvironmentProduction {
-		baseURL = "https://api.storekit.itunes.apple.com"
-	} else if environment == models.EnvironmentLocalTesting {
-		baseURL = "https://local-testing-base-url"
+	baseURL := opts.baseURL
+	switch {
+	case baseURL != "":
+	case opts.legacyEnvironments:
+		baseURL = legacyBaseURLForEnvironment(environment)
+	default:
+		var err error
+		if baseURL, err = baseURLForEnvironment(environment); err != nil {
+			return nil, err
+		}
+	}
+
+	userAgent := opts.userAgent
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+
+	tokenLifetime, err := tokenLifetimeOrDefault(opts.tokenLifetime)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := newKeyRing(keyID, signingKey, opts.signer)
+	if opts.keySource != nil {
+		if keys, err = newKeyRingFromSource(context.Background(), opts.keySource); err != nil {
+			return nil, err
+		}
 	}
 
 	return &BaseAppStoreServerAPIClient{
-		baseURL:     baseURL,
-		signingKey:  signingKey,
-		keyID:       keyID,
-		issuerID:    issuerID,
-		bundleID:    bundleID,
-		environment: environment,
+		baseURL:       baseURL,
+		userAgent:     userAgent,
+		keys:          keys,
+		issuerID:      issuerID,
+		bundleID:      bundleID,
+		environment:   environment,
+		tokenLifetime: tokenLifetime,
 	}, nil
 }
 
-// generateToken generates a JWT token for authenticating with the App Store Server API
-func (c *BaseAppStoreServerAPIClient) generateToken() (string, error) {
-	// Parse the signing key
-	key, err := jwt.ParseECPrivateKeyFromPEM(c.signingKey)
+// generateToken returns a JWT token for authenticating with the App Store Server API, signed with the active key,
+// and the key ring generation it belongs to. Tokens are cached and reused until shortly before they expire.
+func (c *BaseAppStoreServerAPIClient) generateToken() (string, uint64, error) {
+	key, generation, tokens, err := c.keys.current()
 	if err != nil {
-		return "", err
+		return "", 0, err
 	}
 
+	token, err := tokens.getOrCreate(time.Now(), func() (string, time.Time, error) {
+		return c.signToken(key)
+	})
+	return token, generation, err
+}
+
+// signToken signs a new JWT token with the given key and returns it together with its expiry
+func (c *BaseAppStoreServerAPIClient) signToken(key SigningKey) (string, time.Time, error) {
 	// Create the claims
+	now := time.Now()
+	expiresAt := now.Add(c.tokenLifetime)
 	claims := jwt.MapClaims{
 		"bid": c.bundleID,
 		"iss": c.issuerID,
 		"aud": "appstoreconnect-v1",
-		"exp": time.Now().Add(5 * time.Minute).Unix(),
+		"iat": now.Unix(),
+		"exp": expiresAt.Unix(),
 	}
 
 	// Create the token
 	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
 
 	// Set the kid header
-	token.Header["kid"] = c.keyID
+	token.Header["kid"] = key.KeyID
 
 	// Sign the token
-	tokenString, err := token.SignedString(key)
+	tokenString, err := signJWS(token, key.Signer)
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 
-	return tokenString, nil
+	return tokenString, expiresAt, nil
 }
 
 // getFullURL returns the full URL for a given path
@@ -93,119 +145,298 @@ func (c *BaseAppStoreServerAPIClient) getFullURL(path string) string {
 	return c.baseURL + path
 }
 
-// getHeaders returns the headers for a request
-func (c *BaseAppStoreServerAPIClient) getHeaders() (map[string]string, error) {
-	token, err := c.generateToken()
+// getHeaders returns the headers for a request and the key ring generation of its token
+func (c *BaseAppStoreServerAPIClient) getHeaders() (map[string]string, uint64, error) {
+	token, generation, err := c.generateToken()
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	return map[string]string{
-		"User-Agent":    "github.com/DotNetAge/appstore",
+		"User-Agent":    c.userAgent,
 		"Authorization": "Bearer " + token,
 		"Accept":        "application/json",
-	}, nil
+	}, generation, nil
 }
 
-// parseResponse parses a response from the App Store Server API
-func (c *BaseAppStoreServerAPIClient) parseResponse(statusCode int, body []byte, destination interface{}) error {
-	if 200 <= statusCode && statusCode < 300 {
+// parseResponse parses a response from the App Store Server API.
+// Successful responses are decoded as they stream in; error responses are read in full, both within the size limits.
+func (c *AppStoreServerAPIClient) parseResponse(req *http.Request, resp *http.Response, destination interface{}) error {
+	if 200 <= resp.StatusCode && resp.StatusCode < 300 {
 		if destination == nil {
+			drainBody(resp.Body)
 			return nil
 		}
-		return json.Unmarshal(body, destination)
+		return json.NewDecoder(newLimitedReader(resp.Body, c.maxResponseSize, false)).Decode(destination)
+	}
+
+	// Read the error response. A body that can't be read in full, such as one over the size limit, still yields an
+	// APIException with the status code, so retries and the error sentinels keep working.
+	body, readErr := io.ReadAll(newLimitedReader(resp.Body, c.maxErrorResponseSize, true))
+
+	apiException := &models.APIException{
+		HTTPStatusCode: resp.StatusCode,
+		RequestMethod:  req.Method,
+		RequestPath:    req.URL.Path,
+		ResponseHeader: resp.Header,
+		RawBody:        body,
+		Err:            readErr,
+	}
+	if readErr != nil {
+		return apiException
 	}
 
 	// Parse error response
 	var errorResponse struct {
-		ErrorCode    int    `json:"errorCode"`
+		ErrorCode    *int   `json:"errorCode"`
 		ErrorMessage string `json:"errorMessage"`
 	}
 
-	if err := json.Unmarshal(body, &errorResponse); err != nil {
-		return &models.APIException{
-			HTTPStatusCode: statusCode,
-		}
+	if err := json.Unmarshal(body, &errorResponse); err != nil || errorResponse.ErrorCode == nil {
+		return apiException
 	}
 
-	return &models.APIException{
-		HTTPStatusCode: statusCode,
-		RawAPIError:    &errorResponse.ErrorCode,
-		APIError:       models.APIError(errorResponse.ErrorCode),
-		ErrorMessage:   &errorResponse.ErrorMessage,
-	}
+	apiException.RawAPIError = errorResponse.ErrorCode
+	apiException.APIError = models.APIError(*errorResponse.ErrorCode)
+	apiException.ErrorMessage = &errorResponse.ErrorMessage
+	return apiException
 }
 
 // AppStoreServerAPIClient represents the synchronous API client for the App Store Server API
 type AppStoreServerAPIClient struct {
 	*BaseAppStoreServerAPIClient
-	httpClient *http.Client
+	httpClient           *http.Client
+	defaultTimeout       time.Duration
+	maxResponseSize      int64
+	maxErrorResponseSize int64
+	retryPolicy          *RetryPolicy
+	rateLimiter          *RateLimiter
+	metrics              *Metrics
+	logger               *slog.Logger
+	keyFailoverHandler   func(ctx context.Context, failover KeyFailover)
+	idempotencyStore     IdempotencyStore
+	invoker              Invoker
 }
 
-// NewAppStoreServerAPIClient creates a new AppStoreServerAPIClient
+// NewAppStoreServerAPIClient creates a new AppStoreServerAPIClient that sends each request once, without retries or
+// client-side rate limiting. LocalTesting uses a placeholder base URL and environments other than Production use the sandbox.
+// Use NewAppStoreServerAPIClientWithOptions for the retry policy, rate limiter and other options.
 func NewAppStoreServerAPIClient(signingKey []byte, keyID, issuerID, bundleID string, environment models.Environment) (*AppStoreServerAPIClient, error) {
-	baseClient, err := NewBaseAppStoreServerAPIClient(signingKey, keyID, issuerID, bundleID, environment)
+	return newAppStoreServerAPIClient(signingKey, keyID, issuerID, bundleID, environment, newLegacyClientOptions(nil))
+}
+
+// NewAppStoreServerAPIClientWithOptions creates a new AppStoreServerAPIClient configured by the given options.
+// LocalTesting requires WithBaseURL, and environments other than Production, Sandbox and LocalTesting are rejected.
+func NewAppStoreServerAPIClientWithOptions(signingKey []byte, keyID, issuerID, bundleID string, environment models.Environment, options ...ClientOption) (*AppStoreServerAPIClient, error) {
+	return newAppStoreServerAPIClient(signingKey, keyID, issuerID, bundleID, environment, newClientOptions(options))
+}
+
+// newAppStoreServerAPIClient creates a new AppStoreServerAPIClient using the given options
+func newAppStoreServerAPIClient(signingKey []byte, keyID, issuerID, bundleID string, environment models.Environment, opts *clientOptions) (*AppStoreServerAPIClient, error) {
+	baseClient, err := newBaseAppStoreServerAPIClient(signingKey, keyID, issuerID, bundleID, environment, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	return &AppStoreServerAPIClient{
+	httpClient, err := opts.buildHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimiter := opts.rateLimiter
+	if !opts.rateLimiterSet {
+		rateLimiter = sharedRateLimiter(bundleID, environment)
+	}
+
+	idempotencyStore := opts.idempotencyStore
+	if !opts.idempotencyStoreSet {
+		idempotencyStore = NewMemoryIdempotencyStore(DefaultIdempotencyWindow)
+	}
+
+	client := &AppStoreServerAPIClient{
 		BaseAppStoreServerAPIClient: baseClient,
-		httpClient:                  &http.Client{},
-	}, nil
+		httpClient:                  httpClient,
+		defaultTimeout:              opts.defaultTimeout,
+		maxResponseSize:             limitOrDefault(opts.maxResponseSize, DefaultMaxResponseSize),
+		maxErrorResponseSize:        limitOrDefault(opts.maxErrorResponseSize, DefaultMaxErrorResponseSize),
+		retryPolicy:                 opts.retryPolicy,
+		rateLimiter:                 rateLimiter,
+		metrics:                     opts.metrics,
+		logger:                      loggerOrDiscard(opts.logger),
+		keyFailoverHandler:          opts.keyFailoverHandler,
+		idempotencyStore:            idempotencyStore,
+	}
+
+	// Tracing is the outermost interceptor so the others run inside the call's span
+	interceptors := append([]Interceptor{
+		&tracingInterceptor{
+			tracer:      newTracer(opts.tracerProvider),
+			environment: environment,
+		},
+		&loggingInterceptor{logger: client.logger},
+	}, opts.interceptors...)
+	if opts.metrics != nil {
+		interceptors = append(interceptors, opts.metrics)
+	}
+	client.invoker = chainInterceptors(interceptors, client.invoke)
+	return client, nil
 }
 
 // makeRequest makes a request to the App Store Server API
-func (c *AppStoreServerAPIClient) makeRequest(ctx context.Context, path, method string, queryParams url.Values, body interface{}, destination interface{}) error {
+func (c *AppStoreServerAPIClient) makeRequest(ctx context.Context, endpoint Endpoint, path, method string, queryParams url.Values, body interface{}, destination interface{}) error {
+	// Apply the default timeout if the caller didn't set a deadline
+	if _, ok := ctx.Deadline(); !ok && c.defaultTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.defaultTimeout)
+		defer cancel()
+	}
+
+	// Check the request body before anything is sent
+	if validator, ok := body.(requestValidator); ok {
+		if err := validator.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Encode the request body once so it can be resent on retries
+	header := http.Header{}
+	var encodedBody []byte
+	if raw, ok := body.(rawBody); ok {
+		encodedBody = raw.data
+		header.Set("Content-Type", raw.contentType)
+	} else if body != nil {
+		var err error
+		if encodedBody, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	call := &Call{
+		Endpoint: endpoint,
+		Method:   method,
+		Path:     path,
+		Query:    queryParams,
+		Body:     encodedBody,
+		Header:   header,
+		Response: destination,
+	}
+	return c.invoker(ctx, call)
+}
+
+// rawBody is a request body that makeRequest sends as-is instead of encoding it as JSON
+type rawBody struct {
+	contentType string
+	data        []byte
+}
+
+// requestValidator is implemented by request models that can check themselves before they are sent
+type requestValidator interface {
+	Validate() error
+}
+
+// transactionPath validates a transaction identifier and returns prefix followed by the escaped identifier
+func transactionPath(prefix, transactionID string) (string, error) {
+	if err := models.ValidateTransactionID(transactionID); err != nil {
+		return "", err
+	}
+	return prefix + url.PathEscape(transactionID), nil
+}
+
+// invoke sends a call, retrying it according to the retry policy, once the interceptors have run
+func (c *AppStoreServerAPIClient) invoke(ctx context.Context, call *Call) error {
+	start := time.Now()
+	defer func() {
+		call.Duration = time.Since(start)
+	}()
+
+	maxAttempts := c.retryPolicy.maxAttempts(call.Method)
+	failovers := 0
+	for attempt := 1; ; attempt++ {
+		// Wait for quota on the endpoint family
+		wait, err := c.rateLimiter.Wait(ctx, call.Endpoint.Family())
+		if err != nil {
+			return err
+		}
+		c.metrics.observeRateLimitWait(call.Endpoint.Family(), wait)
+
+		call.Attempts = attempt
+		retryAfter, generation, err := c.doRequest(ctx, call)
+
+		// Resend right away with the next signing key if Apple rejected the current one
+		if err != nil && failovers < c.keys.size() && c.failoverKey(ctx, generation, err) {
+			failovers++
+			maxAttempts++
+			continue
+		}
+
+		if err == nil || attempt >= maxAttempts || !c.retryPolicy.shouldRetry(ctx, err) {
+			return err
+		}
+		c.metrics.observeRetry(call.Endpoint)
+
+		backoff := c.retryPolicy.backoff(attempt, retryAfter)
+		c.logger.LogAttrs(ctx, slog.LevelInfo, "retrying App Store Server API request",
+			slog.String("endpoint", string(call.Endpoint)),
+			slog.String("path", call.Path),
+			slog.Int("attempt", attempt),
+			slog.Duration("backoff", backoff),
+			slog.Any("error", err),
+		)
+		if sleepErr := sleepContext(ctx, backoff); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+// doRequest sends a single request to the App Store Server API and returns the delay requested by a Retry-After header
+// and the key ring generation of the token it was signed with
+func (c *AppStoreServerAPIClient) doRequest(ctx context.Context, call *Call) (time.Duration, uint64, error) {
 	// Get the full URL
-	fullURL := c.getFullURL(path)
+	fullURL := c.getFullURL(call.Path)
 
 	// Get the headers
-	headers, err := c.getHeaders()
+	headers, generation, err := c.getHeaders()
 	if err != nil {
-		return err
+		return 0, 0, err
 	}
 
 	// Create the request body
 	var requestBody io.Reader
-	if body != nil {
-		jsonBody, err := json.Marshal(body)
-		if err != nil {
-			return err
+	if call.Body != nil {
+		requestBody = bytes.NewReader(call.Body)
+		if call.Header.Get("Content-Type") == "" {
+			headers["Content-Type"] = "application/json"
 		}
-		requestBody = bytes.NewBuffer(jsonBody)
-		headers["Content-Type"] = "application/json"
 	}
 
 	// Create the request
-	req, err := http.NewRequestWithContext(ctx, method, fullURL, requestBody)
+	req, err := http.NewRequestWithContext(ctx, call.Method, fullURL, requestBody)
 	if err != nil {
-		return err
+		return 0, 0, err
 	}
 
 	// Add headers
+	for key, values := range call.Header {
+		req.Header[key] = append([]string(nil), values...)
+	}
 	for key, value := range headers {
-		req.Header.Add(key, value)
+		req.Header.Set(key, value)
 	}
 
 	// Add query parameters
-	if len(queryParams) > 0 {
-		req.URL.RawQuery = queryParams.Encode()
+	if len(call.Query) > 0 {
+		req.URL.RawQuery = call.Query.Encode()
 	}
 
 	// Send the request
+	call.StatusCode = 0
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return err
+		return 0, 0, err
 	}
 	defer resp.Body.Close()
-
-	// Read the response body
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
+	call.StatusCode = resp.StatusCode
 
 	// Parse the response
-	return c.parseResponse(resp.StatusCode, respBody, destination)
+	return parseRetryAfter(resp.Header), generation, c.parseResponse(req, resp, call.Response)
 }
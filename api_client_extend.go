@@ -9,10 +9,22 @@ import (
 )
 
 // ExtendRenewalDateForAllActiveSubscribers uses a subscription's product identifier to extend the renewal date for all of its eligible active subscribers.
+// A request identifier is generated when the request has none, and the request is recorded in the idempotency store
+// before it is sent; if a request with the same identifier was recorded and Apple already received it, it isn't sent
+// again. A request without an identifier that is identical to one Apple already received returns a
+// *DuplicateExtensionError, which matches ErrDuplicateExtension.
 // https://developer.apple.com/documentation/appstoreserverapi/extend_subscription_renewal_dates_for_all_active_subscribers
 func (c *AppStoreServerAPIClient) ExtendRenewalDateForAllActiveSubscribers(ctx context.Context, request *models.MassExtendRenewalDateRequest) (*models.MassExtendRenewalDateResponse, error) {
+	request, received, err := c.prepareMassExtension(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if received {
+		return &models.MassExtendRenewalDateResponse{RequestIdentifier: request.RequestIdentifier}, nil
+	}
+
 	var response models.MassExtendRenewalDateResponse
-	if err := c.makeRequest(ctx, "/inApps/v1/subscriptions/extend/mass", "POST", url.Values{}, request, &response); err != nil {
+	if err := c.makeRequest(ctx, EndpointExtendRenewalDateForAllActiveSubscribers, "/inApps/v1/subscriptions/extend/mass", "POST", url.Values{}, request, &response); err != nil {
 		return nil, err
 	}
 	return &response, nil
@@ -22,8 +34,11 @@ func (c *AppStoreServerAPIClient) ExtendRenewalDateForAllActiveSubscribers(ctx c
 // https://developer.apple.com/documentation/appstoreserverapi/extend_a_subscription_renewal_date
 func (c *AppStoreServerAPIClient) ExtendSubscriptionRenewalDate(ctx context.Context, originalTransactionID string, request *models.ExtendRenewalDateRequest) (*models.ExtendRenewalDateResponse, error) {
 	var response models.ExtendRenewalDateResponse
-	path := "/inApps/v1/subscriptions/extend/" + originalTransactionID
-	if err := c.makeRequest(ctx, path, "PUT", url.Values{}, request, &response); err != nil {
+	path, err := transactionPath("/inApps/v1/subscriptions/extend/", originalTransactionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.makeRequest(ctx, EndpointExtendSubscriptionRenewalDate, path, "PUT", url.Values{}, request, &response); err != nil {
 		return nil, err
 	}
 	return &response, nil
@@ -33,7 +48,10 @@ func (c *AppStoreServerAPIClient) ExtendSubscriptionRenewalDate(ctx context.Cont
 // https://developer.apple.com/documentation/appstoreserverapi/get_all_subscription_statuses
 func (c *AppStoreServerAPIClient) GetAllSubscriptionStatuses(ctx context.Context, transactionID string, status []models.Status) (*models.StatusResponse, error) {
 	var response models.StatusResponse
-	path := "/inApps/v1/subscriptions/" + transactionID
+	path, err := transactionPath("/inApps/v1/subscriptions/", transactionID)
+	if err != nil {
+		return nil, err
+	}
 
 	queryParams := url.Values{}
 	if len(status) > 0 {
@@ -42,7 +60,7 @@ func (c *AppStoreServerAPIClient) GetAllSubscriptionStatuses(ctx context.Context
 		}
 	}
 
-	if err := c.makeRequest(ctx, path, "GET", queryParams, nil, &response); err != nil {
+	if err := c.makeRequest(ctx, EndpointGetAllSubscriptionStatuses, path, "GET", queryParams, nil, &response); err != nil {
 		return nil, err
 	}
 	return &response, nil
@@ -52,14 +70,17 @@ func (c *AppStoreServerAPIClient) GetAllSubscriptionStatuses(ctx context.Context
 // https://developer.apple.com/documentation/appstoreserverapi/get_refund_history
 func (c *AppStoreServerAPIClient) GetRefundHistory(ctx context.Context, transactionID string, revision string) (*models.RefundHistoryResponse, error) {
 	var response models.RefundHistoryResponse
-	path := "/inApps/v2/refund/lookup/" + transactionID
+	path, err := transactionPath("/inApps/v2/refund/lookup/", transactionID)
+	if err != nil {
+		return nil, err
+	}
 
 	queryParams := url.Values{}
 	if revision != "" {
 		queryParams.Add("revision", revision)
 	}
 
-	if err := c.makeRequest(ctx, path, "GET", queryParams, nil, &response); err != nil {
+	if err := c.makeRequest(ctx, EndpointGetRefundHistory, path, "GET", queryParams, nil, &response); err != nil {
 		return nil, err
 	}
 	return &response, nil
@@ -69,9 +90,9 @@ func (c *AppStoreServerAPIClient) GetRefundHistory(ctx context.Context, transact
 // https://developer.apple.com/documentation/appstoreserverapi/get_status_of_subscription_renewal_date_extensions
 func (c *AppStoreServerAPIClient) GetStatusOfSubscriptionRenewalDateExtensions(ctx context.Context, requestIdentifier string, productID string) (*models.MassExtendRenewalDateStatusResponse, error) {
 	var response models.MassExtendRenewalDateStatusResponse
-	path := "/inApps/v1/subscriptions/extend/mass/" + productID + "/" + requestIdentifier
+	path := "/inApps/v1/subscriptions/extend/mass/" + url.PathEscape(productID) + "/" + url.PathEscape(requestIdentifier)
 
-	if err := c.makeRequest(ctx, path, "GET", url.Values{}, nil, &response); err != nil {
+	if err := c.makeRequest(ctx, EndpointGetStatusOfSubscriptionRenewalDateExtensions, path, "GET", url.Values{}, nil, &response); err != nil {
 		return nil, err
 	}
 	return &response, nil
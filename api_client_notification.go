@@ -11,8 +11,8 @@ import (
 // https://developer.apple.com/documentation/appstoreserverapi/get_test_notification_status
 func (c *AppStoreServerAPIClient) GetTestNotificationStatus(ctx context.Context, testNotificationToken string) (*models.CheckTestNotificationResponse, error) {
 	var response models.CheckTestNotificationResponse
-	path := "/inApps/v1/notifications/test/" + testNotificationToken
-	if err := c.makeRequest(ctx, path, "GET", url.Values{}, nil, &response); err != nil {
+	path := "/inApps/v1/notifications/test/" + url.PathEscape(testNotificationToken)
+	if err := c.makeRequest(ctx, EndpointGetTestNotificationStatus, path, "GET", url.Values{}, nil, &response); err != nil {
 		return nil, err
 	}
 	return &response, nil
@@ -29,7 +29,7 @@ func (c *AppStoreServerAPIClient) GetNotificationHistory(ctx context.Context, pa
 		queryParams.Add("paginationToken", paginationToken)
 	}
 
-	if err := c.makeRequest(ctx, path, "POST", queryParams, request, &response); err != nil {
+	if err := c.makeRequest(ctx, EndpointGetNotificationHistory, path, "POST", queryParams, request, &response); err != nil {
 		return nil, err
 	}
 	return &response, nil
@@ -40,7 +40,7 @@ func (c *AppStoreServerAPIClient) GetNotificationHistory(ctx context.Context, pa
 func (c *AppStoreServerAPIClient) RequestTestNotification(ctx context.Context) (*models.SendTestNotificationResponse, error) {
 	var response models.SendTestNotificationResponse
 	path := "/inApps/v1/notifications/test"
-	if err := c.makeRequest(ctx, path, "POST", url.Values{}, nil, &response); err != nil {
+	if err := c.makeRequest(ctx, EndpointRequestTestNotification, path, "POST", url.Values{}, nil, &response); err != nil {
 		return nil, err
 	}
 	return &response, nil
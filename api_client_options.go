@@ -0,0 +1,169 @@
+package appstore
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/DotNetAge/appstore/models"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	productionBaseURL = "https://api.storekit.itunes.apple.com"
+	sandboxBaseURL    = "https://api.storekit-sandbox.itunes.apple.com"
+	defaultUserAgent  = "github.com/DotNetAge/appstore"
+
+	// localTestingBaseURL is the placeholder the legacy constructors use for the LocalTesting environment
+	localTestingBaseURL = "https://local-testing-base-url"
+)
+
+// ClientOption is a function type for configuring an AppStoreServerAPIClient
+type ClientOption func(*clientOptions)
+
+// clientOptions holds the options for an AppStoreServerAPIClient
+type clientOptions struct {
+	httpClient           *http.Client
+	baseURL              string
+	userAgent            string
+	defaultTimeout       time.Duration
+	proxyURL             *url.URL
+	retryPolicy          *RetryPolicy
+	tokenLifetime        time.Duration
+	rateLimiter          *RateLimiter
+	rateLimiterSet       bool
+	interceptors         []Interceptor
+	tracerProvider       trace.TracerProvider
+	metrics              *Metrics
+	logger               *slog.Logger
+	maxResponseSize      int64
+	maxErrorResponseSize int64
+	signer               crypto.Signer
+	keySource            KeySource
+	keyFailoverHandler   func(ctx context.Context, failover KeyFailover)
+	idempotencyStore     IdempotencyStore
+	idempotencyStoreSet  bool
+	legacyEnvironments   bool
+}
+
+// newClientOptions applies the given options on top of the defaults
+func newClientOptions(options []ClientOption) *clientOptions {
+	opts := &clientOptions{
+		retryPolicy: DefaultRetryPolicy(),
+	}
+	for _, option := range options {
+		option(opts)
+	}
+	return opts
+}
+
+// newLegacyClientOptions applies the given options on top of the behavior the constructors that predate options
+// have always had: the legacy environment fallback, and no retries, rate limiting or mass extension deduplication
+func newLegacyClientOptions(options []ClientOption) *clientOptions {
+	opts := &clientOptions{
+		rateLimiterSet:      true,
+		idempotencyStoreSet: true,
+		legacyEnvironments:  true,
+	}
+	for _, option := range options {
+		option(opts)
+	}
+	return opts
+}
+
+// WithHTTPClient sets the http.Client used to send requests.
+// The client is used as-is, so its transport, connection pooling and middleware are preserved.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(opts *clientOptions) {
+		opts.httpClient = httpClient
+	}
+}
+
+// WithBaseURL overrides the base URL derived from the environment, e.g. to point the client at a local stand-in server.
+// It is required for EnvironmentLocalTesting.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(opts *clientOptions) {
+		opts.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(userAgent string) ClientOption {
+	return func(opts *clientOptions) {
+		opts.userAgent = userAgent
+	}
+}
+
+// WithDefaultTimeout sets the timeout applied to requests whose context has no deadline
+func WithDefaultTimeout(timeout time.Duration) ClientOption {
+	return func(opts *clientOptions) {
+		opts.defaultTimeout = timeout
+	}
+}
+
+// WithProxy routes requests through the given proxy.
+// When combined with WithHTTPClient, the proxy is set on a copy of the client's *http.Transport.
+func WithProxy(proxyURL *url.URL) ClientOption {
+	return func(opts *clientOptions) {
+		opts.proxyURL = proxyURL
+	}
+}
+
+// baseURLForEnvironment returns the App Store Server API base URL for the given environment
+func baseURLForEnvironment(environment models.Environment) (string, error) {
+	switch environment {
+	case models.EnvironmentProduction:
+		return productionBaseURL, nil
+	case models.EnvironmentSandbox:
+		return sandboxBaseURL, nil
+	case models.EnvironmentLocalTesting:
+		return "", fmt.Errorf("a base URL must be provided with WithBaseURL for the LocalTesting environment")
+	default:
+		return "", fmt.Errorf("%s is not a supported environment for an AppStoreServerAPIClient", environment)
+	}
+}
+
+// legacyBaseURLForEnvironment returns the base URL the constructors without options have always used: a placeholder
+// for LocalTesting and the sandbox for any environment other than Production
+func legacyBaseURLForEnvironment(environment models.Environment) string {
+	switch environment {
+	case models.EnvironmentProduction:
+		return productionBaseURL
+	case models.EnvironmentLocalTesting:
+		return localTestingBaseURL
+	default:
+		return sandboxBaseURL
+	}
+}
+
+// buildHTTPClient returns the http.Client described by the options
+func (o *clientOptions) buildHTTPClient() (*http.Client, error) {
+	httpClient := o.httpClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+
+	if o.proxyURL == nil {
+		return httpClient, nil
+	}
+
+	var transport *http.Transport
+	switch t := httpClient.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return nil, fmt.Errorf("WithProxy requires the http.Client transport to be an *http.Transport, got %T", httpClient.Transport)
+	}
+	transport.Proxy = http.ProxyURL(o.proxyURL)
+
+	proxied := *httpClient
+	proxied.Transport = transport
+	return &proxied, nil
+}
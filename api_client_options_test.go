@@ -0,0 +1,153 @@
+package appstore
+
+import (
+	"testing"
+
+	"github.com/DotNetAge/appstore/models"
+)
+
+func TestBaseURLForEnvironment(t *testing.T) {
+	tests := []struct {
+		name        string
+		environment models.Environment
+		options     []ClientOption
+		wantLegacy  string
+		want        string
+	}{
+		{name: "production", environment: models.EnvironmentProduction, wantLegacy: productionBaseURL, want: productionBaseURL},
+		{name: "sandbox", environment: models.EnvironmentSandbox, wantLegacy: sandboxBaseURL, want: sandboxBaseURL},
+		{name: "local testing", environment: models.EnvironmentLocalTesting, wantLegacy: localTestingBaseURL},
+		{name: "local testing with base URL", environment: models.EnvironmentLocalTesting, options: []ClientOption{WithBaseURL("http://localhost:8080")}, wantLegacy: localTestingBaseURL, want: "http://localhost:8080"},
+		{name: "unknown environment", environment: models.Environment("Staging"), wantLegacy: sandboxBaseURL},
+		{name: "unknown environment with base URL", environment: models.Environment("Staging"), options: []ClientOption{WithBaseURL("http://localhost:8080")}, wantLegacy: sandboxBaseURL, want: "http://localhost:8080"},
+	}
+	_, key := newTestSigningKey(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			legacy, err := NewAppStoreServerAPIClient(key, "KEY_ID", "ISSUER_ID", "com.example", tt.environment)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if legacy.baseURL != tt.wantLegacy {
+				t.Errorf("NewAppStoreServerAPIClient base URL = %s, want %s", legacy.baseURL, tt.wantLegacy)
+			}
+			base, err := NewBaseAppStoreServerAPIClient(key, "KEY_ID", "ISSUER_ID", "com.example", tt.environment)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if base.baseURL != tt.wantLegacy {
+				t.Errorf("NewBaseAppStoreServerAPIClient base URL = %s, want %s", base.baseURL, tt.wantLegacy)
+			}
+
+			client, err := NewAppStoreServerAPIClientWithOptions(key, "KEY_ID", "ISSUER_ID", "com.example", tt.environment, tt.options...)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("NewAppStoreServerAPIClientWithOptions accepted %s without WithBaseURL", tt.environment)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if client.baseURL != tt.want {
+				t.Errorf("NewAppStoreServerAPIClientWithOptions base URL = %s, want %s", client.baseURL, tt.want)
+			}
+		})
+	}
+}
+
+func TestXcodeEnvironmentRejected(t *testing.T) {
+	_, key := newTestSigningKey(t)
+	if _, err := NewAppStoreServerAPIClient(key, "KEY_ID", "ISSUER_ID", "com.example", models.EnvironmentXcode); err == nil {
+		t.Error("NewAppStoreServerAPIClient accepted Xcode")
+	}
+	if _, err := NewAppStoreServerAPIClientWithOptions(key, "KEY_ID", "ISSUER_ID", "com.example", models.EnvironmentXcode, WithBaseURL("http://localhost:8080")); err == nil {
+		t.Error("NewAppStoreServerAPIClientWithOptions accepted Xcode")
+	}
+}
+
+func TestAppStoreServerClientLegacyEnvironments(t *testing.T) {
+	tests := []struct {
+		name        string
+		environment models.Environment
+		options     []ClientOption
+		want        string
+	}{
+		{name: "local testing", environment: models.EnvironmentLocalTesting, want: localTestingBaseURL},
+		{name: "local testing with base URL", environment: models.EnvironmentLocalTesting, options: []ClientOption{WithBaseURL("http://localhost:8080")}, want: "http://localhost:8080"},
+		{name: "unknown environment", environment: models.Environment("Staging"), want: sandboxBaseURL},
+		{name: "production", environment: models.EnvironmentProduction, want: productionBaseURL},
+	}
+	_, key := newTestSigningKey(t)
+	rootCertPath := newTestRootCertificateDir(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewAppStoreServerClient("KEY_ID", "ISSUER_ID", "com.example", 1234567890, key, rootCertPath, tt.environment, tt.options...)
+			if client.client.baseURL != tt.want {
+				t.Errorf("NewAppStoreServerClient base URL = %s, want %s", client.client.baseURL, tt.want)
+			}
+		})
+	}
+}
+
+func TestLegacyConstructorDefaults(t *testing.T) {
+	_, key := newTestSigningKey(t)
+	rootCertPath := newTestRootCertificateDir(t)
+	limiter := NewRateLimiter(nil)
+	store := NewMemoryIdempotencyStore(DefaultIdempotencyWindow)
+	newClient := func(t *testing.T, options ...ClientOption) *AppStoreServerAPIClient {
+		client, err := NewAppStoreServerAPIClientWithOptions(key, "KEY_ID", "ISSUER_ID", "com.example", models.EnvironmentSandbox, options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client
+	}
+
+	tests := []struct {
+		name        string
+		client      func(t *testing.T) *AppStoreServerAPIClient
+		retries     bool
+		rateLimiter *RateLimiter
+		store       IdempotencyStore
+	}{
+		{name: "NewAppStoreServerAPIClient", client: func(t *testing.T) *AppStoreServerAPIClient {
+			client, err := NewAppStoreServerAPIClient(key, "KEY_ID", "ISSUER_ID", "com.example", models.EnvironmentSandbox)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return client
+		}},
+		{name: "NewAppStoreServerClient", client: func(t *testing.T) *AppStoreServerAPIClient {
+			return NewAppStoreServerClient("KEY_ID", "ISSUER_ID", "com.example", 1234567890, key, rootCertPath, models.EnvironmentSandbox).client
+		}},
+		{name: "NewAppStoreServerClient with options", client: func(t *testing.T) *AppStoreServerAPIClient {
+			return NewAppStoreServerClient("KEY_ID", "ISSUER_ID", "com.example", 1234567890, key, rootCertPath, models.EnvironmentSandbox,
+				WithRetryPolicy(DefaultRetryPolicy()), WithRateLimiter(limiter), WithIdempotencyStore(store)).client
+		}, retries: true, rateLimiter: limiter, store: store},
+		{name: "NewAppStoreServerAPIClientWithOptions", client: func(t *testing.T) *AppStoreServerAPIClient {
+			return newClient(t)
+		}, retries: true, rateLimiter: sharedRateLimiter("com.example", models.EnvironmentSandbox)},
+		{name: "NewAppStoreServerAPIClientWithOptions without retries, rate limiting or deduplication", client: func(t *testing.T) *AppStoreServerAPIClient {
+			return newClient(t, WithRetryPolicy(nil), WithRateLimiter(nil), WithIdempotencyStore(nil))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := tt.client(t)
+			if got := client.retryPolicy != nil; got != tt.retries {
+				t.Errorf("retries = %v, want %v", got, tt.retries)
+			}
+			if client.rateLimiter != tt.rateLimiter {
+				t.Errorf("rate limiter = %p, want %p", client.rateLimiter, tt.rateLimiter)
+			}
+			switch {
+			case tt.name == "NewAppStoreServerAPIClientWithOptions":
+				if _, ok := client.idempotencyStore.(*MemoryIdempotencyStore); !ok {
+					t.Errorf("idempotency store = %T, want the default memory store", client.idempotencyStore)
+				}
+			case client.idempotencyStore != tt.store:
+				t.Errorf("idempotency store = %v, want %v", client.idempotencyStore, tt.store)
+			}
+		})
+	}
+}
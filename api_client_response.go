@@ -0,0 +1,95 @@
+package appstore
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	// DefaultMaxResponseSize is the default limit, in bytes, on successful response bodies
+	DefaultMaxResponseSize int64 = 16 << 20
+	// DefaultMaxErrorResponseSize is the default limit, in bytes, on error response bodies
+	DefaultMaxErrorResponseSize int64 = 64 << 10
+
+	// drainLimit is how much of an unread response body is discarded so the connection can be reused
+	drainLimit = 4 << 10
+)
+
+// WithMaxResponseSize limits the size, in bytes, of successful response bodies, which are decoded as they stream in
+func WithMaxResponseSize(limit int64) ClientOption {
+	return func(opts *clientOptions) {
+		opts.maxResponseSize = limit
+	}
+}
+
+// WithMaxErrorResponseSize limits the size, in bytes, of error response bodies
+func WithMaxErrorResponseSize(limit int64) ClientOption {
+	return func(opts *clientOptions) {
+		opts.maxErrorResponseSize = limit
+	}
+}
+
+// ResponseTooLargeError is returned when a successful response body exceeds the configured size limit. An error
+// response over its limit is still reported as a *models.APIException, with this error as its Err.
+type ResponseTooLargeError struct {
+	// Limit is the limit, in bytes, that was exceeded
+	Limit int64
+	// ErrorResponse is true if the limit is the one on error responses, set by WithMaxErrorResponseSize
+	ErrorResponse bool
+}
+
+// Error implements the error interface for ResponseTooLargeError
+func (e *ResponseTooLargeError) Error() string {
+	if e.ErrorResponse {
+		return fmt.Sprintf("App Store Server API error response exceeds the %d byte limit set by WithMaxErrorResponseSize", e.Limit)
+	}
+	return fmt.Sprintf("App Store Server API response exceeds the %d byte limit set by WithMaxResponseSize", e.Limit)
+}
+
+// limitedReader reads from r until limit bytes have been read, then fails with a ResponseTooLargeError
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	err       *ResponseTooLargeError
+}
+
+// newLimitedReader returns a reader that fails once more than limit bytes are read from r
+func newLimitedReader(r io.Reader, limit int64, errorResponse bool) *limitedReader {
+	return &limitedReader{
+		r:         r,
+		remaining: limit,
+		err:       &ResponseTooLargeError{Limit: limit, ErrorResponse: errorResponse},
+	}
+}
+
+// Read implements io.Reader
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, l.err
+	}
+
+	// Read one byte past the limit to tell a body of exactly limit bytes from a longer one
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		// Drop the extra byte so callers never see more than limit bytes, even if they stop at the first error
+		return n + int(l.remaining), l.err
+	}
+	return n, err
+}
+
+// drainBody discards a bounded amount of the remaining body so the connection can be reused
+func drainBody(body io.Reader) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(body, drainLimit))
+}
+
+// limitOrDefault returns limit, or def if limit isn't positive
+func limitOrDefault(limit, def int64) int64 {
+	if limit <= 0 {
+		return def
+	}
+	return limit
+}
@@ -0,0 +1,118 @@
+package appstore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DotNetAge/appstore/models"
+)
+
+// newSizeLimitedClient returns a client with 1 KiB response limits that sends requests to a server answering with
+// status and body, and the number of requests the server received
+func newSizeLimitedClient(t *testing.T, status int, body string) (*AppStoreServerAPIClient, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	_, key := newTestSigningKey(t)
+	client, err := NewAppStoreServerAPIClientWithOptions(key, "KEY_ID", "ISSUER_ID", "com.example", models.EnvironmentSandbox,
+		WithBaseURL(server.URL),
+		WithMaxResponseSize(1<<10),
+		WithMaxErrorResponseSize(1<<10),
+		WithRetryPolicy(&RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Multiplier: 1}),
+		WithRateLimiter(NewRateLimiter(nil)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, &requests
+}
+
+// jsonOfSize returns a JSON object of exactly n bytes
+func jsonOfSize(n int) string {
+	const prefix, suffix = `{"signedTransactionInfo":"`, `"}`
+	return prefix + strings.Repeat("x", n-len(prefix)-len(suffix)) + suffix
+}
+
+func TestSuccessResponseSizeLimit(t *testing.T) {
+	t.Run("at the limit", func(t *testing.T) {
+		client, _ := newSizeLimitedClient(t, http.StatusOK, jsonOfSize(1<<10))
+		if _, err := client.GetTransactionInfo(context.Background(), "1000"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("over the limit", func(t *testing.T) {
+		client, _ := newSizeLimitedClient(t, http.StatusOK, jsonOfSize(1<<10+1))
+		_, err := client.GetTransactionInfo(context.Background(), "1000")
+		var tooLarge *ResponseTooLargeError
+		if !errors.As(err, &tooLarge) {
+			t.Fatalf("got %v, want a ResponseTooLargeError", err)
+		}
+		if tooLarge.ErrorResponse || tooLarge.Limit != 1<<10 {
+			t.Errorf("unexpected error %+v", tooLarge)
+		}
+	})
+}
+
+func TestErrorResponseSizeLimit(t *testing.T) {
+	large := strings.Repeat("x", 4<<10)
+	tests := []struct {
+		name         string
+		status       int
+		body         string
+		sentinel     error
+		wantRequests int32
+		wantTooLarge bool
+	}{
+		{name: "server error over the limit is retried", status: http.StatusInternalServerError, body: large, sentinel: models.ErrRetryable, wantRequests: 3, wantTooLarge: true},
+		{name: "rate limit over the limit is retried", status: http.StatusTooManyRequests, body: large, sentinel: models.ErrRateLimited, wantRequests: 3, wantTooLarge: true},
+		{name: "not found over the limit", status: http.StatusNotFound, body: large, sentinel: models.ErrNotFound, wantRequests: 1, wantTooLarge: true},
+		{name: "error within the limit", status: http.StatusNotFound, body: `{"errorCode":4040010,"errorMessage":"Transaction id not found."}`, sentinel: models.APIErrorTransactionIDNotFound, wantRequests: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, requests := newSizeLimitedClient(t, tt.status, tt.body)
+			_, err := client.GetTransactionInfo(context.Background(), "1000")
+
+			var apiErr *models.APIException
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("got %v, want an APIException", err)
+			}
+			if apiErr.HTTPStatusCode != tt.status {
+				t.Errorf("status = %d, want %d", apiErr.HTTPStatusCode, tt.status)
+			}
+			if !errors.Is(err, tt.sentinel) {
+				t.Errorf("%v doesn't match %v", err, tt.sentinel)
+			}
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("sent %d requests, want %d", got, tt.wantRequests)
+			}
+
+			var tooLarge *ResponseTooLargeError
+			if errors.As(err, &tooLarge) != tt.wantTooLarge {
+				t.Fatalf("ResponseTooLargeError attached = %v, want %v", !tt.wantTooLarge, tt.wantTooLarge)
+			}
+			if tt.wantTooLarge {
+				if !tooLarge.ErrorResponse {
+					t.Error("the error response limit isn't reported")
+				}
+				if len(apiErr.RawBody) != 1<<10 {
+					t.Errorf("raw body is %d bytes, want the 1024 byte limit", len(apiErr.RawBody))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package appstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/DotNetAge/appstore/models"
+)
+
+// RetryPolicy configures how failed App Store Server API requests are retried.
+// Requests are retried on retryable APIError codes, 5xx responses and network errors.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one. Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts, including delays requested by Retry-After
+	MaxBackoff time.Duration
+	// Multiplier is the factor the backoff grows by after each attempt
+	Multiplier float64
+	// Jitter is the fraction, between 0 and 1, of each backoff that is randomized
+	Jitter float64
+	// RetryNonIdempotent enables retries for mutating requests such as ExtendSubscriptionRenewalDate.
+	// By default only GET requests are retried.
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy returns the retry policy used when no WithRetryPolicy option is given
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.5,
+	}
+}
+
+// WithRetryPolicy sets the retry policy of the client. A nil policy disables retries.
+func WithRetryPolicy(policy *RetryPolicy) ClientOption {
+	return func(opts *clientOptions) {
+		opts.retryPolicy = policy
+	}
+}
+
+// maxAttempts returns the number of attempts allowed for a request with the given method
+func (p *RetryPolicy) maxAttempts(method string) int {
+	if p == nil || p.MaxAttempts < 2 {
+		return 1
+	}
+	if method != http.MethodGet && method != http.MethodHead && !p.RetryNonIdempotent {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// shouldRetry reports whether a request that failed with err can be retried
+func (p *RetryPolicy) shouldRetry(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var apiErr *models.APIException
+	if errors.As(err, &apiErr) {
+		return apiErr.IsRetryable()
+	}
+
+	// Network errors are reported by http.Client as *url.Error, truncated bodies as io.ErrUnexpectedEOF
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return false
+}
+
+// backoff returns the delay before the given retry, starting at 1, honoring a server-provided Retry-After delay
+func (p *RetryPolicy) backoff(retry int, retryAfter time.Duration) time.Duration {
+	delay := float64(p.InitialBackoff)
+	for i := 1; i < retry; i++ {
+		delay *= p.Multiplier
+	}
+	if p.Jitter > 0 {
+		delay -= delay * p.Jitter * rand.Float64()
+	}
+
+	d := time.Duration(delay)
+	if retryAfter > d {
+		d = retryAfter
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// sleepContext waits for the given duration or until the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
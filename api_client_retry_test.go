@@ -0,0 +1,218 @@
+package appstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DotNetAge/appstore/models"
+)
+
+func TestMaxAttempts(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy *RetryPolicy
+		method string
+		want   int
+	}{
+		{name: "nil policy", policy: nil, method: http.MethodGet, want: 1},
+		{name: "single attempt", policy: &RetryPolicy{MaxAttempts: 1}, method: http.MethodGet, want: 1},
+		{name: "GET", policy: &RetryPolicy{MaxAttempts: 3}, method: http.MethodGet, want: 3},
+		{name: "HEAD", policy: &RetryPolicy{MaxAttempts: 3}, method: http.MethodHead, want: 3},
+		{name: "POST", policy: &RetryPolicy{MaxAttempts: 3}, method: http.MethodPost, want: 1},
+		{name: "PUT", policy: &RetryPolicy{MaxAttempts: 3}, method: http.MethodPut, want: 1},
+		{name: "DELETE", policy: &RetryPolicy{MaxAttempts: 3}, method: http.MethodDelete, want: 1},
+		{name: "PUT with RetryNonIdempotent", policy: &RetryPolicy{MaxAttempts: 3, RetryNonIdempotent: true}, method: http.MethodPut, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.maxAttempts(tt.method); got != tt.want {
+				t.Errorf("maxAttempts(%s) = %d, want %d", tt.method, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	apiError := func(status int, code models.APIError) error {
+		raw := int(code)
+		return &models.APIException{HTTPStatusCode: status, APIError: code, RawAPIError: &raw}
+	}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{name: "retryable error code", err: apiError(http.StatusNotFound, models.APIErrorAccountNotFoundRetryable), want: true},
+		{name: "rate limit exceeded", err: apiError(http.StatusTooManyRequests, models.APIErrorRateLimitExceeded), want: true},
+		{name: "general internal error", err: apiError(http.StatusInternalServerError, models.APIErrorGeneralInternal), want: true},
+		{name: "5xx without error code", err: &models.APIException{HTTPStatusCode: http.StatusBadGateway}, want: true},
+		{name: "429 without error code", err: &models.APIException{HTTPStatusCode: http.StatusTooManyRequests}, want: true},
+		{name: "not found", err: apiError(http.StatusNotFound, models.APIErrorTransactionIDNotFound), want: false},
+		{name: "bad request", err: apiError(http.StatusBadRequest, models.APIErrorInvalidTransactionID), want: false},
+		{name: "wrapped API error", err: fmt.Errorf("lookup: %w", apiError(http.StatusInternalServerError, models.APIErrorGeneralInternal)), want: true},
+		{name: "network error", err: &url.Error{Op: "Get", URL: "https://example.com", Err: errors.New("connection reset")}, want: true},
+		{name: "truncated body", err: io.ErrUnexpectedEOF, want: true},
+		{name: "network error from a deadline", err: &url.Error{Op: "Get", URL: "https://example.com", Err: context.DeadlineExceeded}, want: false},
+		{name: "other error", err: errors.New("failed to encode request"), want: false},
+		{name: "canceled context", ctx: canceled, err: apiError(http.StatusInternalServerError, models.APIErrorGeneralInternal), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			if got := DefaultRetryPolicy().shouldRetry(ctx, tt.err); got != tt.want {
+				t.Errorf("shouldRetry(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		min   time.Duration
+		max   time.Duration
+	}{
+		{name: "missing", value: "", min: 0, max: 0},
+		{name: "seconds", value: "5", min: 5 * time.Second, max: 5 * time.Second},
+		{name: "zero seconds", value: "0", min: 0, max: 0},
+		{name: "negative seconds", value: "-3", min: 0, max: 0},
+		{name: "future date", value: time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), min: 58 * time.Second, max: time.Minute},
+		{name: "past date", value: time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), min: 0, max: 0},
+		{name: "garbage", value: "soon", min: 0, max: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.value != "" {
+				header.Set("Retry-After", tt.value)
+			}
+			if got := parseRetryAfter(header); got < tt.min || got > tt.max {
+				t.Errorf("parseRetryAfter(%q) = %s, want between %s and %s", tt.value, got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := &RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+	tests := []struct {
+		name       string
+		retry      int
+		retryAfter time.Duration
+		want       time.Duration
+	}{
+		{name: "first retry", retry: 1, want: 100 * time.Millisecond},
+		{name: "grows by the multiplier", retry: 3, want: 400 * time.Millisecond},
+		{name: "capped", retry: 10, want: time.Second},
+		{name: "Retry-After is longer", retry: 1, retryAfter: 500 * time.Millisecond, want: 500 * time.Millisecond},
+		{name: "Retry-After is shorter", retry: 3, retryAfter: time.Millisecond, want: 400 * time.Millisecond},
+		{name: "Retry-After is capped", retry: 1, retryAfter: time.Minute, want: time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.backoff(tt.retry, tt.retryAfter); got != tt.want {
+				t.Errorf("backoff(%d, %s) = %s, want %s", tt.retry, tt.retryAfter, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("jitter", func(t *testing.T) {
+		jittered := *policy
+		jittered.Jitter = 0.5
+		for range 100 {
+			if got := jittered.backoff(1, 0); got < 50*time.Millisecond || got > 100*time.Millisecond {
+				t.Fatalf("backoff with jitter = %s, want between 50ms and 100ms", got)
+			}
+		}
+	})
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy *RetryPolicy
+		send   func(*AppStoreServerAPIClient) error
+		want   int32
+	}{
+		{
+			name:   "GET is retried",
+			policy: &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+			send: func(c *AppStoreServerAPIClient) error {
+				_, err := c.GetTransactionInfo(context.Background(), "1000")
+				return err
+			},
+			want: 3,
+		},
+		{
+			name:   "PUT isn't retried",
+			policy: &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+			send:   extendRenewalDate,
+			want:   1,
+		},
+		{
+			name:   "PUT is retried with RetryNonIdempotent",
+			policy: &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, RetryNonIdempotent: true},
+			send:   extendRenewalDate,
+			want:   3,
+		},
+		{
+			name:   "retries disabled",
+			policy: nil,
+			send: func(c *AppStoreServerAPIClient) error {
+				_, err := c.GetTransactionInfo(context.Background(), "1000")
+				return err
+			},
+			want: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte(`{"errorCode":5000000,"errorMessage":"An unknown error occurred."}`))
+			}))
+			defer server.Close()
+
+			_, key := newTestSigningKey(t)
+			client, err := NewAppStoreServerAPIClientWithOptions(key, "KEY_ID", "ISSUER_ID", "com.example", models.EnvironmentSandbox,
+				WithBaseURL(server.URL), WithRetryPolicy(tt.policy), WithRateLimiter(nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.send(client); !errors.Is(err, models.APIErrorGeneralInternal) {
+				t.Fatalf("got %v, want GeneralInternalError", err)
+			}
+			if got := requests.Load(); got != tt.want {
+				t.Errorf("sent %d requests, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// extendRenewalDate sends a valid ExtendSubscriptionRenewalDate request, which uses PUT
+func extendRenewalDate(c *AppStoreServerAPIClient) error {
+	_, err := c.ExtendSubscriptionRenewalDate(context.Background(), "1000", &models.ExtendRenewalDateRequest{
+		ExtendByDays:      ptr(3),
+		ExtendReasonCode:  ptr(models.ExtendReasonCodeCustomerSatisfaction),
+		RequestIdentifier: ptr("f4b1c2d3-1111-4222-8333-444455556666"),
+	})
+	return err
+}
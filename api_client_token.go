@@ -0,0 +1,79 @@
+package appstore
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTokenLifetime is the lifetime of the bearer tokens used to authenticate with the App Store Server API
+	DefaultTokenLifetime = 5 * time.Minute
+	// MaxTokenLifetime is the longest token lifetime the App Store Server API accepts
+	MaxTokenLifetime = 60 * time.Minute
+
+	// tokenRefreshMargin is how long before its expiry a cached token is replaced
+	tokenRefreshMargin = 30 * time.Second
+)
+
+// WithTokenLifetime sets the lifetime of the bearer tokens; creating the client fails if it exceeds MaxTokenLifetime.
+// Tokens are reused across requests until shortly before they expire.
+func WithTokenLifetime(lifetime time.Duration) ClientOption {
+	return func(opts *clientOptions) {
+		opts.tokenLifetime = lifetime
+	}
+}
+
+// tokenCache holds the bearer token currently in use for a signing key
+type tokenCache struct {
+	mu        sync.RWMutex
+	token     string
+	refreshAt time.Time
+}
+
+// get returns the cached token if it is still valid at now
+func (t *tokenCache) get(now time.Time) (string, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.token == "" || !now.Before(t.refreshAt) {
+		return "", false
+	}
+	return t.token, true
+}
+
+// getOrCreate returns the cached token, or stores and returns a new one built by create
+func (t *tokenCache) getOrCreate(now time.Time, create func() (string, time.Time, error)) (string, error) {
+	if token, ok := t.get(now); ok {
+		return token, nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Another goroutine may have refreshed the token while we waited for the lock
+	if t.token != "" && now.Before(t.refreshAt) {
+		return t.token, nil
+	}
+
+	token, expiresAt, err := create()
+	if err != nil {
+		return "", err
+	}
+	margin := min(tokenRefreshMargin, expiresAt.Sub(now)/2)
+	t.token = token
+	t.refreshAt = expiresAt.Add(-margin)
+	return token, nil
+}
+
+// tokenLifetimeOrDefault returns the configured token lifetime, or the default if none is set.
+// Lifetimes over MaxTokenLifetime are rejected, because Apple refuses the tokens.
+func tokenLifetimeOrDefault(lifetime time.Duration) (time.Duration, error) {
+	if lifetime <= 0 {
+		return DefaultTokenLifetime, nil
+	}
+	if lifetime > MaxTokenLifetime {
+		return 0, fmt.Errorf("token lifetime %s exceeds the maximum of %s", lifetime, MaxTokenLifetime)
+	}
+	return lifetime, nil
+}
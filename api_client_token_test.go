@@ -0,0 +1,172 @@
+package appstore
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DotNetAge/appstore/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestTokenCacheReusesTokenUntilShortlyBeforeExpiry(t *testing.T) {
+	var cache tokenCache
+	start := time.Now()
+	expiresAt := start.Add(DefaultTokenLifetime)
+	created := 0
+	create := func() (string, time.Time, error) {
+		created++
+		return "token", expiresAt, nil
+	}
+
+	for _, now := range []time.Time{start, start.Add(time.Minute), expiresAt.Add(-tokenRefreshMargin - time.Second)} {
+		if _, err := cache.getOrCreate(now, create); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if created != 1 {
+		t.Fatalf("created %d tokens before the refresh margin, want 1", created)
+	}
+
+	if _, err := cache.getOrCreate(expiresAt.Add(-tokenRefreshMargin), create); err != nil {
+		t.Fatal(err)
+	}
+	if created != 2 {
+		t.Fatalf("created %d tokens after the refresh margin, want 2", created)
+	}
+}
+
+func TestTokenCacheDoesNotStoreFailures(t *testing.T) {
+	var cache tokenCache
+	failure := errors.New("signing failed")
+	if _, err := cache.getOrCreate(time.Now(), func() (string, time.Time, error) { return "", time.Time{}, failure }); !errors.Is(err, failure) {
+		t.Fatalf("got %v, want %v", err, failure)
+	}
+	if _, ok := cache.get(time.Now()); ok {
+		t.Fatal("a failed creation was cached")
+	}
+}
+
+func TestGenerateTokenReusesToken(t *testing.T) {
+	_, key := newTestSigningKey(t)
+	client, err := NewAppStoreServerAPIClient(key, "KEY_ID", "ISSUER_ID", "com.example", models.EnvironmentSandbox)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, _, err := client.generateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := client.generateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Fatal("the token was not reused")
+	}
+}
+
+func TestTokenClaims(t *testing.T) {
+	privateKey, key := newTestSigningKey(t)
+	client, err := NewAppStoreServerAPIClientWithOptions(key, "KEY_ID", "ISSUER_ID", "com.example", models.EnvironmentSandbox,
+		WithTokenLifetime(30*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, _, err := client.generateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := jwt.MapClaims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return &privateKey.PublicKey, nil
+	}, jwt.WithValidMethods([]string{"ES256"}), jwt.WithAudience("appstoreconnect-v1"), jwt.WithIssuedAt())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if kid := parsed.Header["kid"]; kid != "KEY_ID" {
+		t.Errorf("kid = %v, want KEY_ID", kid)
+	}
+	iat, err := claims.GetIssuedAt()
+	if err != nil || iat == nil {
+		t.Fatalf("iat is missing: %v", err)
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		t.Fatalf("exp is missing: %v", err)
+	}
+	if lifetime := exp.Sub(iat.Time); lifetime != 30*time.Minute {
+		t.Errorf("lifetime = %s, want 30m", lifetime)
+	}
+	if claims["iss"] != "ISSUER_ID" || claims["bid"] != "com.example" {
+		t.Errorf("unexpected claims %v", claims)
+	}
+}
+
+func TestTokenLifetime(t *testing.T) {
+	_, key := newTestSigningKey(t)
+	tests := []struct {
+		name     string
+		lifetime time.Duration
+		want     time.Duration
+		wantErr  bool
+	}{
+		{name: "default", lifetime: 0, want: DefaultTokenLifetime},
+		{name: "custom", lifetime: 20 * time.Minute, want: 20 * time.Minute},
+		{name: "maximum", lifetime: MaxTokenLifetime, want: MaxTokenLifetime},
+		{name: "over maximum", lifetime: MaxTokenLifetime + time.Second, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewAppStoreServerAPIClientWithOptions(key, "KEY_ID", "ISSUER_ID", "com.example", models.EnvironmentSandbox,
+				WithTokenLifetime(tt.lifetime))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if client.tokenLifetime != tt.want {
+				t.Errorf("lifetime = %s, want %s", client.tokenLifetime, tt.want)
+			}
+		})
+	}
+}
+
+// BenchmarkGenerateToken compares a cached token with parsing the key and signing a new token on every call,
+// as the client did before tokens were cached
+func BenchmarkGenerateToken(b *testing.B) {
+	_, key := newTestSigningKey(b)
+	client, err := NewAppStoreServerAPIClient(key, "KEY_ID", "ISSUER_ID", "com.example", models.EnvironmentSandbox)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, _, err := client.generateToken(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("parse and sign", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			signer, err := LoadSigningKey(key)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, _, err := client.signToken(SigningKey{KeyID: "KEY_ID", Signer: signer}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
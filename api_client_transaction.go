@@ -12,7 +12,14 @@ import (
 // https://developer.apple.com/documentation/appstoreserverapi/get_transaction_history
 func (c *AppStoreServerAPIClient) GetTransactionHistory(ctx context.Context, transactionID string, revision string, request *models.TransactionHistoryRequest, version GetTransactionHistoryVersion) (*models.HistoryResponse, error) {
 	var response models.HistoryResponse
-	path := "/inApps/" + string(version) + "/history/" + transactionID
+	path, err := transactionPath("/inApps/"+string(version)+"/history/", transactionID)
+	if err != nil {
+		return nil, err
+	}
+	// The request is sent as query parameters rather than as a body, so makeRequest can't check it
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
 
 	queryParams := url.Values{}
 	if revision != "" {
@@ -52,7 +59,7 @@ func (c *AppStoreServerAPIClient) GetTransactionHistory(ctx context.Context, tra
 		}
 	}
 
-	if err := c.makeRequest(ctx, path, "GET", queryParams, nil, &response); err != nil {
+	if err := c.makeRequest(ctx, EndpointGetTransactionHistory, path, "GET", queryParams, nil, &response); err != nil {
 		return nil, err
 	}
 	return &response, nil
@@ -62,8 +69,26 @@ func (c *AppStoreServerAPIClient) GetTransactionHistory(ctx context.Context, tra
 // https://developer.apple.com/documentation/appstoreserverapi/get_transaction_info
 func (c *AppStoreServerAPIClient) GetTransactionInfo(ctx context.Context, transactionID string) (*models.TransactionInfoResponse, error) {
 	var response models.TransactionInfoResponse
-	path := "/inApps/v1/transactions/" + transactionID
-	if err := c.makeRequest(ctx, path, "GET", url.Values{}, nil, &response); err != nil {
+	path, err := transactionPath("/inApps/v1/transactions/", transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.makeRequest(ctx, EndpointGetTransactionInfo, path, "GET", url.Values{}, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetAppTransactionInfo gets the customer's app transaction, the record of their purchase of the app, using any of
+// their transaction identifiers.
+// https://developer.apple.com/documentation/appstoreserverapi/get-app-transaction-info
+func (c *AppStoreServerAPIClient) GetAppTransactionInfo(ctx context.Context, transactionID string) (*models.AppTransactionInfoResponse, error) {
+	var response models.AppTransactionInfoResponse
+	path, err := transactionPath("/inApps/v1/transactions/appTransactions/", transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.makeRequest(ctx, EndpointGetAppTransactionInfo, path, "GET", url.Values{}, nil, &response); err != nil {
 		return nil, err
 	}
 	return &response, nil
@@ -73,16 +98,61 @@ func (c *AppStoreServerAPIClient) GetTransactionInfo(ctx context.Context, transa
 // https://developer.apple.com/documentation/appstoreserverapi/look_up_order_id
 func (c *AppStoreServerAPIClient) LookUpOrderID(ctx context.Context, orderID string) (*models.OrderLookupResponse, error) {
 	var response models.OrderLookupResponse
-	path := "/inApps/v1/lookup/" + orderID
-	if err := c.makeRequest(ctx, path, "GET", url.Values{}, nil, &response); err != nil {
+	path := "/inApps/v1/lookup/" + url.PathEscape(orderID)
+	if err := c.makeRequest(ctx, EndpointLookUpOrderID, path, "GET", url.Values{}, nil, &response); err != nil {
 		return nil, err
 	}
 	return &response, nil
 }
 
-// SendConsumptionData sends consumption information about a consumable in-app purchase to the App Store.
+// SendConsumptionData sends consumption information about a consumable in-app purchase to the App Store,
+// using version 1 of the endpoint. New integrations should use SendConsumptionDataV2.
 // https://developer.apple.com/documentation/appstoreserverapi/send_consumption_information
 func (c *AppStoreServerAPIClient) SendConsumptionData(ctx context.Context, transactionID string, request *models.ConsumptionRequest) error {
-	path := "/inApps/v1/transactions/consumption/" + transactionID
-	return c.makeRequest(ctx, path, "PUT", url.Values{}, request, nil)
+	return c.sendConsumption(ctx, SendConsumptionDataVersionV1, transactionID, request)
+}
+
+// SendConsumptionDataV2 sends consumption information about an in-app purchase to the App Store, in response to
+// a refund request, using version 2 of the endpoint.
+// https://developer.apple.com/documentation/appstoreserverapi/send-consumption-information
+func (c *AppStoreServerAPIClient) SendConsumptionDataV2(ctx context.Context, transactionID string, request *models.ConsumptionRequestV2) error {
+	return c.sendConsumption(ctx, SendConsumptionDataVersionV2, transactionID, request)
+}
+
+// SendConsumptionInformation sends a request of either version of the Send Consumption Information endpoint,
+// selecting the version from the request type: a *models.ConsumptionRequest for version 1 or a
+// *models.ConsumptionRequestV2 for version 2. It lets integrations that migrate between versions share one call site.
+func (c *AppStoreServerAPIClient) SendConsumptionInformation(ctx context.Context, transactionID string, request interface{}) error {
+	switch request := request.(type) {
+	case *models.ConsumptionRequest:
+		return c.SendConsumptionData(ctx, transactionID, request)
+	case *models.ConsumptionRequestV2:
+		return c.SendConsumptionDataV2(ctx, transactionID, request)
+	default:
+		return fmt.Errorf("unsupported consumption request type %T: %w", request, models.ErrInvalidRequest)
+	}
+}
+
+// sendConsumption sends a Send Consumption Information request to the given version of the endpoint
+func (c *AppStoreServerAPIClient) sendConsumption(ctx context.Context, version SendConsumptionDataVersion, transactionID string, request requestValidator) error {
+	path, err := transactionPath("/inApps/"+string(version)+"/transactions/consumption/", transactionID)
+	if err != nil {
+		return err
+	}
+	endpoint := EndpointSendConsumptionData
+	if version == SendConsumptionDataVersionV2 {
+		endpoint = EndpointSendConsumptionDataV2
+	}
+	return c.makeRequest(ctx, endpoint, path, "PUT", url.Values{}, request, nil)
+}
+
+// SetAppAccountToken sets the app account token of a purchase, identified by its original transaction identifier,
+// to attach it to a customer's account or correct the token the app set at purchase time.
+// https://developer.apple.com/documentation/appstoreserverapi/set-app-account-token
+func (c *AppStoreServerAPIClient) SetAppAccountToken(ctx context.Context, originalTransactionID string, request *models.UpdateAppAccountTokenRequest) error {
+	path, err := transactionPath("/inApps/v1/transactions/", originalTransactionID)
+	if err != nil {
+		return err
+	}
+	return c.makeRequest(ctx, EndpointSetAppAccountToken, path+"/appAccountToken", "PUT", url.Values{}, request, nil)
 }
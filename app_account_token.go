@@ -0,0 +1,84 @@
+package appstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/DotNetAge/appstore/models"
+)
+
+// DefaultAppAccountTokenConcurrency is the number of requests BackfillAppAccountTokens sends at once when no
+// concurrency is given
+const DefaultAppAccountTokenConcurrency = 4
+
+// AppAccountTokenAssignment is an app account token to set on the purchase with an original transaction identifier
+type AppAccountTokenAssignment struct {
+	OriginalTransactionID string
+	AppAccountToken       string
+}
+
+// AppAccountTokenResult is the outcome of one AppAccountTokenAssignment; Err is nil if the token was set
+type AppAccountTokenResult struct {
+	AppAccountTokenAssignment
+	Err error
+}
+
+// BackfillAppAccountTokens sets the app account tokens of many purchases, sending up to concurrency requests at once
+// within the client's rate limit. Every assignment is attempted; one failing doesn't stop the others. The results are
+// in the order of assignments, and the returned error joins the failures, or is nil if every token was set.
+// Once ctx is done, the remaining assignments fail with its error.
+func (c *AppStoreServerAPIClient) BackfillAppAccountTokens(ctx context.Context, assignments []AppAccountTokenAssignment, concurrency int) ([]AppAccountTokenResult, error) {
+	if concurrency <= 0 {
+		concurrency = DefaultAppAccountTokenConcurrency
+	}
+
+	results := make([]AppAccountTokenResult, len(assignments))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(concurrency, len(assignments)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = AppAccountTokenResult{
+					AppAccountTokenAssignment: assignments[i],
+					Err:                       c.setAssignedAppAccountToken(ctx, assignments[i]),
+				}
+			}
+		}()
+	}
+	for i := range assignments {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var errs []error
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, result.Err)
+		}
+	}
+	if len(errs) > 0 {
+		c.logger.LogAttrs(ctx, slog.LevelWarn, "failed to set some app account tokens",
+			slog.Int("failed", len(errs)),
+			slog.Int("total", len(assignments)),
+		)
+	}
+	return results, errors.Join(errs...)
+}
+
+// setAssignedAppAccountToken sets one token for BackfillAppAccountTokens
+func (c *AppStoreServerAPIClient) setAssignedAppAccountToken(ctx context.Context, assignment AppAccountTokenAssignment) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to set app account token of %s: %w", assignment.OriginalTransactionID, err)
+	}
+	token := assignment.AppAccountToken
+	if err := c.SetAppAccountToken(ctx, assignment.OriginalTransactionID, &models.UpdateAppAccountTokenRequest{AppAccountToken: &token}); err != nil {
+		return fmt.Errorf("failed to set app account token of %s: %w", assignment.OriginalTransactionID, err)
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package appstore_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DotNetAge/appstore"
+	"github.com/DotNetAge/appstore/models"
+)
+
+// appAccountTokenServer is a Set App Account Token endpoint that answers 404 for original transaction identifiers
+// starting with 9 and delays each response by the number of milliseconds in the identifier's last two digits
+type appAccountTokenServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	tokens   map[string]string
+	requests atomic.Int32
+}
+
+// newAppAccountTokenServer starts an appAccountTokenServer
+func newAppAccountTokenServer(t *testing.T) *appAccountTokenServer {
+	t.Helper()
+	s := &appAccountTokenServer{tokens: map[string]string{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		originalTransactionID, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/inApps/v1/transactions/"), "/appAccountToken")
+		if r.Method != http.MethodPut || !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if delay, err := strconv.Atoi(originalTransactionID[max(len(originalTransactionID)-2, 0):]); err == nil {
+			time.Sleep(time.Duration(delay) * time.Millisecond)
+		}
+		if strings.HasPrefix(originalTransactionID, "9") {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"errorCode":%d,"errorMessage":"Original transaction id not found."}`, int(models.APIErrorOriginalTransactionIDNotFound))
+			return
+		}
+
+		var request map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || len(request) != 1 || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.tokens[originalTransactionID], _ = request["appAccountToken"].(string)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// token returns the app account token set on the purchase
+func (s *appAccountTokenServer) token(originalTransactionID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokens[originalTransactionID]
+}
+
+// appAccountToken returns a distinct UUID for i
+func appAccountToken(i int) string {
+	return fmt.Sprintf("7e3fb20b-4cdb-47cc-936d-%012d", i)
+}
+
+func TestSetAppAccountToken(t *testing.T) {
+	server := newAppAccountTokenServer(t)
+	client := newInterceptorTestClient(t, server.Server)
+
+	tests := []struct {
+		name                  string
+		originalTransactionID string
+		request               *models.UpdateAppAccountTokenRequest
+		wantErr               error
+		wantSent              bool
+	}{
+		{name: "set", originalTransactionID: "1000", request: &models.UpdateAppAccountTokenRequest{AppAccountToken: ptr(appAccountToken(1))}, wantSent: true},
+		{name: "uppercase UUID", originalTransactionID: "1001", request: &models.UpdateAppAccountTokenRequest{AppAccountToken: ptr(strings.ToUpper(appAccountToken(2)))}, wantSent: true},
+		{name: "not found", originalTransactionID: "9000", request: &models.UpdateAppAccountTokenRequest{AppAccountToken: ptr(appAccountToken(3))}, wantErr: models.APIErrorOriginalTransactionIDNotFound, wantSent: true},
+		{name: "not a UUID", originalTransactionID: "1002", request: &models.UpdateAppAccountTokenRequest{AppAccountToken: ptr("customer-42")}, wantErr: models.ErrInvalidRequest},
+		{name: "UUID without hyphens", originalTransactionID: "1003", request: &models.UpdateAppAccountTokenRequest{AppAccountToken: ptr(strings.ReplaceAll(appAccountToken(4), "-", ""))}, wantErr: models.ErrInvalidRequest},
+		{name: "no token", originalTransactionID: "1004", request: &models.UpdateAppAccountTokenRequest{}, wantErr: models.ErrInvalidRequest},
+		{name: "no request", originalTransactionID: "1005", wantErr: models.ErrInvalidRequest},
+		{name: "invalid transaction identifier", originalTransactionID: "../1006", request: &models.UpdateAppAccountTokenRequest{AppAccountToken: ptr(appAccountToken(5))}, wantErr: models.ErrInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := server.requests.Load()
+			err := client.SetAppAccountToken(context.Background(), tt.originalTransactionID, tt.request)
+			if tt.wantErr == nil && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got := server.requests.Load() > sent; got != tt.wantSent {
+				t.Errorf("request sent = %v, want %v", got, tt.wantSent)
+			}
+			if tt.wantErr == nil {
+				if got := server.token(tt.originalTransactionID); got != *tt.request.AppAccountToken {
+					t.Errorf("server has token %q, want %q", got, *tt.request.AppAccountToken)
+				}
+			}
+		})
+	}
+}
+
+func TestBackfillAppAccountTokens(t *testing.T) {
+	server := newAppAccountTokenServer(t)
+	client := newInterceptorTestClient(t, server.Server)
+
+	// Later assignments answer sooner, so the results only come back in order if the client puts them in order
+	var assignments []appstore.AppAccountTokenAssignment
+	for i := range 12 {
+		originalTransactionID := fmt.Sprintf("10%02d", 12-i)
+		if i%4 == 1 {
+			originalTransactionID = fmt.Sprintf("90%02d", 12-i)
+		}
+		assignments = append(assignments, appstore.AppAccountTokenAssignment{OriginalTransactionID: originalTransactionID, AppAccountToken: appAccountToken(i)})
+	}
+	assignments = append(assignments, appstore.AppAccountTokenAssignment{OriginalTransactionID: "1013", AppAccountToken: "not-a-uuid"})
+
+	results, err := client.BackfillAppAccountTokens(context.Background(), assignments, 4)
+	if err == nil {
+		t.Fatal("no error for the failed assignments")
+	}
+	if len(results) != len(assignments) {
+		t.Fatalf("got %d results, want %d", len(results), len(assignments))
+	}
+
+	failed := 0
+	for i, result := range results {
+		assignment := assignments[i]
+		if result.AppAccountTokenAssignment != assignment {
+			t.Errorf("result %d is for %+v, want %+v", i, result.AppAccountTokenAssignment, assignment)
+		}
+		switch {
+		case strings.HasPrefix(assignment.OriginalTransactionID, "9"):
+			failed++
+			if !errors.Is(result.Err, models.APIErrorOriginalTransactionIDNotFound) || !strings.Contains(result.Err.Error(), assignment.OriginalTransactionID) {
+				t.Errorf("result %d error = %v, want OriginalTransactionIdNotFound naming the purchase", i, result.Err)
+			}
+		case assignment.AppAccountToken == "not-a-uuid":
+			failed++
+			if !errors.Is(result.Err, models.ErrInvalidRequest) {
+				t.Errorf("result %d error = %v, want a validation error", i, result.Err)
+			}
+		default:
+			if result.Err != nil {
+				t.Errorf("result %d error = %v", i, result.Err)
+			}
+			if got := server.token(assignment.OriginalTransactionID); got != assignment.AppAccountToken {
+				t.Errorf("server has token %q for %s, want %q", got, assignment.OriginalTransactionID, assignment.AppAccountToken)
+			}
+		}
+		if result.Err != nil && !errors.Is(err, result.Err) {
+			t.Errorf("the returned error doesn't include result %d: %v", i, result.Err)
+		}
+	}
+	if failed != 4 {
+		t.Errorf("%d assignments failed, want 4", failed)
+	}
+}
+
+func TestBackfillAppAccountTokensSucceeds(t *testing.T) {
+	server := newAppAccountTokenServer(t)
+	client := newInterceptorTestClient(t, server.Server)
+
+	assignments := []appstore.AppAccountTokenAssignment{
+		{OriginalTransactionID: "1000", AppAccountToken: appAccountToken(0)},
+		{OriginalTransactionID: "1001", AppAccountToken: appAccountToken(1)},
+	}
+	// A concurrency of zero uses the default
+	results, err := client.BackfillAppAccountTokens(context.Background(), assignments, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, result := range results {
+		if result.Err != nil || result.AppAccountTokenAssignment != assignments[i] {
+			t.Errorf("result %d = %+v", i, result)
+		}
+	}
+
+	if results, err := client.BackfillAppAccountTokens(context.Background(), nil, 4); err != nil || len(results) != 0 {
+		t.Errorf("no assignments: got %v, %v", results, err)
+	}
+}
+
+func TestBackfillAppAccountTokensCanceled(t *testing.T) {
+	server := newAppAccountTokenServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancel once the second request has completed
+	var completed atomic.Int32
+	cancelAfterTwo := appstore.InterceptorFunc(func(ctx context.Context, call *appstore.Call, next appstore.Invoker) error {
+		err := next(ctx, call)
+		if completed.Add(1) == 2 {
+			cancel()
+		}
+		return err
+	})
+	client := newInterceptorTestClient(t, server.Server, appstore.WithInterceptors(cancelAfterTwo))
+
+	var assignments []appstore.AppAccountTokenAssignment
+	for i := range 6 {
+		assignments = append(assignments, appstore.AppAccountTokenAssignment{OriginalTransactionID: strconv.Itoa(1000 + i), AppAccountToken: appAccountToken(i)})
+	}
+
+	// One worker sends the assignments in order, so exactly the first two are sent
+	results, err := client.BackfillAppAccountTokens(ctx, assignments, 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+	if len(results) != len(assignments) {
+		t.Fatalf("got %d results, want %d", len(results), len(assignments))
+	}
+	for i, result := range results {
+		if result.AppAccountTokenAssignment != assignments[i] {
+			t.Errorf("result %d is for %+v", i, result.AppAccountTokenAssignment)
+		}
+		if i < 2 {
+			if result.Err != nil {
+				t.Errorf("result %d error = %v, want nil", i, result.Err)
+			}
+			continue
+		}
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("result %d error = %v, want context.Canceled", i, result.Err)
+		}
+	}
+	if got := server.requests.Load(); got != 2 {
+		t.Errorf("sent %d requests, want 2", got)
+	}
+}
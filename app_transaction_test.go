@@ -0,0 +1,157 @@
+package appstore_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/DotNetAge/appstore"
+	"github.com/DotNetAge/appstore/appstoretest"
+	"github.com/DotNetAge/appstore/models"
+)
+
+// newAppTransactionServer starts a server answering Get App Transaction Info for transaction 1000 with the response,
+// and AppTransactionDoesNotExistError for any other transaction
+func newAppTransactionServer(t *testing.T, response models.AppTransactionInfoResponse) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodGet || r.URL.Path != "/inApps/v1/transactions/appTransactions/1000" {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"errorCode":    int(models.APIErrorAppTransactionDoesNotExist),
+				"errorMessage": "No AppTransaction exists for the customer.",
+			})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestGetAppTransactionInfo(t *testing.T) {
+	server, requests := newAppTransactionServer(t, models.AppTransactionInfoResponse{SignedAppTransactionInfo: ptr("signed-app-transaction")})
+	client := newInterceptorTestClient(t, server)
+
+	response, err := client.GetAppTransactionInfo(context.Background(), "1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.SignedAppTransactionInfo == nil || *response.SignedAppTransactionInfo != "signed-app-transaction" {
+		t.Errorf("signedAppTransactionInfo = %v", response.SignedAppTransactionInfo)
+	}
+
+	if _, err := client.GetAppTransactionInfo(context.Background(), "2000"); !errors.Is(err, models.APIErrorAppTransactionDoesNotExist) || !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("unknown transaction: error = %v, want AppTransactionDoesNotExist", err)
+	}
+
+	sent := requests.Load()
+	if _, err := client.GetAppTransactionInfo(context.Background(), "1000/../1"); !errors.Is(err, models.ErrInvalidRequest) {
+		t.Errorf("invalid transaction identifier: error = %v, want a validation error", err)
+	}
+	if requests.Load() != sent {
+		t.Error("sent a request for an invalid transaction identifier")
+	}
+}
+
+func TestAppStoreServerClientGetAppTransactionInfo(t *testing.T) {
+	ca, err := appstoretest.NewCertificateAuthority()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootCertPath := writeRootCertificate(t, ca)
+	sandbox := models.EnvironmentSandbox
+	production := models.EnvironmentProduction
+	sign := func(appTransaction models.AppTransaction) *string {
+		signed, err := ca.SignAppTransaction(appTransaction)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &signed
+	}
+	valid := models.AppTransaction{
+		ReceiptType:                &sandbox,
+		AppAppleId:                 ptr(int64(1234567890)),
+		BundleId:                   ptr("com.example"),
+		ApplicationVersion:         ptr("2.1"),
+		OriginalApplicationVersion: ptr("1.0"),
+		OriginalPurchaseDate:       ptr(int64(1700000000000)),
+	}
+	otherBundle := valid
+	otherBundle.BundleId = ptr("com.example.other")
+	otherEnvironment := valid
+	otherEnvironment.ReceiptType = &production
+
+	tests := []struct {
+		name     string
+		response models.AppTransactionInfoResponse
+		status   appstore.VerificationStatus
+		wantErr  bool
+	}{
+		{name: "valid", response: models.AppTransactionInfoResponse{SignedAppTransactionInfo: sign(valid)}},
+		{name: "other bundle", response: models.AppTransactionInfoResponse{SignedAppTransactionInfo: sign(otherBundle)}, status: appstore.VerificationStatusInvalidAppIdentifier, wantErr: true},
+		{name: "other environment", response: models.AppTransactionInfoResponse{SignedAppTransactionInfo: sign(otherEnvironment)}, status: appstore.VerificationStatusInvalidEnvironment, wantErr: true},
+		{name: "tampered", response: models.AppTransactionInfoResponse{SignedAppTransactionInfo: ptr(tamper(t, *sign(valid), "originalApplicationVersion", "0.1"))}, status: appstore.VerificationStatusVerificationFailure, wantErr: true},
+		{name: "no signed app transaction", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newAppTransactionServer(t, tt.response)
+			client := appstore.NewAppStoreServerClient("KEY_ID", "ISSUER_ID", "com.example", 1234567890, nil, rootCertPath, sandbox,
+				appstore.WithBaseURL(server.URL), appstore.WithRateLimiter(nil), appstore.WithSigner(newSigningKeys(t, "KEY_ID")[0].Signer))
+
+			appTransaction, err := client.GetAppTransactionInfo(context.Background(), "1000")
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if *appTransaction.OriginalApplicationVersion != "1.0" || *appTransaction.ApplicationVersion != "2.1" || *appTransaction.OriginalPurchaseDate != 1700000000000 {
+					t.Errorf("app transaction = %+v", appTransaction)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("accepted the app transaction")
+			}
+			var verificationErr *appstore.VerificationException
+			if tt.status != 0 && (!errors.As(err, &verificationErr) || verificationErr.Status != tt.status) {
+				t.Errorf("error = %v, want status %s", err, tt.status)
+			}
+		})
+	}
+}
+
+func TestAppStoreServerClientGetAppTransactionInfoFromFake(t *testing.T) {
+	ca, err := appstoretest.NewCertificateAuthority()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := appstoretest.NewServer(appstoretest.WithCertificateAuthority(ca))
+	defer server.Close()
+	transaction := server.AddTransaction("customer", models.JWSTransactionDecodedPayload{})
+	stored := server.SetAppTransaction("customer", models.AppTransaction{OriginalApplicationVersion: ptr("1.0")})
+	other := server.AddTransaction("other customer", models.JWSTransactionDecodedPayload{})
+
+	client := appstore.NewAppStoreServerClient(appstoretest.DefaultKeyID, appstoretest.DefaultIssuerID, server.BundleID(), server.AppAppleID(),
+		nil, writeRootCertificate(t, ca), server.Environment(),
+		appstore.WithBaseURL(server.URL), appstore.WithRateLimiter(nil), appstore.WithSigner(newSigningKeys(t, "KEY_ID")[0].Signer))
+
+	appTransaction, err := client.GetAppTransactionInfo(context.Background(), *transaction.TransactionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *appTransaction.BundleId != server.BundleID() || *appTransaction.OriginalApplicationVersion != "1.0" || *appTransaction.OriginalPurchaseDate != *stored.OriginalPurchaseDate {
+		t.Errorf("app transaction = %+v", appTransaction)
+	}
+
+	if _, err := client.GetAppTransactionInfo(context.Background(), *other.TransactionId); !errors.Is(err, models.APIErrorAppTransactionDoesNotExist) {
+		t.Errorf("customer without an app transaction: error = %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package appstoretest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/DotNetAge/appstore"
+	"github.com/DotNetAge/appstore/internal/apple"
+	"github.com/DotNetAge/appstore/models"
+)
+
+// CertificateAuthority is a stand-in for Apple's certificate hierarchy: a root, an intermediate carrying
+// Apple's WWDR marker OID and a leaf carrying the App Store signing marker OID. Data it signs passes the
+// full chain verification of an appstore.SignedDataVerifier that trusts its root.
+//
+// The certificates are valid from 2000 until twenty years after creation, so payloads with old signed dates
+// verify too.
+type CertificateAuthority struct {
+	root         *x509.Certificate
+	intermediate *x509.Certificate
+	leaf         *x509.Certificate
+	leafKey      *ecdsa.PrivateKey
+}
+
+// NewCertificateAuthority generates a root, intermediate and leaf certificate with fresh P-256 keys
+func NewCertificateAuthority() (*CertificateAuthority, error) {
+	notBefore := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	notAfter := time.Now().AddDate(20, 0, 0)
+
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate root key: %w", err)
+	}
+	root, err := createCertificate(&x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "appstoretest Root CA", Organization: []string{"appstoretest"}},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, nil, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create root certificate: %w", err)
+	}
+
+	intermediateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate intermediate key: %w", err)
+	}
+	intermediate, err := createCertificate(&x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "appstoretest Intermediate CA", Organization: []string{"appstoretest"}},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+		ExtraExtensions:       []pkix.Extension{markerExtension(apple.IntermediateCertificateOID)},
+	}, root, &intermediateKey.PublicKey, rootKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create intermediate certificate: %w", err)
+	}
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate leaf key: %w", err)
+	}
+	leaf, err := createCertificate(&x509.Certificate{
+		SerialNumber:          big.NewInt(3),
+		Subject:               pkix.Name{CommonName: "appstoretest App Store Signing", Organization: []string{"appstoretest"}},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		ExtraExtensions:       []pkix.Extension{markerExtension(apple.LeafCertificateOID)},
+	}, intermediate, &leafKey.PublicKey, intermediateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create leaf certificate: %w", err)
+	}
+
+	return &CertificateAuthority{
+		root:         root,
+		intermediate: intermediate,
+		leaf:         leaf,
+		leafKey:      leafKey,
+	}, nil
+}
+
+// createCertificate signs template with the key of parent, or self-signs it if parent is nil
+func createCertificate(template, parent *x509.Certificate, publicKey *ecdsa.PublicKey, signer *ecdsa.PrivateKey) (*x509.Certificate, error) {
+	if parent == nil {
+		parent = template
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, publicKey, signer)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
+
+// markerExtension returns a non-critical extension with a NULL value, the form Apple uses for its marker OIDs
+func markerExtension(oid asn1.ObjectIdentifier) pkix.Extension {
+	return pkix.Extension{Id: oid, Value: asn1.NullBytes}
+}
+
+// RootCertificate returns the DER encoding of the root certificate
+func (ca *CertificateAuthority) RootCertificate() []byte {
+	return ca.root.Raw
+}
+
+// RootCertificates returns the root certificate in the form NewSignedDataVerifier and the clients take
+func (ca *CertificateAuthority) RootCertificates() [][]byte {
+	return [][]byte{ca.root.Raw}
+}
+
+// Chain returns the DER encodings of the leaf, intermediate and root certificates, in the order of an x5c header
+func (ca *CertificateAuthority) Chain() [][]byte {
+	return [][]byte{ca.leaf.Raw, ca.intermediate.Raw, ca.root.Raw}
+}
+
+// NewVerifier creates a SignedDataVerifier that trusts the root certificate, with online checks disabled
+func (ca *CertificateAuthority) NewVerifier(environment models.Environment, bundleID string, appAppleID *int64, options ...appstore.VerifierOption) (*appstore.SignedDataVerifier, error) {
+	return appstore.NewSignedDataVerifier(ca.RootCertificates(), false, environment, bundleID, appAppleID, options...)
+}
+
+// Sign returns the compact JWS serialization of payload, signed with ES256 by the leaf key and carrying the chain
+func (ca *CertificateAuthority) Sign(payload interface{}) (string, error) {
+	return signJWS(payload, ca.leafKey, ca.Chain())
+}
+
+// SignTransaction signs a transaction the way Apple signs signedTransactionInfo
+func (ca *CertificateAuthority) SignTransaction(transaction models.JWSTransactionDecodedPayload) (string, error) {
+	return ca.Sign(transaction)
+}
+
+// SignRenewalInfo signs renewal information the way Apple signs signedRenewalInfo
+func (ca *CertificateAuthority) SignRenewalInfo(renewalInfo models.JWSRenewalInfoDecodedPayload) (string, error) {
+	return ca.Sign(renewalInfo)
+}
+
+// SignNotification signs a notification the way Apple signs the signedPayload of App Store Server Notifications V2
+func (ca *CertificateAuthority) SignNotification(notification models.ResponseBodyV2DecodedPayload) (string, error) {
+	return ca.Sign(notification)
+}
+
+// SignAppTransaction signs an app transaction the way Apple signs signedAppTransactionInfo
+func (ca *CertificateAuthority) SignAppTransaction(appTransaction models.AppTransaction) (string, error) {
+	return ca.Sign(appTransaction)
+}
+
+// SignRealtimeRequest signs a real-time retention message request the way Apple signs its signedPayload
+func (ca *CertificateAuthority) SignRealtimeRequest(request models.DecodedRealtimeRequestBody) (string, error) {
+	return ca.Sign(request)
+}
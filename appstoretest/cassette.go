@@ -0,0 +1,323 @@
+package appstoretest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/DotNetAge/appstore"
+)
+
+const (
+	// redacted replaces the values of redacted headers in recorded requests
+	redacted = "REDACTED"
+	// massExtensionPath is the path of mass renewal extensions, whose request identifiers are ignored when matching
+	massExtensionPath = "/inApps/v1/subscriptions/extend/mass"
+)
+
+// CassetteMode selects whether a Cassette replays recorded exchanges, records new ones, or both
+type CassetteMode int
+
+const (
+	// ModeReplay answers requests from the cassette file only; a request without a recorded match fails
+	ModeReplay CassetteMode = iota
+	// ModeRecord sends every request and records the exchange, replacing the cassette file on Save
+	ModeRecord
+	// ModeReplayOrRecord answers requests from the cassette file and sends and records those without a match
+	ModeReplayOrRecord
+)
+
+// ErrNoInteraction is returned by a Cassette in ModeReplay for a request it has no unused recording of
+var ErrNoInteraction = errors.New("appstoretest: no recorded interaction matches the request")
+
+// Interaction is one recorded request and the response it got
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request as stored in a cassette, with redacted headers masked. The body is stored as base64
+// so binary bodies, such as uploaded message images, survive the JSON file unchanged.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// RecordedResponse is a response as stored in a cassette. The body is stored as base64 so binary bodies survive the
+// JSON file unchanged.
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       []byte      `json:"body,omitempty"`
+}
+
+// CassetteOption configures a Cassette
+type CassetteOption func(*Cassette)
+
+// WithCassetteMode sets the mode of the Cassette; the default is ModeReplay
+func WithCassetteMode(mode CassetteMode) CassetteOption {
+	return func(c *Cassette) {
+		c.mode = mode
+	}
+}
+
+// WithCassetteTransport sets the transport that sends requests being recorded; the default is http.DefaultTransport
+func WithCassetteTransport(transport http.RoundTripper) CassetteOption {
+	return func(c *Cassette) {
+		c.transport = transport
+	}
+}
+
+// WithRedactedHeaders masks more request headers in recordings, in addition to Authorization
+func WithRedactedHeaders(names ...string) CassetteOption {
+	return func(c *Cassette) {
+		for _, name := range names {
+			c.redactedHeaders = append(c.redactedHeaders, http.CanonicalHeaderKey(name))
+		}
+	}
+}
+
+// Cassette is an http.RoundTripper that records the exchanges of an AppStoreServerAPIClient to a file and replays
+// them offline, so real sandbox traffic can become a regression test:
+//
+//	cassette, err := appstoretest.NewCassette("testdata/refund-lookup.json", appstoretest.WithCassetteMode(mode))
+//	client, err := appstore.NewAppStoreServerAPIClientWithOptions(key, keyID, issuerID, bundleID, environment,
+//		cassette.ClientOption())
+//	...
+//	err = cassette.Save()
+//
+// The Authorization header is never written to the file. Requests match a recording by method, path, query
+// parameters in any order and body, with JSON bodies compared regardless of key order and whitespace; the
+// scheme, host and headers are ignored, because tokens differ on every run. The request identifier of a mass renewal
+// extension is ignored too, in the request body and in the status lookup path, because the client generates a random
+// one when the request has none. Identical requests replay their recordings in the order they were recorded.
+type Cassette struct {
+	path            string
+	mode            CassetteMode
+	transport       http.RoundTripper
+	redactedHeaders []string
+
+	mu           sync.Mutex
+	interactions []*Interaction
+	used         []bool
+}
+
+// NewCassette creates a Cassette backed by the file at path. In ModeReplay the file must exist; in the other modes a
+// missing file starts an empty cassette.
+func NewCassette(path string, options ...CassetteOption) (*Cassette, error) {
+	c := &Cassette{
+		path:            path,
+		transport:       http.DefaultTransport,
+		redactedHeaders: []string{"Authorization"},
+	}
+	for _, option := range options {
+		option(c)
+	}
+
+	if c.mode == ModeRecord {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && c.mode == ModeReplayOrRecord:
+		return c, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	if err := json.Unmarshal(data, &c.interactions); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	c.used = make([]bool, len(c.interactions))
+	return c, nil
+}
+
+// Client returns an http.Client that sends requests through the Cassette
+func (c *Cassette) Client() *http.Client {
+	return &http.Client{Transport: c}
+}
+
+// ClientOption returns the option that makes an AppStoreServerAPIClient send requests through the Cassette
+func (c *Cassette) ClientOption() appstore.ClientOption {
+	return appstore.WithHTTPClient(c.Client())
+}
+
+// Interactions returns the exchanges in the Cassette
+func (c *Cassette) Interactions() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	interactions := make([]Interaction, len(c.interactions))
+	for i, interaction := range c.interactions {
+		interactions[i] = *interaction
+	}
+	return interactions
+}
+
+// Save writes the Cassette to its file, creating parent directories as needed. It does nothing in ModeReplay.
+func (c *Cassette) Save() error {
+	if c.mode == ModeReplay {
+		return nil
+	}
+
+	c.mu.Lock()
+	data, err := json.MarshalIndent(c.interactions, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("failed to save cassette: %w", err)
+	}
+	if err := os.WriteFile(c.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to save cassette: %w", err)
+	}
+	return nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("appstoretest: failed to read request body: %w", err)
+		}
+	}
+
+	key := matchKey(req.Method, req.URL.Path, req.URL.Query().Encode(), body)
+	if c.mode != ModeRecord {
+		if interaction := c.replay(key); interaction != nil {
+			return interaction.Response.toResponse(req), nil
+		}
+		if c.mode == ModeReplay {
+			return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, req.Method, req.URL.RequestURI())
+		}
+	}
+	return c.record(req, body)
+}
+
+// replay returns the first unused recording matching key and marks it used, or nil if there is none
+func (c *Cassette) replay(key string) *Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, interaction := range c.interactions {
+		if c.used[i] || interaction.Request.matchKey() != key {
+			continue
+		}
+		c.used[i] = true
+		return interaction
+	}
+	return nil
+}
+
+// record sends the request with the Cassette's transport and appends the exchange
+func (c *Cassette) record(req *http.Request, body []byte) (*http.Response, error) {
+	outgoing := req.Clone(req.Context())
+	if body != nil {
+		outgoing.Body = io.NopCloser(bytes.NewReader(body))
+		outgoing.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	resp, err := c.transport.RoundTrip(outgoing)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("appstoretest: failed to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	header := req.Header.Clone()
+	for _, name := range c.redactedHeaders {
+		if _, ok := header[name]; ok {
+			header[name] = []string{redacted}
+		}
+	}
+	interaction := &Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: header,
+			Body:   body,
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header.Clone(),
+			Body:       respBody,
+		},
+	}
+
+	c.mu.Lock()
+	c.interactions = append(c.interactions, interaction)
+	c.used = append(c.used, true)
+	c.mu.Unlock()
+	return resp, nil
+}
+
+// matchKey returns the key a recorded request is matched by
+func (r RecordedRequest) matchKey() string {
+	path, query := r.URL, ""
+	if u, err := url.Parse(r.URL); err == nil {
+		path, query = u.Path, u.Query().Encode()
+	}
+	return matchKey(r.Method, path, query, r.Body)
+}
+
+// toResponse returns the recorded response as the answer to req
+func (r RecordedResponse) toResponse(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+// matchKey combines the parts of a request that identify it across runs. query must be encoded with
+// url.Values.Encode, which sorts it by key, and JSON bodies are re-encoded, which sorts object keys.
+func matchKey(method, path, query string, body []byte) string {
+	var decoded interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if len(body) > 0 && decoder.Decode(&decoded) == nil {
+		if path == massExtensionPath {
+			if object, ok := decoded.(map[string]interface{}); ok {
+				delete(object, "requestIdentifier")
+			}
+		}
+		if canonical, err := json.Marshal(decoded); err == nil {
+			body = canonical
+		}
+	}
+
+	// The status of a mass renewal extension is looked up at massExtensionPath/{productId}/{requestIdentifier}
+	if rest, ok := strings.CutPrefix(path, massExtensionPath+"/"); ok {
+		if productID, _, ok := strings.Cut(rest, "/"); ok {
+			path = massExtensionPath + "/" + productID + "/{requestIdentifier}"
+		}
+	}
+	return method + " " + path + "?" + query + "\n" + string(body)
+}
@@ -0,0 +1,98 @@
+package appstoretest_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/DotNetAge/appstore"
+	"github.com/DotNetAge/appstore/appstoretest"
+	"github.com/DotNetAge/appstore/models"
+)
+
+// image is a PNG signature followed by bytes that aren't valid UTF-8
+var image = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0x00, 0xff, 0xfe, 0x80}
+
+// cassetteResults are the results of cassetteScenario, compared between recording and replay
+type cassetteResults struct {
+	signedTransactionInfo string
+	massExtensionStatus   *models.MassExtendRenewalDateStatusResponse
+}
+
+// cassetteScenario sends a JSON lookup, a binary upload and a mass renewal extension without a request identifier
+func cassetteScenario(t *testing.T, client *appstore.AppStoreServerAPIClient, transactionID string) cassetteResults {
+	t.Helper()
+	ctx := context.Background()
+
+	transaction, err := client.GetTransactionInfo(ctx, transactionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.RetentionMessaging().UploadImage(ctx, "a3f1c2d4-5b6e-4f70-8a9b-0c1d2e3f4a5b", image); err != nil {
+		t.Fatal(err)
+	}
+
+	days, reason, productID := 3, models.ExtendReasonCodeCustomerSatisfaction, "com.example.monthly"
+	extension, err := client.ExtendRenewalDateForAllActiveSubscribers(ctx, &models.MassExtendRenewalDateRequest{
+		ExtendByDays:     &days,
+		ExtendReasonCode: &reason,
+		ProductId:        &productID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := client.GetStatusOfSubscriptionRenewalDateExtensions(ctx, *extension.RequestIdentifier, productID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cassetteResults{signedTransactionInfo: *transaction.SignedTransactionInfo, massExtensionStatus: status}
+}
+
+func TestCassetteRecordThenReplay(t *testing.T) {
+	server := appstoretest.NewServer()
+	defer server.Close()
+	transactionID := *server.AddTransaction("customer", models.JWSTransactionDecodedPayload{}).TransactionId
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	recorder, err := appstoretest.NewCassette(path, appstoretest.WithCassetteMode(appstoretest.ModeRecord))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := server.NewClient(recorder.ClientOption())
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := cassetteScenario(t, client, transactionID)
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replay with the server gone, so every answer must come from the file
+	server.Close()
+	player, err := appstoretest.NewCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var uploaded bool
+	for _, interaction := range player.Interactions() {
+		if _, ok := interaction.Request.Header["Authorization"]; ok && interaction.Request.Header.Get("Authorization") != "REDACTED" {
+			t.Errorf("%s %s recorded the Authorization header", interaction.Request.Method, interaction.Request.URL)
+		}
+		if interaction.Request.Method == "PUT" {
+			uploaded = bytes.Equal(interaction.Request.Body, image)
+		}
+	}
+	if !uploaded {
+		t.Error("the uploaded image didn't survive the cassette file")
+	}
+
+	client, err = server.NewClient(player.ClientOption())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed := cassetteScenario(t, client, transactionID); !reflect.DeepEqual(replayed, recorded) {
+		t.Errorf("replayed %+v, recorded %+v", replayed, recorded)
+	}
+}
@@ -0,0 +1,802 @@
+package appstoretest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DotNetAge/appstore/internal/apple"
+	"github.com/DotNetAge/appstore/models"
+)
+
+const (
+	// maxExtensionsPerYear is how many times Apple lets you extend a subscription within a year
+	maxExtensionsPerYear = 2
+	// dayMillis is the length of a day in milliseconds
+	dayMillis = 24 * 60 * 60 * 1000
+)
+
+var (
+	productTypes = map[string]models.Type{
+		string(models.ProductTypeAutoRenewable): models.TypeAutoRenewableSubscription,
+		string(models.ProductTypeNonRenewable):  models.TypeNonRenewingSubscription,
+		string(models.ProductTypeConsumable):    models.TypeConsumable,
+		string(models.ProductTypeNonConsumable): models.TypeNonConsumable,
+	}
+	validDeliveryStatusesV2 = map[models.DeliveryStatusV2]bool{
+		models.DeliveryStatusV2Delivered:               true,
+		models.DeliveryStatusV2UndeliveredQualityIssue: true,
+		models.DeliveryStatusV2UndeliveredWrongItem:    true,
+		models.DeliveryStatusV2UndeliveredServerOutage: true,
+		models.DeliveryStatusV2UndeliveredOther:        true,
+	}
+	validRefundPreferencesV2 = map[models.RefundPreferenceV2]bool{
+		models.RefundPreferenceV2Decline:       true,
+		models.RefundPreferenceV2GrantFull:     true,
+		models.RefundPreferenceV2GrantProrated: true,
+	}
+)
+
+// getTransactionHistory serves Get Transaction History, versions 1 and 2
+func (s *Server) getTransactionHistory(w http.ResponseWriter, r *http.Request) {
+	if version := r.PathValue("version"); version != "v1" && version != "v2" {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, apiErr := s.findTransaction(r.PathValue("transactionId"))
+	if apiErr != 0 {
+		writeError(w, apiErr)
+		return
+	}
+
+	query := r.URL.Query()
+	filter, apiErr := parseHistoryFilter(query)
+	if apiErr != 0 {
+		writeError(w, apiErr)
+		return
+	}
+	offset, ok := parsePageToken(query.Get("revision"))
+	if !ok {
+		writeError(w, models.APIErrorInvalidRequestRevision)
+		return
+	}
+
+	var transactions []models.JWSTransactionDecodedPayload
+	for _, transaction := range s.customerTransactions(record.customerID) {
+		if filter.matches(transaction) {
+			transactions = append(transactions, transaction)
+		}
+	}
+	if filter.descending {
+		for i, j := 0, len(transactions)-1; i < j; i, j = i+1, j-1 {
+			transactions[i], transactions[j] = transactions[j], transactions[i]
+		}
+	}
+
+	items, revision, hasMore := page(transactions, offset)
+	response := models.HistoryResponse{
+		Revision:           &revision,
+		HasMore:            &hasMore,
+		BundleId:           ptr(s.bundleID),
+		AppAppleId:         ptr(s.appAppleID),
+		Environment:        ptr(s.environment),
+		SignedTransactions: []string{},
+	}
+	for _, transaction := range items {
+		response.SignedTransactions = append(response.SignedTransactions, s.signedTransaction(transaction))
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// historyFilter holds the query parameters of Get Transaction History
+type historyFilter struct {
+	startDate, endDate           *int64
+	productIDs                   map[string]bool
+	types                        map[models.Type]bool
+	subscriptionGroupIdentifiers map[string]bool
+	inAppOwnershipType           *models.InAppOwnershipType
+	revoked                      *bool
+	descending                   bool
+}
+
+// parseHistoryFilter parses the query parameters of Get Transaction History
+func parseHistoryFilter(query map[string][]string) (*historyFilter, models.APIError) {
+	filter := &historyFilter{}
+	get := func(name string) string {
+		if values := query[name]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	if value := get("startDate"); value != "" {
+		date, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, models.APIErrorInvalidStartDate
+		}
+		filter.startDate = &date
+	}
+	if value := get("endDate"); value != "" {
+		date, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, models.APIErrorInvalidEndDate
+		}
+		filter.endDate = &date
+	}
+	if filter.startDate != nil && filter.endDate != nil && *filter.startDate >= *filter.endDate {
+		return nil, models.APIErrorStartDateAfterEndDate
+	}
+
+	if ids := query["productId"]; len(ids) > 0 {
+		filter.productIDs = make(map[string]bool)
+		for _, id := range ids {
+			filter.productIDs[id] = true
+		}
+	}
+	if names := query["productType"]; len(names) > 0 {
+		filter.types = make(map[models.Type]bool)
+		for _, name := range names {
+			productType, ok := productTypes[name]
+			if !ok {
+				return nil, models.APIErrorInvalidProductType
+			}
+			filter.types[productType] = true
+		}
+	}
+	if ids := query["subscriptionGroupIdentifier"]; len(ids) > 0 {
+		filter.subscriptionGroupIdentifiers = make(map[string]bool)
+		for _, id := range ids {
+			filter.subscriptionGroupIdentifiers[id] = true
+		}
+	}
+
+	switch sort := get("sort"); sort {
+	case "", string(models.OrderAscending):
+	case string(models.OrderDescending):
+		filter.descending = true
+	default:
+		return nil, models.APIErrorInvalidSort
+	}
+
+	switch ownership := models.InAppOwnershipType(get("inAppOwnershipType")); ownership {
+	case "":
+	case models.InAppOwnershipTypeFamilyShared, models.InAppOwnershipTypePurchased:
+		filter.inAppOwnershipType = &ownership
+	default:
+		return nil, models.APIErrorInvalidInAppOwnershipType
+	}
+
+	if value := get("revoked"); value != "" {
+		revoked, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, models.APIErrorInvalidRevoked
+		}
+		filter.revoked = &revoked
+	}
+	return filter, 0
+}
+
+// matches reports whether the transaction passes the filter
+func (f *historyFilter) matches(transaction models.JWSTransactionDecodedPayload) bool {
+	purchaseDate := *transaction.PurchaseDate
+	switch {
+	case f.startDate != nil && purchaseDate < *f.startDate,
+		f.endDate != nil && purchaseDate >= *f.endDate,
+		f.productIDs != nil && !f.productIDs[*transaction.ProductId],
+		f.types != nil && !f.types[*transaction.Type],
+		f.subscriptionGroupIdentifiers != nil && (transaction.SubscriptionGroupIdentifier == nil || !f.subscriptionGroupIdentifiers[*transaction.SubscriptionGroupIdentifier]),
+		f.inAppOwnershipType != nil && *transaction.InAppOwnershipType != *f.inAppOwnershipType,
+		f.revoked != nil && (transaction.RevocationDate != nil) != *f.revoked:
+		return false
+	}
+	return true
+}
+
+// getTransactionInfo serves Get Transaction Info
+func (s *Server) getTransactionInfo(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, apiErr := s.findTransaction(r.PathValue("transactionId"))
+	if apiErr != 0 {
+		writeError(w, apiErr)
+		return
+	}
+	writeJSON(w, http.StatusOK, models.TransactionInfoResponse{
+		SignedTransactionInfo: ptr(s.signedTransaction(record.transaction)),
+	})
+}
+
+// getAppTransactionInfo serves Get App Transaction Info
+func (s *Server) getAppTransactionInfo(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, apiErr := s.findTransaction(r.PathValue("transactionId"))
+	if apiErr != 0 {
+		writeError(w, apiErr)
+		return
+	}
+	appTransaction, ok := s.appTransactions[record.customerID]
+	if !ok {
+		writeError(w, models.APIErrorAppTransactionDoesNotExist)
+		return
+	}
+	writeJSON(w, http.StatusOK, models.AppTransactionInfoResponse{
+		SignedAppTransactionInfo: ptr(s.signedAppTransaction(*appTransaction)),
+	})
+}
+
+// getAllSubscriptionStatuses serves Get All Subscription Statuses
+func (s *Server) getAllSubscriptionStatuses(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, apiErr := s.findTransaction(r.PathValue("transactionId"))
+	if apiErr != 0 {
+		writeError(w, apiErr)
+		return
+	}
+
+	var wanted map[models.Status]bool
+	if values := r.URL.Query()["status"]; len(values) > 0 {
+		wanted = make(map[models.Status]bool)
+		for _, value := range values {
+			status, err := strconv.Atoi(value)
+			if err != nil || status < int(models.StatusActive) || status > int(models.StatusRevoked) {
+				writeError(w, models.APIErrorInvalidStatus)
+				return
+			}
+			wanted[models.Status(status)] = true
+		}
+	}
+
+	// Group the latest transaction of each subscription by subscription group
+	groups := make(map[string][]models.LastTransactionsItem)
+	var groupOrder []string
+	seen := make(map[string]bool)
+	for _, transaction := range s.customerTransactions(record.customerID) {
+		originalID := *transaction.OriginalTransactionId
+		if *transaction.Type != models.TypeAutoRenewableSubscription || seen[originalID] {
+			continue
+		}
+		seen[originalID] = true
+
+		latest, _ := s.latestTransaction(originalID)
+		status := s.subscriptionStatus(latest.transaction)
+		if wanted != nil && !wanted[status] {
+			continue
+		}
+
+		group := ""
+		if latest.transaction.SubscriptionGroupIdentifier != nil {
+			group = *latest.transaction.SubscriptionGroupIdentifier
+		}
+		if _, ok := groups[group]; !ok {
+			groupOrder = append(groupOrder, group)
+		}
+		groups[group] = append(groups[group], models.LastTransactionsItem{
+			Status:                &status,
+			OriginalTransactionId: ptr(originalID),
+			SignedTransactionInfo: ptr(s.signedTransaction(latest.transaction)),
+			SignedRenewalInfo:     s.signedRenewalInfo(originalID),
+		})
+	}
+
+	response := models.StatusResponse{
+		Environment: ptr(s.environment),
+		BundleId:    ptr(s.bundleID),
+		AppAppleId:  ptr(s.appAppleID),
+		Data:        []models.SubscriptionGroupIdentifierItem{},
+	}
+	for _, group := range groupOrder {
+		response.Data = append(response.Data, models.SubscriptionGroupIdentifierItem{
+			SubscriptionGroupIdentifier: ptr(group),
+			LastTransactions:            groups[group],
+		})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// lookUpOrderID serves Look Up Order ID
+func (s *Server) lookUpOrderID(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	transactionIDs, ok := s.orders[r.PathValue("orderId")]
+	if !ok {
+		writeJSON(w, http.StatusOK, models.OrderLookupResponse{Status: ptr(models.OrderLookupStatusInvalid)})
+		return
+	}
+
+	response := models.OrderLookupResponse{Status: ptr(models.OrderLookupStatusValid), SignedTransactions: []string{}}
+	for _, id := range transactionIDs {
+		if record, ok := s.transactions[id]; ok {
+			response.SignedTransactions = append(response.SignedTransactions, s.signedTransaction(record.transaction))
+		}
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// getRefundHistory serves Get Refund History
+func (s *Server) getRefundHistory(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, apiErr := s.findTransaction(r.PathValue("transactionId"))
+	if apiErr != 0 {
+		writeError(w, apiErr)
+		return
+	}
+	offset, ok := parsePageToken(r.URL.Query().Get("revision"))
+	if !ok {
+		writeError(w, models.APIErrorInvalidRequestRevision)
+		return
+	}
+
+	var refunded []models.JWSTransactionDecodedPayload
+	for _, transaction := range s.customerTransactions(record.customerID) {
+		if transaction.RevocationDate != nil {
+			refunded = append(refunded, transaction)
+		}
+	}
+	sort.SliceStable(refunded, func(i, j int) bool {
+		return *refunded[i].RevocationDate < *refunded[j].RevocationDate
+	})
+
+	items, revision, hasMore := page(refunded, offset)
+	response := models.RefundHistoryResponse{Revision: &revision, HasMore: &hasMore, SignedTransactions: []string{}}
+	for _, transaction := range items {
+		response.SignedTransactions = append(response.SignedTransactions, s.signedTransaction(transaction))
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// extendSubscriptionRenewalDate serves Extend a Subscription Renewal Date
+func (s *Server) extendSubscriptionRenewalDate(w http.ResponseWriter, r *http.Request) {
+	var request models.ExtendRenewalDateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, models.APIErrorGeneralBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	originalID := r.PathValue("originalTransactionId")
+	if !apple.TransactionIDPattern.MatchString(originalID) {
+		writeError(w, models.APIErrorInvalidOriginalTransactionID)
+		return
+	}
+	if apiErr := checkExtension(request.ExtendByDays, request.ExtendReasonCode, request.RequestIdentifier); apiErr != 0 {
+		writeError(w, apiErr)
+		return
+	}
+	latest, ok := s.latestTransaction(originalID)
+	if !ok || *latest.transaction.OriginalTransactionId != originalID {
+		writeError(w, models.APIErrorOriginalTransactionIDNotFound)
+		return
+	}
+	if apiErr := s.extend(latest, *request.ExtendByDays); apiErr != 0 {
+		writeError(w, apiErr)
+		return
+	}
+
+	s.addNotification(Notification{
+		Payload: models.ResponseBodyV2DecodedPayload{
+			NotificationType: ptr(models.NotificationTypeV2RenewalExtended),
+			Data: &models.Data{
+				SignedTransactionInfo: ptr(s.signedTransaction(latest.transaction)),
+				SignedRenewalInfo:     s.signedRenewalInfo(originalID),
+				Status:                ptr(models.StatusActive),
+			},
+		},
+		TransactionID: originalID,
+	})
+	writeJSON(w, http.StatusOK, models.ExtendRenewalDateResponse{
+		OriginalTransactionId: ptr(originalID),
+		WebOrderLineItemId:    latest.transaction.WebOrderLineItemId,
+		Success:               ptr(true),
+		EffectiveDate:         latest.transaction.ExpiresDate,
+	})
+}
+
+// extendRenewalDateForAllActiveSubscribers serves Extend Subscription Renewal Dates for All Active Subscribers.
+// The extension completes immediately.
+func (s *Server) extendRenewalDateForAllActiveSubscribers(w http.ResponseWriter, r *http.Request) {
+	var request models.MassExtendRenewalDateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, models.APIErrorGeneralBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if apiErr := checkExtension(request.ExtendByDays, request.ExtendReasonCode, request.RequestIdentifier); apiErr != 0 {
+		writeError(w, apiErr)
+		return
+	}
+	if request.ProductId == nil || *request.ProductId == "" {
+		writeError(w, models.APIErrorInvalidProductID)
+		return
+	}
+	storefronts := make(map[string]bool)
+	for _, code := range request.StorefrontCountryCodes {
+		if !apple.StorefrontCountryCodePattern.MatchString(code) {
+			writeError(w, models.APIErrorInvalidStorefrontCountryCode)
+			return
+		}
+		storefronts[code] = true
+	}
+
+	// Extend the latest transaction of every subscription to the product
+	succeeded, failed := 0, 0
+	seen := make(map[string]bool)
+	for _, record := range s.transactions {
+		originalID := *record.transaction.OriginalTransactionId
+		if seen[originalID] {
+			continue
+		}
+		seen[originalID] = true
+
+		latest, _ := s.latestTransaction(originalID)
+		transaction := latest.transaction
+		if *transaction.Type != models.TypeAutoRenewableSubscription || *transaction.ProductId != *request.ProductId {
+			continue
+		}
+		if len(storefronts) > 0 && !storefronts[*transaction.Storefront] {
+			continue
+		}
+		if s.subscriptionStatus(transaction) != models.StatusActive {
+			continue
+		}
+		if s.extend(latest, *request.ExtendByDays) == 0 {
+			succeeded++
+		} else {
+			failed++
+		}
+	}
+
+	now := s.now().UnixMilli()
+	s.massExtensions[*request.RequestIdentifier] = &massExtension{
+		productID: *request.ProductId,
+		status: models.MassExtendRenewalDateStatusResponse{
+			RequestIdentifier: ptr(*request.RequestIdentifier),
+			Complete:          ptr(true),
+			CompleteDate:      &now,
+			SucceededCount:    &succeeded,
+			FailedCount:       &failed,
+		},
+	}
+	s.addNotification(Notification{
+		Payload: models.ResponseBodyV2DecodedPayload{
+			NotificationType: ptr(models.NotificationTypeV2RenewalExtension),
+			Subtype:          ptr(models.SubtypeSummary),
+			Summary: &models.Summary{
+				ProductId:              ptr(*request.ProductId),
+				RequestIdentifier:      ptr(*request.RequestIdentifier),
+				StorefrontCountryCodes: request.StorefrontCountryCodes,
+				SucceededCount:         &succeeded,
+				FailedCount:            &failed,
+			},
+		},
+	})
+	writeJSON(w, http.StatusOK, models.MassExtendRenewalDateResponse{RequestIdentifier: ptr(*request.RequestIdentifier)})
+}
+
+// getStatusOfSubscriptionRenewalDateExtensions serves Get Status of Subscription Renewal Date Extensions
+func (s *Server) getStatusOfSubscriptionRenewalDateExtensions(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	extension, ok := s.massExtensions[r.PathValue("requestIdentifier")]
+	if !ok || extension.productID != r.PathValue("productId") {
+		writeError(w, models.APIErrorStatusRequestNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, extension.status)
+}
+
+// checkExtension checks the fields shared by the renewal-date extension requests
+func checkExtension(extendByDays *int, reasonCode *models.ExtendReasonCode, requestIdentifier *string) models.APIError {
+	switch {
+	case extendByDays == nil || *extendByDays < models.MinExtendByDays || *extendByDays > models.MaxExtendByDays:
+		return models.APIErrorInvalidExtendByDays
+	case reasonCode == nil || *reasonCode < models.ExtendReasonCodeUndeclared || *reasonCode > models.ExtendReasonCodeServiceIssueOrOutage:
+		return models.APIErrorInvalidExtendReasonCode
+	case requestIdentifier == nil || *requestIdentifier == "" || len(*requestIdentifier) > models.MaxRequestIdentifierLength:
+		return models.APIErrorInvalidRequestIdentifier
+	}
+	return 0
+}
+
+// extend moves the expiry and renewal date of a subscription's latest transaction; s.mu must be held
+func (s *Server) extend(latest *transactionRecord, days int) models.APIError {
+	transaction := &latest.transaction
+	originalID := *transaction.OriginalTransactionId
+	if *transaction.Type != models.TypeAutoRenewableSubscription || s.subscriptionStatus(*transaction) != models.StatusActive {
+		return models.APIErrorSubscriptionExtensionIneligible
+	}
+	if *transaction.InAppOwnershipType == models.InAppOwnershipTypeFamilyShared {
+		return models.APIErrorFamilySharedSubscriptionExtensionIneligible
+	}
+
+	now := s.now().UnixMilli()
+	recent := 0
+	for _, date := range s.extensions[originalID] {
+		if now-date < 365*dayMillis {
+			recent++
+		}
+	}
+	if recent >= maxExtensionsPerYear {
+		return models.APIErrorSubscriptionMaxExtension
+	}
+
+	s.extensions[originalID] = append(s.extensions[originalID], now)
+	transaction.ExpiresDate = ptr(*transaction.ExpiresDate + int64(days)*dayMillis)
+	if renewal, ok := s.renewals[originalID]; ok {
+		renewal.RenewalDate = ptr(*transaction.ExpiresDate)
+	}
+	return 0
+}
+
+// sendConsumptionData serves Send Consumption Information
+func (s *Server) sendConsumptionData(w http.ResponseWriter, r *http.Request) {
+	var request models.ConsumptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, models.APIErrorGeneralBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, apiErr := s.findTransaction(r.PathValue("transactionId"))
+	if apiErr != 0 {
+		writeError(w, apiErr)
+		return
+	}
+	if *record.transaction.Type != models.TypeConsumable {
+		writeError(w, models.APIErrorInvalidTransactionNotConsumable)
+		return
+	}
+	if request.CustomerConsented == nil || !*request.CustomerConsented {
+		writeError(w, models.APIErrorInvalidCustomerConsented)
+		return
+	}
+
+	s.consumption[*record.transaction.TransactionId] = &request
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// sendConsumptionDataV2 serves version 2 of Send Consumption Information, which accepts every product type
+func (s *Server) sendConsumptionDataV2(w http.ResponseWriter, r *http.Request) {
+	var request models.ConsumptionRequestV2
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, models.APIErrorGeneralBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, apiErr := s.findTransaction(r.PathValue("transactionId"))
+	if apiErr != 0 {
+		writeError(w, apiErr)
+		return
+	}
+	switch {
+	case request.CustomerConsented == nil || !*request.CustomerConsented:
+		writeError(w, models.APIErrorInvalidCustomerConsented)
+		return
+	case request.DeliveryStatus == nil || !validDeliveryStatusesV2[*request.DeliveryStatus]:
+		writeError(w, models.APIErrorInvalidDeliveryStatus)
+		return
+	case request.SampleContentProvided == nil:
+		writeError(w, models.APIErrorInvalidSampleContentProvided)
+		return
+	case request.ConsumptionPercentage != nil && (*request.ConsumptionPercentage < 0 || *request.ConsumptionPercentage > models.MaxConsumptionPercentage),
+		request.RefundPreference != nil && !validRefundPreferencesV2[*request.RefundPreference]:
+		writeError(w, models.APIErrorGeneralBadRequest)
+		return
+	}
+
+	s.consumptionV2[*record.transaction.TransactionId] = &request
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// putTransaction routes PUT /inApps/v1/transactions/consumption/{transactionId} and
+// PUT /inApps/v1/transactions/{originalTransactionId}/appAccountToken, whose patterns overlap in a ServeMux
+func (s *Server) putTransaction(w http.ResponseWriter, r *http.Request) {
+	switch first, second := r.PathValue("first"), r.PathValue("second"); {
+	case first == "consumption":
+		r.SetPathValue("transactionId", second)
+		s.sendConsumptionData(w, r)
+	case second == "appAccountToken":
+		r.SetPathValue("originalTransactionId", first)
+		s.setAppAccountToken(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// setAppAccountToken serves Set App Account Token; the token applies to the purchase and all of its renewals
+func (s *Server) setAppAccountToken(w http.ResponseWriter, r *http.Request) {
+	var request models.UpdateAppAccountTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, models.APIErrorGeneralBadRequest)
+		return
+	}
+	if request.AppAccountToken == nil || !apple.UUIDPattern.MatchString(*request.AppAccountToken) {
+		writeError(w, models.APIErrorInvalidAppAccountTokenUUID)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	originalTransactionID := r.PathValue("originalTransactionId")
+	record, apiErr := s.findTransaction(originalTransactionID)
+	if apiErr != 0 {
+		writeError(w, apiErr)
+		return
+	}
+	if *record.transaction.OriginalTransactionId != originalTransactionID {
+		writeError(w, models.APIErrorTransactionIDIsNotOriginalTransactionID)
+		return
+	}
+	if *record.transaction.InAppOwnershipType == models.InAppOwnershipTypeFamilyShared {
+		writeError(w, models.APIErrorFamilyTransactionNotSupported)
+		return
+	}
+
+	for _, record := range s.transactions {
+		if *record.transaction.OriginalTransactionId == originalTransactionID {
+			record.transaction.AppAccountToken = ptr(*request.AppAccountToken)
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// getNotificationHistory serves Get Notification History
+func (s *Server) getNotificationHistory(w http.ResponseWriter, r *http.Request) {
+	var request models.NotificationHistoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, models.APIErrorGeneralBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	switch {
+	case request.StartDate == nil:
+		writeError(w, models.APIErrorInvalidStartDate)
+		return
+	case request.EndDate == nil:
+		writeError(w, models.APIErrorInvalidEndDate)
+		return
+	case *request.StartDate >= *request.EndDate:
+		writeError(w, models.APIErrorStartDateAfterEndDate)
+		return
+	case *request.StartDate < now.AddDate(0, 0, -models.NotificationHistoryMaxAgeDays).UnixMilli():
+		writeError(w, models.APIErrorStartDateTooFarInPast)
+		return
+	case request.TransactionId != nil && request.NotificationType != nil:
+		writeError(w, models.APIErrorMultipleFiltersSupplied)
+		return
+	case request.NotificationSubtype != nil && request.NotificationType == nil:
+		writeError(w, models.APIErrorInvalidNotificationType)
+		return
+	}
+
+	customerID := ""
+	if request.TransactionId != nil {
+		record, apiErr := s.findTransaction(*request.TransactionId)
+		if apiErr != 0 {
+			writeError(w, apiErr)
+			return
+		}
+		customerID = record.customerID
+	}
+	offset, ok := parsePageToken(r.URL.Query().Get("paginationToken"))
+	if !ok {
+		writeError(w, models.APIErrorInvalidPaginationToken)
+		return
+	}
+
+	var matched []*Notification
+	for _, notification := range s.notifications {
+		payload := notification.Payload
+		switch {
+		case *payload.SignedDate < *request.StartDate, *payload.SignedDate >= *request.EndDate,
+			request.NotificationType != nil && (payload.NotificationType == nil || *payload.NotificationType != *request.NotificationType),
+			request.NotificationSubtype != nil && (payload.Subtype == nil || *payload.Subtype != *request.NotificationSubtype),
+			request.OnlyFailures != nil && *request.OnlyFailures && !failed(notification):
+			continue
+		}
+		if customerID != "" {
+			record, ok := s.transactions[notification.TransactionID]
+			if !ok || record.customerID != customerID {
+				continue
+			}
+		}
+		matched = append(matched, notification)
+	}
+
+	items, token, hasMore := page(matched, offset)
+	response := models.NotificationHistoryResponse{HasMore: &hasMore, NotificationHistory: []models.NotificationHistoryResponseItem{}}
+	if hasMore {
+		response.PaginationToken = &token
+	}
+	for _, notification := range items {
+		response.NotificationHistory = append(response.NotificationHistory, models.NotificationHistoryResponseItem{
+			SignedPayload: ptr(s.mustSign(notification.Payload)),
+			SendAttempts:  notification.SendAttempts,
+		})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// failed reports whether the last attempt to send the notification failed
+func failed(notification *Notification) bool {
+	attempts := notification.SendAttempts
+	last := attempts[len(attempts)-1].SendAttemptResult
+	return last == nil || *last != models.SendAttemptResultSuccess
+}
+
+// requestTestNotification serves Request a Test Notification
+func (s *Server) requestTestNotification(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token := newUUID() + "_" + strconv.FormatInt(s.now().UnixMilli(), 10)
+	s.testNotifications[token] = s.addNotification(Notification{
+		Payload: models.ResponseBodyV2DecodedPayload{
+			NotificationType: ptr(models.NotificationTypeV2Test),
+			Data:             &models.Data{},
+		},
+	})
+	writeJSON(w, http.StatusOK, models.SendTestNotificationResponse{TestNotificationToken: &token})
+}
+
+// getTestNotificationStatus serves Get Test Notification Status
+func (s *Server) getTestNotificationStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token := r.PathValue("testNotificationToken")
+	if !strings.Contains(token, "_") {
+		writeError(w, models.APIErrorInvalidTestNotificationToken)
+		return
+	}
+	notification, ok := s.testNotifications[token]
+	if !ok {
+		writeError(w, models.APIErrorTestNotificationNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, models.CheckTestNotificationResponse{
+		SignedPayload: ptr(s.mustSign(notification.Payload)),
+		SendAttempts:  notification.SendAttempts,
+	})
+}
+
+// findTransaction returns the stored transaction with the given identifier, or the error Apple reports; s.mu must be held
+func (s *Server) findTransaction(transactionID string) (*transactionRecord, models.APIError) {
+	if !apple.TransactionIDPattern.MatchString(transactionID) {
+		return nil, models.APIErrorInvalidTransactionID
+	}
+	record, ok := s.transactions[transactionID]
+	if !ok {
+		return nil, models.APIErrorTransactionIDNotFound
+	}
+	return record, 0
+}
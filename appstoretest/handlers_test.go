@@ -0,0 +1,661 @@
+package appstoretest_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DotNetAge/appstore"
+	"github.com/DotNetAge/appstore/appstoretest"
+	"github.com/DotNetAge/appstore/models"
+)
+
+const dayMillis = 24 * 60 * 60 * 1000
+
+// newFake starts a Server whose clock stands still and returns it with a client that doesn't retry and a verifier
+// for its signed payloads
+func newFake(t *testing.T, options ...appstore.ClientOption) (*appstoretest.Server, *appstore.AppStoreServerAPIClient, *appstore.SignedDataVerifier) {
+	t.Helper()
+	now := time.Now().Truncate(time.Millisecond)
+	server := appstoretest.NewServer(appstoretest.WithClock(func() time.Time { return now }))
+	t.Cleanup(server.Close)
+	client, err := server.NewClient(append([]appstore.ClientOption{appstore.WithRetryPolicy(nil)}, options...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := server.NewVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, client, verifier
+}
+
+// transactionIDs verifies the signed transactions and returns their identifiers
+func transactionIDs(t *testing.T, verifier *appstore.SignedDataVerifier, signedTransactions []string) []string {
+	t.Helper()
+	ids := []string{}
+	for _, signed := range signedTransactions {
+		transaction, err := verifier.VerifyAndDecodeSignedTransaction(signed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, *transaction.TransactionId)
+	}
+	return ids
+}
+
+// checkAPIError fails the test unless err is an APIException with the error code and the HTTP status code derived
+// from it
+func checkAPIError(t *testing.T, err error, want models.APIError) {
+	t.Helper()
+	var apiErr *models.APIException
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("error = %v, want %s", err, want)
+	}
+	if apiErr.APIError != want || apiErr.HTTPStatusCode != int(want)/10000 {
+		t.Errorf("got %s with status %d, want %s with status %d", apiErr.APIError, apiErr.HTTPStatusCode, want, int(want)/10000)
+	}
+}
+
+// addPurchases adds n transactions of the customer, an hour apart and ending now, alternating between products a and b
+func addPurchases(server *appstoretest.Server, customerID string, n int) []string {
+	start := time.Now().Add(-time.Duration(n) * time.Hour).UnixMilli()
+	var ids []string
+	for i := range n {
+		productID := "a"
+		if i%2 == 1 {
+			productID = "b"
+		}
+		transaction := server.AddTransaction(customerID, models.JWSTransactionDecodedPayload{
+			ProductId:    &productID,
+			PurchaseDate: ptr(start + int64(i)*time.Hour.Milliseconds()),
+		})
+		ids = append(ids, *transaction.TransactionId)
+	}
+	return ids
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestServerTransactionHistory(t *testing.T) {
+	server, client, verifier := newFake(t)
+	ctx := context.Background()
+	ids := addPurchases(server, "customer", 25)
+	addPurchases(server, "other customer", 1)
+
+	for _, version := range []appstore.GetTransactionHistoryVersion{appstore.GetTransactionHistoryVersionV1, appstore.GetTransactionHistoryVersionV2} {
+		t.Run(string(version), func(t *testing.T) {
+			var got []string
+			revision := ""
+			for pages := 1; ; pages++ {
+				response, err := client.GetTransactionHistory(ctx, ids[3], revision, nil, version)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, transactionIDs(t, verifier, response.SignedTransactions)...)
+				if *response.BundleId != server.BundleID() || *response.AppAppleId != server.AppAppleID() || *response.Environment != server.Environment() {
+					t.Errorf("response = %+v", response)
+				}
+				if !*response.HasMore {
+					if pages != 2 {
+						t.Errorf("got %d pages, want 2", pages)
+					}
+					break
+				}
+				revision = *response.Revision
+			}
+			if !reflect.DeepEqual(got, ids) {
+				t.Errorf("history = %v, want %v", got, ids)
+			}
+		})
+	}
+
+	t.Run("filtered", func(t *testing.T) {
+		descending := models.OrderDescending
+		response, err := client.GetTransactionHistory(ctx, ids[0], "", &models.TransactionHistoryRequest{
+			ProductIds: []string{"b"},
+			StartDate:  ptr(time.Now().Add(-10*time.Hour - 30*time.Minute).UnixMilli()),
+			Sort:       &descending,
+		}, appstore.GetTransactionHistoryVersionV2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Products b bought in the last ten and a half hours, newest first
+		want := []string{ids[23], ids[21], ids[19], ids[17], ids[15]}
+		if got := transactionIDs(t, verifier, response.SignedTransactions); !reflect.DeepEqual(got, want) {
+			t.Errorf("history = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("invalid revision", func(t *testing.T) {
+		_, err := client.GetTransactionHistory(ctx, ids[0], "not-a-revision", nil, appstore.GetTransactionHistoryVersionV2)
+		checkAPIError(t, err, models.APIErrorInvalidRequestRevision)
+	})
+	t.Run("unknown transaction", func(t *testing.T) {
+		_, err := client.GetTransactionHistory(ctx, "999999", "", nil, appstore.GetTransactionHistoryVersionV2)
+		checkAPIError(t, err, models.APIErrorTransactionIDNotFound)
+	})
+}
+
+func TestServerTransactionInfo(t *testing.T) {
+	server, client, verifier := newFake(t)
+	ctx := context.Background()
+	ids := addPurchases(server, "customer", 1)
+
+	response, err := client.GetTransactionInfo(ctx, ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := transactionIDs(t, verifier, []string{*response.SignedTransactionInfo}); got[0] != ids[0] {
+		t.Errorf("transaction = %s, want %s", got[0], ids[0])
+	}
+	_, err = client.GetTransactionInfo(ctx, "999999")
+	checkAPIError(t, err, models.APIErrorTransactionIDNotFound)
+}
+
+func TestServerLookUpOrderID(t *testing.T) {
+	server, client, verifier := newFake(t)
+	ctx := context.Background()
+	ids := addPurchases(server, "customer", 3)
+	server.AddOrder("MK5TTTVWJH", ids[0], ids[2])
+
+	response, err := client.LookUpOrderID(ctx, "MK5TTTVWJH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *response.Status != models.OrderLookupStatusValid {
+		t.Errorf("status = %v, want valid", *response.Status)
+	}
+	if got, want := transactionIDs(t, verifier, response.SignedTransactions), []string{ids[0], ids[2]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("transactions = %v, want %v", got, want)
+	}
+
+	response, err = client.LookUpOrderID(ctx, "UNKNOWN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *response.Status != models.OrderLookupStatusInvalid || len(response.SignedTransactions) != 0 {
+		t.Errorf("unknown order: response = %+v", response)
+	}
+}
+
+func TestServerRefundHistory(t *testing.T) {
+	server, client, verifier := newFake(t)
+	ctx := context.Background()
+	ids := addPurchases(server, "customer", 23)
+	refunded := append(append([]string(nil), ids[:10]...), ids[11:]...)
+	for _, id := range refunded {
+		if err := server.Refund(id, models.RevocationReasonRefundedDueToIssue); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first, err := client.GetRefundHistory(ctx, ids[0], "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !*first.HasMore || len(first.SignedTransactions) != 20 {
+		t.Fatalf("first page has %d refunds, more = %v; want 20 and more", len(first.SignedTransactions), *first.HasMore)
+	}
+	second, err := client.GetRefundHistory(ctx, ids[0], *first.Revision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *second.HasMore {
+		t.Error("second page has more")
+	}
+	got := transactionIDs(t, verifier, append(first.SignedTransactions, second.SignedTransactions...))
+	if !reflect.DeepEqual(got, refunded) {
+		t.Errorf("refunds = %v, want %v", got, refunded)
+	}
+
+	transaction, err := verifier.VerifyAndDecodeSignedTransaction(first.SignedTransactions[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transaction.RevocationDate == nil || *transaction.RevocationReason != models.RevocationReasonRefundedDueToIssue {
+		t.Errorf("refund = %+v", transaction)
+	}
+
+	_, err = client.GetRefundHistory(ctx, ids[0], "not-a-revision")
+	checkAPIError(t, err, models.APIErrorInvalidRequestRevision)
+}
+
+func TestServerSubscriptionStatuses(t *testing.T) {
+	server, client, verifier := newFake(t)
+	ctx := context.Background()
+	now := time.Now().UnixMilli()
+	active := server.AddTransaction("customer", models.JWSTransactionDecodedPayload{ExpiresDate: ptr(now + dayMillis), SubscriptionGroupIdentifier: ptr("g1")})
+	expired := server.AddTransaction("customer", models.JWSTransactionDecodedPayload{ExpiresDate: ptr(now - dayMillis), SubscriptionGroupIdentifier: ptr("g1")})
+	retrying := server.AddTransaction("customer", models.JWSTransactionDecodedPayload{ExpiresDate: ptr(now - dayMillis), SubscriptionGroupIdentifier: ptr("g2")})
+	server.SetSubscriptionStatus(*retrying.TransactionId, models.StatusBillingRetry)
+	oneTime := server.AddTransaction("customer", models.JWSTransactionDecodedPayload{})
+
+	// statuses maps the subscription group to the status of each subscription in it
+	statuses := func(response *models.StatusResponse) map[string]map[string]models.Status {
+		groups := map[string]map[string]models.Status{}
+		for _, group := range response.Data {
+			groups[*group.SubscriptionGroupIdentifier] = map[string]models.Status{}
+			for _, item := range group.LastTransactions {
+				if got := transactionIDs(t, verifier, []string{*item.SignedTransactionInfo}); got[0] != *item.OriginalTransactionId {
+					t.Errorf("last transaction %s of subscription %s", got[0], *item.OriginalTransactionId)
+				}
+				if _, err := verifier.VerifyAndDecodeRenewalInfo(*item.SignedRenewalInfo); err != nil {
+					t.Error(err)
+				}
+				groups[*group.SubscriptionGroupIdentifier][*item.OriginalTransactionId] = *item.Status
+			}
+		}
+		return groups
+	}
+
+	response, err := client.GetAllSubscriptionStatuses(ctx, *oneTime.TransactionId, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]models.Status{
+		"g1": {*active.TransactionId: models.StatusActive, *expired.TransactionId: models.StatusExpired},
+		"g2": {*retrying.TransactionId: models.StatusBillingRetry},
+	}
+	if got := statuses(response); !reflect.DeepEqual(got, want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+
+	response, err = client.GetAllSubscriptionStatuses(ctx, *oneTime.TransactionId, []models.Status{models.StatusActive, models.StatusBillingRetry})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]map[string]models.Status{
+		"g1": {*active.TransactionId: models.StatusActive},
+		"g2": {*retrying.TransactionId: models.StatusBillingRetry},
+	}
+	if got := statuses(response); !reflect.DeepEqual(got, want) {
+		t.Errorf("filtered statuses = %v, want %v", got, want)
+	}
+
+	_, err = client.GetAllSubscriptionStatuses(ctx, *oneTime.TransactionId, []models.Status{models.StatusRevoked + 1})
+	checkAPIError(t, err, models.APIErrorInvalidStatus)
+}
+
+func TestServerExtendSubscriptionRenewalDate(t *testing.T) {
+	server, client, verifier := newFake(t)
+	ctx := context.Background()
+	now := time.Now().UnixMilli()
+	subscription := server.AddTransaction("customer", models.JWSTransactionDecodedPayload{ExpiresDate: ptr(now + dayMillis)})
+	expired := server.AddTransaction("customer", models.JWSTransactionDecodedPayload{ExpiresDate: ptr(now - dayMillis)})
+	shared := server.AddTransaction("family", models.JWSTransactionDecodedPayload{
+		ExpiresDate:        ptr(now + dayMillis),
+		InAppOwnershipType: ptr(models.InAppOwnershipTypeFamilyShared),
+	})
+	reason := models.ExtendReasonCodeCustomerSatisfaction
+	extend := func(originalTransactionID string) (*models.ExtendRenewalDateResponse, error) {
+		return client.ExtendSubscriptionRenewalDate(ctx, originalTransactionID, &models.ExtendRenewalDateRequest{
+			ExtendByDays:      ptr(7),
+			ExtendReasonCode:  &reason,
+			RequestIdentifier: ptr("7e3fb20b-4cdb-47cc-936d-99d65f608138"),
+		})
+	}
+
+	// Apple allows two extensions a year
+	for i := 1; i <= 2; i++ {
+		response, err := extend(*subscription.TransactionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := *subscription.ExpiresDate + int64(7*i)*dayMillis; !*response.Success || *response.EffectiveDate != want {
+			t.Errorf("extension %d: response = %+v, want effective date %d", i, response, want)
+		}
+	}
+	_, err := extend(*subscription.TransactionId)
+	checkAPIError(t, err, models.APIErrorSubscriptionMaxExtension)
+	_, err = extend(*expired.TransactionId)
+	checkAPIError(t, err, models.APIErrorSubscriptionExtensionIneligible)
+	_, err = extend(*shared.TransactionId)
+	checkAPIError(t, err, models.APIErrorFamilySharedSubscriptionExtensionIneligible)
+	_, err = extend("999999")
+	checkAPIError(t, err, models.APIErrorOriginalTransactionIDNotFound)
+
+	renewal, ok := server.RenewalInfo(*subscription.TransactionId)
+	if !ok || *renewal.RenewalDate != *subscription.ExpiresDate+14*dayMillis {
+		t.Errorf("renewal info = %+v", renewal)
+	}
+	history, err := client.GetNotificationHistory(ctx, "", &models.NotificationHistoryRequest{
+		StartDate:        ptr(now - dayMillis),
+		EndDate:          ptr(now + dayMillis),
+		NotificationType: ptr(models.NotificationTypeV2RenewalExtended),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.NotificationHistory) != 2 {
+		t.Fatalf("got %d RENEWAL_EXTENDED notifications, want 2", len(history.NotificationHistory))
+	}
+	notification, err := verifier.VerifyAndDecodeNotification(*history.NotificationHistory[0].SignedPayload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := transactionIDs(t, verifier, []string{*notification.Data.SignedTransactionInfo}); got[0] != *subscription.TransactionId {
+		t.Errorf("notification is about %s", got[0])
+	}
+}
+
+func TestServerExtendRenewalDateForAllActiveSubscribers(t *testing.T) {
+	server, client, _ := newFake(t)
+	ctx := context.Background()
+	now := time.Now().UnixMilli()
+	monthly := "com.example.monthly"
+	for _, transaction := range []models.JWSTransactionDecodedPayload{
+		{ProductId: &monthly, ExpiresDate: ptr(now + dayMillis)},
+		{ProductId: &monthly, ExpiresDate: ptr(now + dayMillis), Storefront: ptr("GBR")},
+		{ProductId: &monthly, ExpiresDate: ptr(now - dayMillis)},
+		{ProductId: ptr("com.example.yearly"), ExpiresDate: ptr(now + dayMillis)},
+	} {
+		server.AddTransaction("customer", transaction)
+	}
+
+	reason := models.ExtendReasonCodeServiceIssueOrOutage
+	response, err := client.ExtendRenewalDateForAllActiveSubscribers(ctx, &models.MassExtendRenewalDateRequest{
+		ExtendByDays:           ptr(3),
+		ExtendReasonCode:       &reason,
+		ProductId:              &monthly,
+		StorefrontCountryCodes: []string{"USA"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := client.GetStatusOfSubscriptionRenewalDateExtensions(ctx, *response.RequestIdentifier, monthly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the active USA subscription to the product is extended
+	if !*status.Complete || *status.SucceededCount != 1 || *status.FailedCount != 0 {
+		t.Errorf("status = %+v", status)
+	}
+
+	_, err = client.GetStatusOfSubscriptionRenewalDateExtensions(ctx, *response.RequestIdentifier, "com.example.yearly")
+	checkAPIError(t, err, models.APIErrorStatusRequestNotFound)
+}
+
+func TestServerSendConsumptionData(t *testing.T) {
+	server, client, _ := newFake(t)
+	ctx := context.Background()
+	consumable := server.AddTransaction("customer", models.JWSTransactionDecodedPayload{Type: ptr(models.TypeConsumable)})
+	nonConsumable := server.AddTransaction("customer", models.JWSTransactionDecodedPayload{})
+	request := &models.ConsumptionRequest{
+		CustomerConsented:        ptr(true),
+		ConsumptionStatus:        ptr(models.ConsumptionStatusFullyConsumed),
+		Platform:                 ptr(models.PlatformApple),
+		SampleContentProvided:    ptr(false),
+		DeliveryStatus:           ptr(models.DeliveryStatusDeliveredAndWorkingProperly),
+		AppAccountToken:          ptr(""),
+		AccountTenure:            ptr(models.AccountTenureUndeclared),
+		PlayTime:                 ptr(models.PlayTimeUndeclared),
+		LifetimeDollarsRefunded:  ptr(models.LifetimeDollarsRefundedUndeclared),
+		LifetimeDollarsPurchased: ptr(models.LifetimeDollarsPurchasedUndeclared),
+		UserStatus:               ptr(models.UserStatusActive),
+	}
+
+	if err := client.SendConsumptionData(ctx, *consumable.TransactionId, request); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := server.ConsumptionData(*consumable.TransactionId); !ok || !reflect.DeepEqual(&got, request) {
+		t.Errorf("stored %+v, want %+v", got, request)
+	}
+	checkAPIError(t, client.SendConsumptionData(ctx, *nonConsumable.TransactionId, request), models.APIErrorInvalidTransactionNotConsumable)
+
+	// Version 2 accepts every product type
+	requestV2 := &models.ConsumptionRequestV2{
+		CustomerConsented:     ptr(true),
+		ConsumptionPercentage: ptr(50000),
+		DeliveryStatus:        ptr(models.DeliveryStatusV2Delivered),
+		SampleContentProvided: ptr(true),
+	}
+	if err := client.SendConsumptionDataV2(ctx, *nonConsumable.TransactionId, requestV2); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := server.ConsumptionDataV2(*nonConsumable.TransactionId); !ok || !reflect.DeepEqual(&got, requestV2) {
+		t.Errorf("stored %+v, want %+v", got, requestV2)
+	}
+	checkAPIError(t, client.SendConsumptionDataV2(ctx, "999999", requestV2), models.APIErrorTransactionIDNotFound)
+}
+
+func TestServerSetAppAccountToken(t *testing.T) {
+	server, client, _ := newFake(t)
+	ctx := context.Background()
+	now := time.Now().UnixMilli()
+	original := server.AddTransaction("customer", models.JWSTransactionDecodedPayload{ExpiresDate: ptr(now), PurchaseDate: ptr(now - 30*dayMillis)})
+	renewal := server.AddTransaction("customer", models.JWSTransactionDecodedPayload{
+		OriginalTransactionId: original.TransactionId,
+		ExpiresDate:           ptr(now + 30*dayMillis),
+		PurchaseDate:          ptr(now),
+	})
+	shared := server.AddTransaction("family", models.JWSTransactionDecodedPayload{InAppOwnershipType: ptr(models.InAppOwnershipTypeFamilyShared)})
+	token := &models.UpdateAppAccountTokenRequest{AppAccountToken: ptr("7e3fb20b-4cdb-47cc-936d-99d65f608138")}
+
+	if err := client.SetAppAccountToken(ctx, *original.TransactionId, token); err != nil {
+		t.Fatal(err)
+	}
+	// The token applies to the renewals too
+	for _, id := range []string{*original.TransactionId, *renewal.TransactionId} {
+		if transaction, _ := server.Transaction(id); transaction.AppAccountToken == nil || *transaction.AppAccountToken != *token.AppAccountToken {
+			t.Errorf("transaction %s has token %v", id, transaction.AppAccountToken)
+		}
+	}
+	checkAPIError(t, client.SetAppAccountToken(ctx, *renewal.TransactionId, token), models.APIErrorTransactionIDIsNotOriginalTransactionID)
+	checkAPIError(t, client.SetAppAccountToken(ctx, *shared.TransactionId, token), models.APIErrorFamilyTransactionNotSupported)
+	checkAPIError(t, client.SetAppAccountToken(ctx, "999999", token), models.APIErrorTransactionIDNotFound)
+}
+
+func TestServerNotificationHistory(t *testing.T) {
+	server, client, verifier := newFake(t)
+	ctx := context.Background()
+	now := time.Now().UnixMilli()
+	ids := addPurchases(server, "customer", 1)
+	other := addPurchases(server, "other customer", 1)
+
+	var uuids []string
+	for i := range 24 {
+		notification := appstoretest.Notification{
+			Payload: models.ResponseBodyV2DecodedPayload{
+				NotificationType: ptr(models.NotificationTypeV2DidRenew),
+				SignedDate:       ptr(now - int64(24-i)*time.Minute.Milliseconds()),
+				Data:             &models.Data{},
+			},
+			TransactionID: ids[0],
+		}
+		if i == 5 {
+			notification.TransactionID = other[0]
+		}
+		if i == 7 {
+			notification.SendAttempts = []models.SendAttemptItem{{AttemptDate: ptr(now), SendAttemptResult: ptr(models.SendAttemptResultTimedOut)}}
+		}
+		uuids = append(uuids, *server.AddNotification(notification).Payload.NotificationUUID)
+	}
+
+	// history pages through the notifications matching the request and returns their UUIDs
+	history := func(request *models.NotificationHistoryRequest) []string {
+		t.Helper()
+		var got []string
+		token := ""
+		for {
+			response, err := client.GetNotificationHistory(ctx, token, request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, item := range response.NotificationHistory {
+				notification, err := verifier.VerifyAndDecodeNotification(*item.SignedPayload)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, *notification.NotificationUUID)
+			}
+			if !*response.HasMore {
+				return got
+			}
+			token = *response.PaginationToken
+		}
+	}
+	window := func() *models.NotificationHistoryRequest {
+		return &models.NotificationHistoryRequest{StartDate: ptr(now - dayMillis), EndDate: ptr(now + dayMillis)}
+	}
+
+	if got := history(window()); !reflect.DeepEqual(got, uuids) {
+		t.Errorf("history = %v, want %v", got, uuids)
+	}
+	byTransaction := window()
+	byTransaction.TransactionId = &ids[0]
+	if got, want := history(byTransaction), append(append([]string(nil), uuids[:5]...), uuids[6:]...); !reflect.DeepEqual(got, want) {
+		t.Errorf("history of the customer = %v, want %v", got, want)
+	}
+	onlyFailures := window()
+	onlyFailures.OnlyFailures = ptr(true)
+	if got, want := history(onlyFailures), []string{uuids[7]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("failed notifications = %v, want %v", got, want)
+	}
+
+	_, err := client.GetNotificationHistory(ctx, "not-a-token", window())
+	checkAPIError(t, err, models.APIErrorInvalidPaginationToken)
+}
+
+func TestServerTestNotification(t *testing.T) {
+	_, client, verifier := newFake(t)
+	ctx := context.Background()
+
+	response, err := client.RequestTestNotification(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := client.GetTestNotificationStatus(ctx, *response.TestNotificationToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notification, err := verifier.VerifyAndDecodeNotification(*status.SignedPayload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *notification.NotificationType != models.NotificationTypeV2Test || *status.SendAttempts[0].SendAttemptResult != models.SendAttemptResultSuccess {
+		t.Errorf("test notification = %+v, attempts %+v", notification, status.SendAttempts)
+	}
+
+	_, err = client.GetTestNotificationStatus(ctx, "no-timestamp")
+	checkAPIError(t, err, models.APIErrorInvalidTestNotificationToken)
+	_, err = client.GetTestNotificationStatus(ctx, "7e3fb20b-4cdb-47cc-936d-99d65f608138_1")
+	checkAPIError(t, err, models.APIErrorTestNotificationNotFound)
+}
+
+func TestServerErrorStatus(t *testing.T) {
+	server, client, _ := newFake(t)
+	ctx := context.Background()
+	ids := addPurchases(server, "customer", 1)
+
+	injected := []models.APIError{
+		models.APIErrorGeneralBadRequest,
+		models.APIErrorSubscriptionExtensionIneligible,
+		models.APIErrorAccountNotFoundRetryable,
+		models.APIErrorImageAlreadyExists,
+		models.APIErrorRateLimitExceeded,
+		models.APIErrorGeneralInternal,
+	}
+	server.FailNext(injected...)
+	for _, want := range injected {
+		t.Run(want.String(), func(t *testing.T) {
+			_, err := client.GetTransactionInfo(ctx, ids[0])
+			checkAPIError(t, err, want)
+			var apiErr *models.APIException
+			if errors.As(err, &apiErr) && *apiErr.ErrorMessage == "" {
+				t.Error("no error message")
+			}
+			wantRetryAfter := ""
+			if want == models.APIErrorRateLimitExceeded {
+				wantRetryAfter = "1"
+			}
+			if got := apiErr.ResponseHeader.Get("Retry-After"); got != wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, wantRetryAfter)
+			}
+		})
+	}
+
+	// Injected errors are used up
+	if _, err := client.GetTransactionInfo(ctx, ids[0]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServerAuthentication(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		setup      func(*appstoretest.Server)
+		bundleID   string
+		authorized bool
+	}{
+		{name: "any key", authorized: true},
+		{name: "registered key", setup: func(s *appstoretest.Server) { s.AddAPIKey(appstoretest.DefaultKeyID, &key.PublicKey) }, authorized: true},
+		{name: "unregistered key", setup: func(s *appstoretest.Server) { s.AddAPIKey(appstoretest.DefaultKeyID, &otherKey.PublicKey) }},
+		{name: "rejected key", setup: func(s *appstoretest.Server) { s.RejectKey(appstoretest.DefaultKeyID) }},
+		{name: "other bundle", bundleID: "com.example.other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := appstoretest.NewServer()
+			defer server.Close()
+			transaction := server.AddTransaction("customer", models.JWSTransactionDecodedPayload{})
+			if tt.setup != nil {
+				tt.setup(server)
+			}
+			bundleID := server.BundleID()
+			if tt.bundleID != "" {
+				bundleID = tt.bundleID
+			}
+			client, err := appstore.NewAppStoreServerAPIClientWithOptions(nil, appstoretest.DefaultKeyID, appstoretest.DefaultIssuerID, bundleID, server.Environment(),
+				appstore.WithBaseURL(server.URL), appstore.WithSigner(key), appstore.WithRateLimiter(nil), appstore.WithRetryPolicy(nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = client.GetTransactionInfo(context.Background(), *transaction.TransactionId)
+			if tt.authorized {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var apiErr *models.APIException
+			if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != http.StatusUnauthorized || apiErr.RawAPIError != nil {
+				t.Errorf("error = %v, want 401 Unauthorized without an error code", err)
+			}
+		})
+	}
+
+	t.Run("injected errors wait for authentication", func(t *testing.T) {
+		server := appstoretest.NewServer()
+		defer server.Close()
+		transaction := server.AddTransaction("customer", models.JWSTransactionDecodedPayload{})
+		server.RejectKey(appstoretest.DefaultKeyID)
+		server.FailNext(models.APIErrorGeneralInternal)
+		client, err := server.NewClient(appstore.WithRetryPolicy(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var apiErr *models.APIException
+		if _, err := client.GetTransactionInfo(context.Background(), *transaction.TransactionId); !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != http.StatusUnauthorized {
+			t.Errorf("error = %v, want 401", err)
+		}
+	})
+}
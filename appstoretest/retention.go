@@ -0,0 +1,258 @@
+package appstoretest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/DotNetAge/appstore/internal/apple"
+	"github.com/DotNetAge/appstore/models"
+)
+
+var (
+	pngSignature  = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+	localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,4})*$`)
+)
+
+// retentionMessage is a message uploaded to the Retention Messaging API
+type retentionMessage struct {
+	body  models.UploadMessageRequestBody
+	state models.MessageState
+}
+
+// defaultMessageKey identifies the default message of a product in a locale
+type defaultMessageKey struct {
+	productID string
+	locale    string
+}
+
+// SetImageState sets the approval state of an uploaded image, as Apple's review would
+func (s *Server) SetImageState(imageIdentifier string, state models.ImageState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.images[imageIdentifier]; !ok {
+		return fmt.Errorf("appstoretest: no image %s", imageIdentifier)
+	}
+	s.images[imageIdentifier] = state
+	return nil
+}
+
+// SetMessageState sets the approval state of an uploaded message, as Apple's review would
+func (s *Server) SetMessageState(messageIdentifier string, state models.MessageState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	message, ok := s.messages[messageIdentifier]
+	if !ok {
+		return fmt.Errorf("appstoretest: no message %s", messageIdentifier)
+	}
+	message.state = state
+	return nil
+}
+
+// DefaultMessage returns the identifier of the default message configured for a product in a locale
+func (s *Server) DefaultMessage(productID, locale string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messageIdentifier, ok := s.defaultMessages[defaultMessageKey{productID: productID, locale: locale}]
+	return messageIdentifier, ok
+}
+
+// uploadImage serves Upload Image; images start PENDING
+func (s *Server) uploadImage(w http.ResponseWriter, r *http.Request) {
+	imageIdentifier := r.PathValue("imageIdentifier")
+	if !apple.UUIDPattern.MatchString(imageIdentifier) {
+		writeError(w, models.APIErrorGeneralBadRequest)
+		return
+	}
+	image, err := io.ReadAll(r.Body)
+	if err != nil || r.Header.Get("Content-Type") != "image/png" || !bytes.HasPrefix(image, pngSignature) {
+		writeError(w, models.APIErrorInvalidImage)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.images[imageIdentifier]; ok {
+		writeError(w, models.APIErrorImageAlreadyExists)
+		return
+	}
+	s.images[imageIdentifier] = models.ImageStatePending
+	w.WriteHeader(http.StatusOK)
+}
+
+// deleteImage serves Delete Image
+func (s *Server) deleteImage(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	imageIdentifier := r.PathValue("imageIdentifier")
+	if _, ok := s.images[imageIdentifier]; !ok {
+		writeError(w, models.APIErrorImageNotFound)
+		return
+	}
+	for _, message := range s.messages {
+		if image := message.body.Image; image != nil && *image.ImageIdentifier == imageIdentifier {
+			writeError(w, models.APIErrorImageInUse)
+			return
+		}
+	}
+	delete(s.images, imageIdentifier)
+	w.WriteHeader(http.StatusOK)
+}
+
+// getImageList serves Get Image List
+func (s *Server) getImageList(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	response := models.GetImageListResponse{ImageIdentifiers: []models.GetImageListResponseItem{}}
+	for _, imageIdentifier := range sortedKeys(s.images) {
+		response.ImageIdentifiers = append(response.ImageIdentifiers, models.GetImageListResponseItem{
+			ImageIdentifier: ptr(imageIdentifier),
+			ImageState:      ptr(s.images[imageIdentifier]),
+		})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// uploadMessage serves Upload Message; messages start PENDING and may only use approved images
+func (s *Server) uploadMessage(w http.ResponseWriter, r *http.Request) {
+	messageIdentifier := r.PathValue("messageIdentifier")
+	var request models.UploadMessageRequestBody
+	if !apple.UUIDPattern.MatchString(messageIdentifier) || json.NewDecoder(r.Body).Decode(&request) != nil {
+		writeError(w, models.APIErrorGeneralBadRequest)
+		return
+	}
+	switch {
+	case request.Header == nil || utf8.RuneCountInString(*request.Header) > models.MaxRetentionMessageHeaderLength:
+		writeError(w, models.APIErrorHeaderTooLong)
+		return
+	case request.Body == nil || utf8.RuneCountInString(*request.Body) > models.MaxRetentionMessageBodyLength:
+		writeError(w, models.APIErrorBodyTooLong)
+		return
+	case request.Image != nil && (request.Image.AltText == nil || utf8.RuneCountInString(*request.Image.AltText) > models.MaxRetentionImageAltTextLength):
+		writeError(w, models.APIErrorAltTextTooLong)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.messages[messageIdentifier]; ok {
+		writeError(w, models.APIErrorMessageAlreadyExists)
+		return
+	}
+	if request.Image != nil {
+		if request.Image.ImageIdentifier == nil {
+			writeError(w, models.APIErrorImageNotFound)
+			return
+		}
+		state, ok := s.images[*request.Image.ImageIdentifier]
+		if !ok {
+			writeError(w, models.APIErrorImageNotFound)
+			return
+		}
+		if state != models.ImageStateApproved {
+			writeError(w, models.APIErrorImageNotApproved)
+			return
+		}
+	}
+	s.messages[messageIdentifier] = &retentionMessage{body: request, state: models.MessageStatePending}
+	w.WriteHeader(http.StatusOK)
+}
+
+// deleteMessage serves Delete Message; defaults that show the message are removed with it
+func (s *Server) deleteMessage(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messageIdentifier := r.PathValue("messageIdentifier")
+	if _, ok := s.messages[messageIdentifier]; !ok {
+		writeError(w, models.APIErrorMessageNotFound)
+		return
+	}
+	delete(s.messages, messageIdentifier)
+	for key, identifier := range s.defaultMessages {
+		if identifier == messageIdentifier {
+			delete(s.defaultMessages, key)
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// getMessageList serves Get Message List
+func (s *Server) getMessageList(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	response := models.GetMessageListResponse{MessageIdentifiers: []models.GetMessageListResponseItem{}}
+	for _, messageIdentifier := range sortedKeys(s.messages) {
+		response.MessageIdentifiers = append(response.MessageIdentifiers, models.GetMessageListResponseItem{
+			MessageIdentifier: ptr(messageIdentifier),
+			MessageState:      ptr(s.messages[messageIdentifier].state),
+		})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// configureDefaultMessage serves Configure Default Message; only approved messages can be defaults
+func (s *Server) configureDefaultMessage(w http.ResponseWriter, r *http.Request) {
+	var request models.DefaultConfigurationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.MessageIdentifier == nil {
+		writeError(w, models.APIErrorGeneralBadRequest)
+		return
+	}
+	locale := r.PathValue("locale")
+	if !localePattern.MatchString(locale) {
+		writeError(w, models.APIErrorInvalidLocale)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	message, ok := s.messages[*request.MessageIdentifier]
+	if !ok {
+		writeError(w, models.APIErrorMessageNotFound)
+		return
+	}
+	if message.state != models.MessageStateApproved {
+		writeError(w, models.APIErrorMessageNotApproved)
+		return
+	}
+	s.defaultMessages[defaultMessageKey{productID: r.PathValue("productId"), locale: locale}] = *request.MessageIdentifier
+	w.WriteHeader(http.StatusOK)
+}
+
+// deleteDefaultMessage serves Delete Default Message
+func (s *Server) deleteDefaultMessage(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := defaultMessageKey{productID: r.PathValue("productId"), locale: r.PathValue("locale")}
+	if _, ok := s.defaultMessages[key]; !ok {
+		writeError(w, models.APIErrorMessageNotFound)
+		return
+	}
+	delete(s.defaultMessages, key)
+	w.WriteHeader(http.StatusOK)
+}
+
+// sortedKeys returns the keys of m in ascending order, for stable list responses
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
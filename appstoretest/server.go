@@ -0,0 +1,400 @@
+// Package appstoretest provides an in-process fake of the App Store Server API for integration tests.
+//
+// A Server keeps customers, transactions, subscription statuses, refunds, renewal-date extensions,
+// notification history and Retention Messaging images and messages in memory, answers with signed JWS payloads shaped like Apple's, and reports the
+// same error codes as the real service:
+//
+//	server := appstoretest.NewServer()
+//	defer server.Close()
+//
+//	server.AddTransaction("customer-1", models.JWSTransactionDecodedPayload{TransactionId: &transactionID, ProductId: &productID})
+//	client, _ := server.NewClient()
+//	history, err := client.GetTransactionHistory(ctx, transactionID, "", nil, appstore.GetTransactionHistoryVersionV2)
+package appstoretest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DotNetAge/appstore"
+	"github.com/DotNetAge/appstore/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// DefaultBundleID is the bundle identifier of a Server created without WithBundleID
+	DefaultBundleID = "com.example.app"
+	// DefaultAppAppleID is the App Apple ID of a Server created without WithAppAppleID
+	DefaultAppAppleID int64 = 1234567890
+	// DefaultKeyID is the key identifier used by the clients created with NewClient
+	DefaultKeyID = "APPSTORETEST"
+	// DefaultIssuerID is the issuer identifier used by the clients created with NewClient
+	DefaultIssuerID = "00000000-0000-0000-0000-000000000000"
+
+	// pageSize is the number of items per page of paginated responses
+	pageSize = 20
+)
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithBundleID sets the bundle identifier the Server serves; tokens for other bundles are rejected
+func WithBundleID(bundleID string) Option {
+	return func(s *Server) {
+		s.bundleID = bundleID
+	}
+}
+
+// WithAppAppleID sets the App Apple ID included in responses and signed payloads
+func WithAppAppleID(appAppleID int64) Option {
+	return func(s *Server) {
+		s.appAppleID = appAppleID
+	}
+}
+
+// WithEnvironment sets the environment included in responses and signed payloads; the default is Sandbox
+func WithEnvironment(environment models.Environment) Option {
+	return func(s *Server) {
+		s.environment = environment
+	}
+}
+
+// WithClock sets the function the Server uses to get the current time
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) {
+		s.now = now
+	}
+}
+
+// WithCertificateAuthority sets the certificate authority that signs payloads; by default each Server generates one
+func WithCertificateAuthority(ca *CertificateAuthority) Option {
+	return func(s *Server) {
+		s.ca = ca
+	}
+}
+
+// Server is an in-process fake of the App Store Server API
+type Server struct {
+	*httptest.Server
+
+	bundleID    string
+	appAppleID  int64
+	environment models.Environment
+	now         func() time.Time
+
+	ca *CertificateAuthority
+
+	mu                sync.Mutex
+	customers         map[string][]string
+	transactions      map[string]*transactionRecord
+	appTransactions   map[string]*models.AppTransaction
+	renewals          map[string]*models.JWSRenewalInfoDecodedPayload
+	statuses          map[string]models.Status
+	orders            map[string][]string
+	consumption       map[string]*models.ConsumptionRequest
+	consumptionV2     map[string]*models.ConsumptionRequestV2
+	extensions        map[string][]int64
+	massExtensions    map[string]*massExtension
+	notifications     []*Notification
+	testNotifications map[string]*Notification
+	images            map[string]models.ImageState
+	messages          map[string]*retentionMessage
+	defaultMessages   map[defaultMessageKey]string
+	apiKeys           map[string]*ecdsa.PublicKey
+	rejectedKeys      map[string]bool
+	injectedErrors    []models.APIError
+	sequence          int64
+}
+
+// transactionRecord is a stored transaction and the customer it belongs to
+type transactionRecord struct {
+	customerID  string
+	transaction models.JWSTransactionDecodedPayload
+}
+
+// massExtension is a renewal-date extension for all active subscribers of a product
+type massExtension struct {
+	productID string
+	status    models.MassExtendRenewalDateStatusResponse
+}
+
+// NewServer starts a Server; call Close when done
+func NewServer(options ...Option) *Server {
+	s := newServer(options)
+	s.Server = httptest.NewServer(s.routes())
+	return s
+}
+
+// newServer creates an unstarted Server
+func newServer(options []Option) *Server {
+	s := &Server{
+		bundleID:          DefaultBundleID,
+		appAppleID:        DefaultAppAppleID,
+		environment:       models.EnvironmentSandbox,
+		now:               time.Now,
+		customers:         make(map[string][]string),
+		transactions:      make(map[string]*transactionRecord),
+		appTransactions:   make(map[string]*models.AppTransaction),
+		renewals:          make(map[string]*models.JWSRenewalInfoDecodedPayload),
+		statuses:          make(map[string]models.Status),
+		orders:            make(map[string][]string),
+		consumption:       make(map[string]*models.ConsumptionRequest),
+		consumptionV2:     make(map[string]*models.ConsumptionRequestV2),
+		extensions:        make(map[string][]int64),
+		massExtensions:    make(map[string]*massExtension),
+		testNotifications: make(map[string]*Notification),
+		images:            make(map[string]models.ImageState),
+		messages:          make(map[string]*retentionMessage),
+		defaultMessages:   make(map[defaultMessageKey]string),
+		apiKeys:           make(map[string]*ecdsa.PublicKey),
+		rejectedKeys:      make(map[string]bool),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	if s.ca == nil {
+		s.useGeneratedCertificateAuthority()
+	}
+	return s
+}
+
+// NewClient creates an AppStoreServerAPIClient for the Server, signing with a freshly generated key.
+// Rate limiting is disabled unless options set a rate limiter.
+func (s *Server) NewClient(options ...appstore.ClientOption) (*appstore.AppStoreServerAPIClient, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	options = append([]appstore.ClientOption{
+		appstore.WithBaseURL(s.URL),
+		appstore.WithRateLimiter(nil),
+		appstore.WithSigner(key),
+	}, options...)
+	return appstore.NewAppStoreServerAPIClientWithOptions(nil, DefaultKeyID, DefaultIssuerID, s.bundleID, s.environment, options...)
+}
+
+// BundleID returns the bundle identifier the Server serves
+func (s *Server) BundleID() string {
+	return s.bundleID
+}
+
+// AppAppleID returns the App Apple ID of the Server
+func (s *Server) AppAppleID() int64 {
+	return s.appAppleID
+}
+
+// Environment returns the environment of the Server
+func (s *Server) Environment() models.Environment {
+	return s.environment
+}
+
+// AddAPIKey registers the public key of an API key; once any key is registered, tokens are only
+// accepted if they are signed by a registered key
+func (s *Server) AddAPIKey(keyID string, publicKey *ecdsa.PublicKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apiKeys[keyID] = publicKey
+}
+
+// RejectKey makes the Server answer 401 Unauthorized to requests signed with the key, as Apple does for revoked keys
+func (s *Server) RejectKey(keyID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejectedKeys[keyID] = true
+}
+
+// FailNext makes the next requests fail with the given errors, in order, before any other processing.
+// The HTTP status code is derived from the error code, so models.APIErrorRateLimitExceeded answers 429.
+func (s *Server) FailNext(errs ...models.APIError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.injectedErrors = append(s.injectedErrors, errs...)
+}
+
+// routes returns the handler serving the App Store Server API endpoints
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /inApps/{version}/history/{transactionId}", s.getTransactionHistory)
+	mux.HandleFunc("GET /inApps/v1/transactions/{transactionId}", s.getTransactionInfo)
+	mux.HandleFunc("GET /inApps/v1/transactions/appTransactions/{transactionId}", s.getAppTransactionInfo)
+	mux.HandleFunc("GET /inApps/v1/subscriptions/{transactionId}", s.getAllSubscriptionStatuses)
+	mux.HandleFunc("GET /inApps/v1/lookup/{orderId}", s.lookUpOrderID)
+	mux.HandleFunc("GET /inApps/v2/refund/lookup/{transactionId}", s.getRefundHistory)
+	mux.HandleFunc("PUT /inApps/v1/subscriptions/extend/{originalTransactionId}", s.extendSubscriptionRenewalDate)
+	mux.HandleFunc("POST /inApps/v1/subscriptions/extend/mass", s.extendRenewalDateForAllActiveSubscribers)
+	mux.HandleFunc("GET /inApps/v1/subscriptions/extend/mass/{productId}/{requestIdentifier}", s.getStatusOfSubscriptionRenewalDateExtensions)
+	mux.HandleFunc("PUT /inApps/v1/transactions/{first}/{second}", s.putTransaction)
+	mux.HandleFunc("PUT /inApps/v2/transactions/consumption/{transactionId}", s.sendConsumptionDataV2)
+	mux.HandleFunc("POST /inApps/v1/notifications/history", s.getNotificationHistory)
+	mux.HandleFunc("POST /inApps/v1/notifications/test", s.requestTestNotification)
+	mux.HandleFunc("GET /inApps/v1/notifications/test/{testNotificationToken}", s.getTestNotificationStatus)
+	mux.HandleFunc("PUT /inApps/v1/messaging/image/{imageIdentifier}", s.uploadImage)
+	mux.HandleFunc("DELETE /inApps/v1/messaging/image/{imageIdentifier}", s.deleteImage)
+	mux.HandleFunc("GET /inApps/v1/messaging/image/list", s.getImageList)
+	mux.HandleFunc("PUT /inApps/v1/messaging/message/{messageIdentifier}", s.uploadMessage)
+	mux.HandleFunc("DELETE /inApps/v1/messaging/message/{messageIdentifier}", s.deleteMessage)
+	mux.HandleFunc("GET /inApps/v1/messaging/message/list", s.getMessageList)
+	mux.HandleFunc("PUT /inApps/v1/messaging/default/{productId}/{locale}", s.configureDefaultMessage)
+	mux.HandleFunc("DELETE /inApps/v1/messaging/default/{productId}/{locale}", s.deleteDefaultMessage)
+	return s.authenticate(mux)
+}
+
+// authenticate checks the bearer token of each request and applies injected errors
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		s.mu.Lock()
+		var injected *models.APIError
+		if len(s.injectedErrors) > 0 {
+			injected = &s.injectedErrors[0]
+			s.injectedErrors = s.injectedErrors[1:]
+		}
+		s.mu.Unlock()
+		if injected != nil {
+			writeError(w, *injected)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorized reports whether the request carries a valid bearer token for the Server's bundle
+func (s *Server) authorized(r *http.Request) bool {
+	tokenString, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodES256.Alg()}),
+		jwt.WithAudience("appstoreconnect-v1"),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithTimeFunc(s.now),
+	}
+	claims := jwt.MapClaims{}
+	var token *jwt.Token
+	var err error
+	if len(s.apiKeys) == 0 {
+		// Without registered keys, any well-formed ES256 token is accepted
+		if token, _, err = jwt.NewParser(options...).ParseUnverified(tokenString, claims); err == nil {
+			if token.Method != jwt.SigningMethodES256 {
+				return false
+			}
+			err = jwt.NewValidator(options...).Validate(claims)
+		}
+	} else {
+		token, err = jwt.NewParser(options...).ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+			keyID, _ := token.Header["kid"].(string)
+			if key, ok := s.apiKeys[keyID]; ok {
+				return key, nil
+			}
+			return nil, jwt.ErrTokenUnverifiable
+		})
+	}
+	if err != nil {
+		return false
+	}
+
+	keyID, _ := token.Header["kid"].(string)
+	bundleID, _ := claims["bid"].(string)
+	issuer, _ := claims["iss"].(string)
+	return keyID != "" && !s.rejectedKeys[keyID] && bundleID == s.bundleID && issuer != ""
+}
+
+// writeJSON writes value as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, statusCode int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// writeError writes an Apple-shaped error response for the error code
+func writeError(w http.ResponseWriter, apiError models.APIError) {
+	statusCode := int(apiError) / 10000
+	if apiError == models.APIErrorRateLimitExceeded {
+		w.Header().Set("Retry-After", "1")
+	}
+	writeJSON(w, statusCode, map[string]interface{}{
+		"errorCode":    int(apiError),
+		"errorMessage": errorMessage(apiError),
+	})
+}
+
+// errorMessage returns the message Apple sends with an error code
+func errorMessage(apiError models.APIError) string {
+	if message, ok := errorMessages[apiError]; ok {
+		return message
+	}
+	return apiError.String()
+}
+
+// errorMessages holds the messages Apple sends with the error codes the Server produces
+var errorMessages = map[models.APIError]string{
+	models.APIErrorGeneralBadRequest:                           "Bad request.",
+	models.APIErrorInvalidRequestRevision:                      "Invalid request revision.",
+	models.APIErrorInvalidTransactionID:                        "Invalid transaction id.",
+	models.APIErrorInvalidOriginalTransactionID:                "Invalid original transaction id.",
+	models.APIErrorInvalidExtendByDays:                         "Invalid extend by days value.",
+	models.APIErrorInvalidExtendReasonCode:                     "Invalid extend reason code.",
+	models.APIErrorInvalidRequestIdentifier:                    "Invalid request identifier.",
+	models.APIErrorStartDateTooFarInPast:                       "Start date too far in past.",
+	models.APIErrorStartDateAfterEndDate:                       "Start date after end date.",
+	models.APIErrorInvalidPaginationToken:                      "Invalid pagination token.",
+	models.APIErrorInvalidStartDate:                            "Invalid start date.",
+	models.APIErrorInvalidEndDate:                              "Invalid end date.",
+	models.APIErrorInvalidNotificationType:                     "Invalid notification type.",
+	models.APIErrorMultipleFiltersSupplied:                     "Multiple filters supplied.",
+	models.APIErrorInvalidTestNotificationToken:                "Invalid test notification token.",
+	models.APIErrorInvalidSort:                                 "Invalid sort.",
+	models.APIErrorInvalidProductType:                          "Invalid product type.",
+	models.APIErrorInvalidProductID:                            "Invalid product id.",
+	models.APIErrorInvalidInAppOwnershipType:                   "Invalid in-app ownership type.",
+	models.APIErrorInvalidStorefrontCountryCode:                "Invalid storefront country code.",
+	models.APIErrorInvalidRevoked:                              "Invalid revoked value.",
+	models.APIErrorInvalidStatus:                               "Invalid status.",
+	models.APIErrorInvalidCustomerConsented:                    "Invalid customer consented.",
+	models.APIErrorInvalidTransactionNotConsumable:             "Invalid transaction not consumable.",
+	models.APIErrorInvalidDeliveryStatus:                       "Invalid delivery status.",
+	models.APIErrorInvalidSampleContentProvided:                "Invalid sample content provided.",
+	models.APIErrorInvalidAppAccountTokenUUID:                  "Invalid request. The app account token field must be a valid UUID.",
+	models.APIErrorFamilyTransactionNotSupported:               "Invalid request. Family Sharing transactions aren't supported by this endpoint.",
+	models.APIErrorTransactionIDIsNotOriginalTransactionID:     "Invalid request. The transaction ID provided is not an original transaction ID.",
+	models.APIErrorSubscriptionExtensionIneligible:             "Forbidden - subscription state ineligible for extension.",
+	models.APIErrorSubscriptionMaxExtension:                    "Forbidden - subscription has reached maximum extension count.",
+	models.APIErrorFamilySharedSubscriptionExtensionIneligible: "Forbidden - subscriptions for Family Sharing are ineligible for extension.",
+	models.APIErrorOriginalTransactionIDNotFound:               "Original transaction id not found.",
+	models.APIErrorTestNotificationNotFound:                    "Test notification not found.",
+	models.APIErrorStatusRequestNotFound:                       "The server didn't find a subscription-renewal-date extension request for this requestIdentifier and productId combination.",
+	models.APIErrorTransactionIDNotFound:                       "Transaction id not found.",
+	models.APIErrorAppTransactionDoesNotExist:                  "No AppTransaction exists for the customer.",
+	models.APIErrorInvalidImage:                                "Invalid image.",
+	models.APIErrorHeaderTooLong:                               "Header too long.",
+	models.APIErrorBodyTooLong:                                 "Body too long.",
+	models.APIErrorInvalidLocale:                               "Invalid locale.",
+	models.APIErrorAltTextTooLong:                              "Alt text too long.",
+	models.APIErrorMessageNotApproved:                          "Message not approved.",
+	models.APIErrorImageNotApproved:                            "Image not approved.",
+	models.APIErrorImageInUse:                                  "Image in use.",
+	models.APIErrorImageNotFound:                               "Image not found.",
+	models.APIErrorMessageNotFound:                             "Message not found.",
+	models.APIErrorImageAlreadyExists:                          "Image already exists.",
+	models.APIErrorMessageAlreadyExists:                        "Message already exists.",
+	models.APIErrorRateLimitExceeded:                           "Rate limit exceeded.",
+	models.APIErrorGeneralInternal:                             "An unknown error occurred.",
+}
@@ -0,0 +1,78 @@
+package appstoretest
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/DotNetAge/appstore"
+)
+
+// useGeneratedCertificateAuthority signs payloads with a new CertificateAuthority
+func (s *Server) useGeneratedCertificateAuthority() {
+	ca, err := NewCertificateAuthority()
+	if err != nil {
+		panic(fmt.Sprintf("appstoretest: %v", err))
+	}
+	s.ca = ca
+}
+
+// CertificateAuthority returns the certificate authority that signs the Server's payloads
+func (s *Server) CertificateAuthority() *CertificateAuthority {
+	return s.ca
+}
+
+// RootCertificate returns the DER encoding of the root certificate of the chain in the x5c header of signed payloads
+func (s *Server) RootCertificate() []byte {
+	return s.ca.RootCertificate()
+}
+
+// NewVerifier creates a SignedDataVerifier for the Server's bundle, App Apple ID and environment that trusts its
+// root certificate, so payloads returned by the Server verify end to end
+func (s *Server) NewVerifier(options ...appstore.VerifierOption) (*appstore.SignedDataVerifier, error) {
+	return s.ca.NewVerifier(s.environment, s.bundleID, &s.appAppleID, options...)
+}
+
+// sign returns the compact JWS serialization of payload, signed with ES256 and carrying the certificate chain
+func (s *Server) sign(payload interface{}) (string, error) {
+	return s.ca.Sign(payload)
+}
+
+// mustSign is sign for payloads built by the Server, which always encode
+func (s *Server) mustSign(payload interface{}) string {
+	signed, err := s.sign(payload)
+	if err != nil {
+		panic(fmt.Sprintf("appstoretest: failed to sign payload: %v", err))
+	}
+	return signed
+}
+
+// signJWS returns the compact JWS serialization of payload, signed with ES256 by key, with chain in its x5c header
+func signJWS(payload interface{}, key *ecdsa.PrivateKey, chain [][]byte) (string, error) {
+	x5c := make([]string, len(chain))
+	for i, certificate := range chain {
+		x5c[i] = base64.StdEncoding.EncodeToString(certificate)
+	}
+	header, err := json.Marshal(map[string]interface{}{"alg": "ES256", "x5c": x5c})
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	signingString := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	digest := sha256.Sum256([]byte(signingString))
+	r, sig, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	sig.FillBytes(signature[32:])
+	return signingString + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
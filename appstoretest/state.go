@@ -0,0 +1,375 @@
+package appstoretest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/DotNetAge/appstore/models"
+)
+
+// Notification is an App Store Server Notification in the Server's notification history
+type Notification struct {
+	// Payload is the decoded notification; the Server signs it when the history is requested
+	Payload models.ResponseBodyV2DecodedPayload
+	// TransactionID links the notification to the customer who owns the transaction, for history filtering
+	TransactionID string
+	// SendAttempts lists the attempts to send the notification to your server; none means it was sent successfully
+	SendAttempts []models.SendAttemptItem
+}
+
+// AddTransaction stores a transaction of a customer, creating the customer on first use, and returns it
+// with its defaults filled in. Missing identifiers are generated; the transaction is its own original
+// transaction unless OriginalTransactionId is set; transactions with an expiry date or a subscription group
+// are auto-renewable subscriptions, and get renewal info that renews automatically.
+func (s *Server) AddTransaction(customerID string, transaction models.JWSTransactionDecodedPayload) models.JWSTransactionDecodedPayload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now().UnixMilli()
+	if transaction.TransactionId == nil {
+		transaction.TransactionId = ptr(s.nextID())
+	}
+	if transaction.OriginalTransactionId == nil {
+		transaction.OriginalTransactionId = ptr(*transaction.TransactionId)
+	}
+	if transaction.PurchaseDate == nil {
+		transaction.PurchaseDate = ptr(now)
+	}
+	if transaction.OriginalPurchaseDate == nil {
+		transaction.OriginalPurchaseDate = ptr(*transaction.PurchaseDate)
+		if original, ok := s.transactions[*transaction.OriginalTransactionId]; ok {
+			transaction.OriginalPurchaseDate = ptr(*original.transaction.PurchaseDate)
+		}
+	}
+	if transaction.Type == nil {
+		transaction.Type = ptr(models.TypeNonConsumable)
+		if transaction.ExpiresDate != nil || transaction.SubscriptionGroupIdentifier != nil {
+			transaction.Type = ptr(models.TypeAutoRenewableSubscription)
+		}
+	}
+	if *transaction.Type == models.TypeAutoRenewableSubscription && transaction.WebOrderLineItemId == nil {
+		transaction.WebOrderLineItemId = ptr(s.nextID())
+	}
+	transaction.BundleId = ptr(s.bundleID)
+	transaction.Environment = ptr(s.environment)
+	setDefault(&transaction.ProductId, "com.example.product")
+	setDefault(&transaction.Quantity, 1)
+	setDefault(&transaction.InAppOwnershipType, models.InAppOwnershipTypePurchased)
+	setDefault(&transaction.TransactionReason, models.TransactionReasonPurchase)
+	setDefault(&transaction.Storefront, "USA")
+	setDefault(&transaction.StorefrontId, "143441")
+
+	if _, ok := s.transactions[*transaction.TransactionId]; !ok {
+		s.customers[customerID] = append(s.customers[customerID], *transaction.TransactionId)
+	}
+	s.transactions[*transaction.TransactionId] = &transactionRecord{customerID: customerID, transaction: transaction}
+
+	if *transaction.Type == models.TypeAutoRenewableSubscription {
+		renewal, ok := s.renewals[*transaction.OriginalTransactionId]
+		if !ok {
+			renewal = &models.JWSRenewalInfoDecodedPayload{
+				OriginalTransactionId:       ptr(*transaction.OriginalTransactionId),
+				AutoRenewStatus:             ptr(models.AutoRenewStatusOn),
+				RecentSubscriptionStartDate: ptr(*transaction.OriginalPurchaseDate),
+				Environment:                 ptr(s.environment),
+			}
+			s.renewals[*transaction.OriginalTransactionId] = renewal
+		}
+		renewal.ProductId = ptr(*transaction.ProductId)
+		renewal.AutoRenewProductId = ptr(*transaction.ProductId)
+		renewal.RenewalDate = transaction.ExpiresDate
+	}
+	return transaction
+}
+
+// Transaction returns a stored transaction
+func (s *Server) Transaction(transactionID string) (models.JWSTransactionDecodedPayload, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.transactions[transactionID]
+	if !ok {
+		return models.JWSTransactionDecodedPayload{}, false
+	}
+	return record.transaction, true
+}
+
+// SetRenewalInfo replaces the renewal info of the subscription identified by info.OriginalTransactionId
+func (s *Server) SetRenewalInfo(info models.JWSRenewalInfoDecodedPayload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if info.OriginalTransactionId == nil {
+		return fmt.Errorf("appstoretest: renewal info needs an OriginalTransactionId")
+	}
+	if _, ok := s.transactions[*info.OriginalTransactionId]; !ok {
+		return fmt.Errorf("appstoretest: no transaction %s", *info.OriginalTransactionId)
+	}
+	info.Environment = ptr(s.environment)
+	s.renewals[*info.OriginalTransactionId] = &info
+	return nil
+}
+
+// RenewalInfo returns the renewal info of a subscription
+func (s *Server) RenewalInfo(originalTransactionID string) (models.JWSRenewalInfoDecodedPayload, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	renewal, ok := s.renewals[originalTransactionID]
+	if !ok {
+		return models.JWSRenewalInfoDecodedPayload{}, false
+	}
+	return *renewal, true
+}
+
+// SetAppTransaction stores the app transaction of a customer, their purchase of the app, and returns it with its
+// defaults filled in. Without one, Get App Transaction Info answers AppTransactionDoesNotExistError.
+func (s *Server) SetAppTransaction(customerID string, appTransaction models.AppTransaction) models.AppTransaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	appTransaction.BundleId = ptr(s.bundleID)
+	appTransaction.AppAppleId = ptr(s.appAppleID)
+	appTransaction.ReceiptType = ptr(s.environment)
+	setDefault(&appTransaction.ApplicationVersion, "1")
+	setDefault(&appTransaction.OriginalApplicationVersion, *appTransaction.ApplicationVersion)
+	setDefault(&appTransaction.OriginalPurchaseDate, s.now().UnixMilli())
+	s.appTransactions[customerID] = &appTransaction
+	return appTransaction
+}
+
+// SetSubscriptionStatus overrides the status of a subscription, which is otherwise derived from its latest
+// transaction and renewal info
+func (s *Server) SetSubscriptionStatus(originalTransactionID string, status models.Status) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses[originalTransactionID] = status
+}
+
+// Refund revokes a transaction as if Apple had refunded it
+func (s *Server) Refund(transactionID string, reason models.RevocationReason) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.transactions[transactionID]
+	if !ok {
+		return fmt.Errorf("appstoretest: no transaction %s", transactionID)
+	}
+	record.transaction.RevocationDate = ptr(s.now().UnixMilli())
+	record.transaction.RevocationReason = ptr(reason)
+	return nil
+}
+
+// AddOrder stores the transactions of an order, for Look Up Order ID
+func (s *Server) AddOrder(orderID string, transactionIDs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orders[orderID] = append(s.orders[orderID], transactionIDs...)
+}
+
+// AddNotification stores a notification in the notification history and returns it with its defaults filled in
+func (s *Server) AddNotification(notification Notification) Notification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.addNotification(notification)
+}
+
+// ConsumptionData returns the consumption information last sent for a transaction
+func (s *Server) ConsumptionData(transactionID string) (models.ConsumptionRequest, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	request, ok := s.consumption[transactionID]
+	if !ok {
+		return models.ConsumptionRequest{}, false
+	}
+	return *request, true
+}
+
+// ConsumptionDataV2 returns the consumption information last sent for a transaction with version 2 of the endpoint
+func (s *Server) ConsumptionDataV2(transactionID string) (models.ConsumptionRequestV2, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	request, ok := s.consumptionV2[transactionID]
+	if !ok {
+		return models.ConsumptionRequestV2{}, false
+	}
+	return *request, true
+}
+
+// MassExtensionStatus returns the status of a renewal-date extension for all active subscribers
+func (s *Server) MassExtensionStatus(requestIdentifier string) (models.MassExtendRenewalDateStatusResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	extension, ok := s.massExtensions[requestIdentifier]
+	if !ok {
+		return models.MassExtendRenewalDateStatusResponse{}, false
+	}
+	return extension.status, true
+}
+
+// addNotification fills in the notification defaults and stores it; s.mu must be held
+func (s *Server) addNotification(notification Notification) *Notification {
+	payload := &notification.Payload
+	if payload.NotificationUUID == nil {
+		payload.NotificationUUID = ptr(newUUID())
+	}
+	setDefault(&payload.Version, "2.0")
+	setDefault(&payload.SignedDate, s.now().UnixMilli())
+	if payload.Data != nil {
+		payload.Data.Environment = ptr(s.environment)
+		payload.Data.BundleId = ptr(s.bundleID)
+		payload.Data.AppAppleId = ptr(s.appAppleID)
+	}
+	if payload.Summary != nil {
+		payload.Summary.Environment = ptr(s.environment)
+		payload.Summary.BundleId = ptr(s.bundleID)
+		payload.Summary.AppAppleId = ptr(s.appAppleID)
+	}
+	if len(notification.SendAttempts) == 0 {
+		notification.SendAttempts = []models.SendAttemptItem{{
+			AttemptDate:       ptr(*payload.SignedDate),
+			SendAttemptResult: ptr(models.SendAttemptResultSuccess),
+		}}
+	}
+
+	s.notifications = append(s.notifications, &notification)
+	return &notification
+}
+
+// customerTransactions returns the transactions of a customer in purchase order; s.mu must be held
+func (s *Server) customerTransactions(customerID string) []models.JWSTransactionDecodedPayload {
+	transactions := make([]models.JWSTransactionDecodedPayload, 0, len(s.customers[customerID]))
+	for _, id := range s.customers[customerID] {
+		transactions = append(transactions, s.transactions[id].transaction)
+	}
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return *transactions[i].PurchaseDate < *transactions[j].PurchaseDate
+	})
+	return transactions
+}
+
+// latestTransaction returns the most recent transaction of a subscription; s.mu must be held
+func (s *Server) latestTransaction(originalTransactionID string) (*transactionRecord, bool) {
+	original, ok := s.transactions[originalTransactionID]
+	if !ok {
+		return nil, false
+	}
+
+	latest := original
+	for _, id := range s.customers[original.customerID] {
+		record := s.transactions[id]
+		if *record.transaction.OriginalTransactionId == originalTransactionID && *record.transaction.PurchaseDate >= *latest.transaction.PurchaseDate {
+			latest = record
+		}
+	}
+	return latest, true
+}
+
+// subscriptionStatus returns the status of a subscription given its latest transaction; s.mu must be held
+func (s *Server) subscriptionStatus(latest models.JWSTransactionDecodedPayload) models.Status {
+	if status, ok := s.statuses[*latest.OriginalTransactionId]; ok {
+		return status
+	}
+
+	now := s.now().UnixMilli()
+	renewal := s.renewals[*latest.OriginalTransactionId]
+	switch {
+	case latest.RevocationDate != nil:
+		return models.StatusRevoked
+	case latest.ExpiresDate != nil && *latest.ExpiresDate > now:
+		return models.StatusActive
+	case renewal != nil && renewal.GracePeriodExpiresDate != nil && *renewal.GracePeriodExpiresDate > now:
+		return models.StatusBillingGracePeriod
+	case renewal != nil && renewal.IsInBillingRetryPeriod != nil && *renewal.IsInBillingRetryPeriod:
+		return models.StatusBillingRetry
+	default:
+		return models.StatusExpired
+	}
+}
+
+// signedTransaction signs a transaction as Apple does when returning it; s.mu must be held
+func (s *Server) signedTransaction(transaction models.JWSTransactionDecodedPayload) string {
+	transaction.SignedDate = ptr(s.now().UnixMilli())
+	return s.mustSign(transaction)
+}
+
+// signedAppTransaction signs an app transaction as Apple does when returning it; s.mu must be held
+func (s *Server) signedAppTransaction(appTransaction models.AppTransaction) string {
+	appTransaction.ReceiptCreationDate = ptr(s.now().UnixMilli())
+	return s.mustSign(appTransaction)
+}
+
+// signedRenewalInfo signs the renewal info of a subscription, if it has any; s.mu must be held
+func (s *Server) signedRenewalInfo(originalTransactionID string) *string {
+	renewal, ok := s.renewals[originalTransactionID]
+	if !ok {
+		return nil
+	}
+	signed := *renewal
+	signed.SignedDate = ptr(s.now().UnixMilli())
+	return ptr(s.mustSign(signed))
+}
+
+// nextID returns a new numeric identifier shaped like Apple's transaction identifiers; s.mu must be held
+func (s *Server) nextID() string {
+	s.sequence++
+	return strconv.FormatInt(2000000000000000+s.sequence, 10)
+}
+
+// pageToken returns the opaque token of the page starting at offset
+func pageToken(offset int) string {
+	return hex.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+// parsePageToken returns the offset encoded by a page token, or 0 for an empty token
+func parsePageToken(token string) (int, bool) {
+	if token == "" {
+		return 0, true
+	}
+	decoded, err := hex.DecodeString(token)
+	if err != nil {
+		return 0, false
+	}
+	offset, err := strconv.Atoi(string(decoded[min(len(decoded), len("offset:")):]))
+	if err != nil || offset < 0 || string(decoded[:min(len(decoded), len("offset:"))]) != "offset:" {
+		return 0, false
+	}
+	return offset, true
+}
+
+// page returns the items of the page starting at offset, the token of the next page and whether there is one
+func page[T any](items []T, offset int) ([]T, string, bool) {
+	if offset > len(items) {
+		offset = len(items)
+	}
+	end := min(offset+pageSize, len(items))
+	return items[offset:end], pageToken(end), end < len(items)
+}
+
+// newUUID returns a random (version 4) UUID
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// ptr returns a pointer to a copy of v
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// setDefault sets *field to v if it is nil
+func setDefault[T any](field **T, v T) {
+	if *field == nil {
+		*field = &v
+	}
+}
@@ -2,6 +2,7 @@ package appstore
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 
@@ -11,20 +12,26 @@ import (
 type AppStoreServerClient struct {
 	client   *AppStoreServerAPIClient
 	verifier *SignedDataVerifier
+	logger   *slog.Logger
 }
 
 func NewAppStoreServerClient(
 	keyID, issuerID, bundleID string, appID int64,
 	signingKey []byte,
 	rootCertPath string,
-	environment models.Environment) *AppStoreServerClient {
+	environment models.Environment,
+	options ...ClientOption) *AppStoreServerClient {
 
-	baseClient, err := NewAppStoreServerAPIClient(
+	// Without options, the client behaves as this constructor always has: LocalTesting and unrecognised environments
+	// fall back as before, and requests aren't retried or rate limited
+	opts := newLegacyClientOptions(options)
+	baseClient, err := newAppStoreServerAPIClient(
 		signingKey,
 		keyID,
 		issuerID,
 		bundleID,
-		environment)
+		environment,
+		opts)
 	if err != nil {
 		panic(fmt.Sprintf("failed to create AppStoreServerAPIClient: %v", err))
 	}
@@ -36,7 +43,10 @@ func NewAppStoreServerClient(
 	}
 
 	// 初始化验证器
-	verifier, err := NewSignedDataVerifier(rootCerts, false, environment, bundleID, &appID)
+	verifier, err := NewSignedDataVerifier(rootCerts, false, environment, bundleID, &appID,
+		WithVerifierTracerProvider(opts.tracerProvider),
+		WithVerifierMetrics(opts.metrics),
+		WithVerifierLogger(opts.logger))
 	if err != nil {
 		panic(fmt.Sprintf("failed to create signed data verifier: %v", err))
 	}
@@ -44,6 +54,7 @@ func NewAppStoreServerClient(
 	return &AppStoreServerClient{
 		client:   baseClient,
 		verifier: verifier,
+		logger:   loggerOrDiscard(opts.logger),
 	}
 }
 
@@ -0,0 +1,156 @@
+package appstore
+
+import (
+	"context"
+	"errors"
+
+	"github.com/DotNetAge/appstore/models"
+)
+
+// DualEnvironmentClient queries Production first and falls back to Sandbox when the transaction isn't found there,
+// following Apple's recommended flow for apps whose reviewers and TestFlight users share the production backend.
+// Each method also returns the environment that answered.
+type DualEnvironmentClient struct {
+	production *AppStoreServerClient
+	sandbox    *AppStoreServerClient
+}
+
+// NewDualEnvironmentClient creates a DualEnvironmentClient holding a Production and a Sandbox AppStoreServerClient.
+// The options apply to both clients, so they can't include WithBaseURL, which would send both environments to the
+// same host; use NewDualEnvironmentClientWithOptions to configure each environment separately.
+func NewDualEnvironmentClient(
+	keyID, issuerID, bundleID string, appID int64,
+	signingKey []byte,
+	rootCertPath string,
+	options ...ClientOption) *DualEnvironmentClient {
+
+	if newClientOptions(options).baseURL != "" {
+		panic("WithBaseURL can't be shared by the Production and Sandbox clients; use NewDualEnvironmentClientWithOptions")
+	}
+	return NewDualEnvironmentClientWithOptions(keyID, issuerID, bundleID, appID, signingKey, rootCertPath, options, options)
+}
+
+// NewDualEnvironmentClientWithOptions creates a DualEnvironmentClient whose Production and Sandbox clients are
+// configured by their own options, for example to point each of them at a different WithBaseURL
+func NewDualEnvironmentClientWithOptions(
+	keyID, issuerID, bundleID string, appID int64,
+	signingKey []byte,
+	rootCertPath string,
+	productionOptions, sandboxOptions []ClientOption) *DualEnvironmentClient {
+
+	return &DualEnvironmentClient{
+		production: NewAppStoreServerClient(keyID, issuerID, bundleID, appID, signingKey, rootCertPath, models.EnvironmentProduction, productionOptions...),
+		sandbox:    NewAppStoreServerClient(keyID, issuerID, bundleID, appID, signingKey, rootCertPath, models.EnvironmentSandbox, sandboxOptions...),
+	}
+}
+
+// Production returns the client bound to the Production environment
+func (c *DualEnvironmentClient) Production() *AppStoreServerClient {
+	return c.production
+}
+
+// Sandbox returns the client bound to the Sandbox environment
+func (c *DualEnvironmentClient) Sandbox() *AppStoreServerClient {
+	return c.sandbox
+}
+
+// isTransactionNotFound reports whether err means the transaction doesn't exist in the queried environment
+func isTransactionNotFound(err error) bool {
+	return errors.Is(err, models.APIErrorTransactionIDNotFound) || errors.Is(err, models.APIErrorOriginalTransactionIDNotFound)
+}
+
+// withFallback calls fn with the Production client, then with the Sandbox client if the transaction wasn't found
+func withFallback[T any](ctx context.Context, c *DualEnvironmentClient, fn func(client *AppStoreServerClient) (T, error)) (T, models.Environment, error) {
+	result, err := fn(c.production)
+	if err == nil || !isTransactionNotFound(err) || ctx.Err() != nil {
+		return result, models.EnvironmentProduction, err
+	}
+
+	c.production.logger.DebugContext(ctx, "transaction not found in Production, retrying in Sandbox", "error", err)
+	result, err = fn(c.sandbox)
+	return result, models.EnvironmentSandbox, err
+}
+
+// GetTransactionInfo gets information about a single transaction for your app.
+func (c *DualEnvironmentClient) GetTransactionInfo(ctx context.Context, transactionID string) (*models.JWSTransactionDecodedPayload, models.Environment, error) {
+	return withFallback(ctx, c, func(client *AppStoreServerClient) (*models.JWSTransactionDecodedPayload, error) {
+		return client.GetTransactionInfo(ctx, transactionID)
+	})
+}
+
+// GetAppTransactionInfo gets the customer's verified app transaction.
+func (c *DualEnvironmentClient) GetAppTransactionInfo(ctx context.Context, transactionID string) (*models.AppTransaction, models.Environment, error) {
+	return withFallback(ctx, c, func(client *AppStoreServerClient) (*models.AppTransaction, error) {
+		return client.GetAppTransactionInfo(ctx, transactionID)
+	})
+}
+
+// GetTransactionHistory gets a customer's in-app purchase transaction history for your app.
+func (c *DualEnvironmentClient) GetTransactionHistory(ctx context.Context, transactionID string, options ...TransactionHistoryOption) ([]*models.JWSTransactionDecodedPayload, models.Environment, error) {
+	return withFallback(ctx, c, func(client *AppStoreServerClient) ([]*models.JWSTransactionDecodedPayload, error) {
+		return client.GetTransactionHistory(ctx, transactionID, options...)
+	})
+}
+
+// GetAllSubscriptionStatuses gets the statuses for all of a customer's auto-renewable subscriptions in your app.
+func (c *DualEnvironmentClient) GetAllSubscriptionStatuses(ctx context.Context, transactionID string, status []models.Status) (*models.StatusResponse, models.Environment, error) {
+	return withFallback(ctx, c, func(client *AppStoreServerClient) (*models.StatusResponse, error) {
+		return client.GetAllSubscriptionStatuses(ctx, transactionID, status)
+	})
+}
+
+// GetRefundHistory gets a paginated list of all of a customer's refunded in-app purchases for your app.
+func (c *DualEnvironmentClient) GetRefundHistory(ctx context.Context, transactionID string, revision string) (*models.RefundHistoryResponse, models.Environment, error) {
+	return withFallback(ctx, c, func(client *AppStoreServerClient) (*models.RefundHistoryResponse, error) {
+		return client.GetRefundHistory(ctx, transactionID, revision)
+	})
+}
+
+// ExtendSubscriptionRenewalDate extends the renewal date of a customer's active subscription using the original transaction identifier.
+func (c *DualEnvironmentClient) ExtendSubscriptionRenewalDate(ctx context.Context, originalTransactionID string, request *models.ExtendRenewalDateRequest) (*models.ExtendRenewalDateResponse, models.Environment, error) {
+	return withFallback(ctx, c, func(client *AppStoreServerClient) (*models.ExtendRenewalDateResponse, error) {
+		return client.ExtendSubscriptionRenewalDate(ctx, originalTransactionID, request)
+	})
+}
+
+// SendConsumptionData sends consumption information about a consumable in-app purchase to the App Store.
+func (c *DualEnvironmentClient) SendConsumptionData(ctx context.Context, transactionID string, request *models.ConsumptionRequest) (models.Environment, error) {
+	_, environment, err := withFallback(ctx, c, func(client *AppStoreServerClient) (struct{}, error) {
+		return struct{}{}, client.SendConsumptionData(ctx, transactionID, request)
+	})
+	return environment, err
+}
+
+// SendConsumptionDataV2 sends consumption information about an in-app purchase to the App Store, using version 2 of the endpoint.
+func (c *DualEnvironmentClient) SendConsumptionDataV2(ctx context.Context, transactionID string, request *models.ConsumptionRequestV2) (models.Environment, error) {
+	_, environment, err := withFallback(ctx, c, func(client *AppStoreServerClient) (struct{}, error) {
+		return struct{}{}, client.SendConsumptionDataV2(ctx, transactionID, request)
+	})
+	return environment, err
+}
+
+// VerifyAndDecodeSignedTransaction verifies and decodes a signedTransaction with the verifier of the environment it was signed in
+func (c *DualEnvironmentClient) VerifyAndDecodeSignedTransaction(ctx context.Context, signedTransaction string) (*models.JWSTransactionDecodedPayload, models.Environment, error) {
+	payload, err := c.production.verifier.VerifyAndDecodeSignedTransactionContext(ctx, signedTransaction)
+	if !isWrongEnvironment(err) {
+		return payload, models.EnvironmentProduction, err
+	}
+	payload, err = c.sandbox.verifier.VerifyAndDecodeSignedTransactionContext(ctx, signedTransaction)
+	return payload, models.EnvironmentSandbox, err
+}
+
+// VerifyAndDecodeNotification verifies and decodes a notification signedPayload with the verifier of the environment it was sent from
+func (c *DualEnvironmentClient) VerifyAndDecodeNotification(ctx context.Context, signedPayload string) (*models.ResponseBodyV2DecodedPayload, models.Environment, error) {
+	payload, err := c.production.verifier.VerifyAndDecodeNotificationContext(ctx, signedPayload)
+	if !isWrongEnvironment(err) {
+		return payload, models.EnvironmentProduction, err
+	}
+	payload, err = c.sandbox.verifier.VerifyAndDecodeNotificationContext(ctx, signedPayload)
+	return payload, models.EnvironmentSandbox, err
+}
+
+// isWrongEnvironment reports whether err means the signed data comes from another environment
+func isWrongEnvironment(err error) bool {
+	var verificationErr *VerificationException
+	return errors.As(err, &verificationErr) && verificationErr.Status == VerificationStatusInvalidEnvironment
+}
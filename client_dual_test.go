@@ -0,0 +1,79 @@
+package appstore_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DotNetAge/appstore"
+	"github.com/DotNetAge/appstore/appstoretest"
+	"github.com/DotNetAge/appstore/models"
+)
+
+// writeRootCertificate writes the root of ca to a directory and returns its path
+func writeRootCertificate(t *testing.T, ca *appstoretest.CertificateAuthority) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "root.cer"), ca.RootCertificate(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestDualEnvironmentClientRejectsSharedBaseURL(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewDualEnvironmentClient accepted WithBaseURL")
+		}
+	}()
+	appstore.NewDualEnvironmentClient("KEY_ID", "ISSUER_ID", "com.example", 1234567890, nil, t.TempDir(),
+		appstore.WithBaseURL("http://localhost:8080"))
+}
+
+func TestDualEnvironmentClientFallback(t *testing.T) {
+	ca, err := appstoretest.NewCertificateAuthority()
+	if err != nil {
+		t.Fatal(err)
+	}
+	production := appstoretest.NewServer(appstoretest.WithEnvironment(models.EnvironmentProduction), appstoretest.WithCertificateAuthority(ca))
+	defer production.Close()
+	sandbox := appstoretest.NewServer(appstoretest.WithEnvironment(models.EnvironmentSandbox), appstoretest.WithCertificateAuthority(ca))
+	defer sandbox.Close()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := func(server *appstoretest.Server) []appstore.ClientOption {
+		return []appstore.ClientOption{appstore.WithBaseURL(server.URL), appstore.WithSigner(key), appstore.WithRateLimiter(nil)}
+	}
+	dual := appstore.NewDualEnvironmentClientWithOptions(appstoretest.DefaultKeyID, appstoretest.DefaultIssuerID,
+		production.BundleID(), production.AppAppleID(), nil, writeRootCertificate(t, ca), options(production), options(sandbox))
+
+	// The servers number transactions alike, so give each transaction an identifier the other server doesn't have
+	tests := []struct {
+		name          string
+		server        *appstoretest.Server
+		transactionID string
+		want          models.Environment
+	}{
+		{name: "found in Production", server: production, transactionID: "1000000000000001", want: models.EnvironmentProduction},
+		{name: "found in Sandbox", server: sandbox, transactionID: "2000000000000001", want: models.EnvironmentSandbox},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.server.AddTransaction("customer", models.JWSTransactionDecodedPayload{TransactionId: &tt.transactionID})
+			transaction, environment, err := dual.GetTransactionInfo(context.Background(), tt.transactionID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if environment != tt.want || *transaction.Environment != tt.want {
+				t.Errorf("answered by %s with a %s transaction, want %s", environment, *transaction.Environment, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package appstore
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/DotNetAge/appstore/models"
 )
@@ -18,7 +19,7 @@ func (c *AppStoreServerClient) GetTestNotificationStatus(ctx context.Context, te
 		return nil, fmt.Errorf("signed payload is nil")
 	}
 
-	decodedPayload, err := c.verifier.VerifyAndDecodeNotification(*resp.SignedPayload)
+	decodedPayload, err := c.verifier.VerifyAndDecodeNotificationContext(ctx, *resp.SignedPayload)
 	if err != nil {
 		return nil, err
 	}
@@ -84,14 +85,14 @@ func WithOnlyFailures(onlyFailures bool) NotificationHistoryOption {
 }
 
 // decodeNotifications decodes a slice of notification history items into decoded payloads
-func (c *AppStoreServerClient) decodeNotifications(notifications []models.NotificationHistoryResponseItem) ([]*models.ResponseBodyV2DecodedPayload, error) {
+func (c *AppStoreServerClient) decodeNotifications(ctx context.Context, notifications []models.NotificationHistoryResponseItem) ([]*models.ResponseBodyV2DecodedPayload, error) {
 	var decodedPayloads []*models.ResponseBodyV2DecodedPayload
 	for _, notification := range notifications {
 		if notification.SignedPayload == nil {
 			return nil, fmt.Errorf("signed payload is nil")
 		}
 
-		decodedPayload, err := c.verifier.VerifyAndDecodeNotification(*notification.SignedPayload)
+		decodedPayload, err := c.verifier.VerifyAndDecodeNotificationContext(ctx, *notification.SignedPayload)
 		if err != nil {
 			return nil, err
 		}
@@ -131,16 +132,21 @@ func (c *AppStoreServerClient) GetNotificationHistory(ctx context.Context, pagin
 		return nil, fmt.Errorf("notification history is nil")
 	}
 
-	decodedPayloads, err := c.decodeNotifications(resp.NotificationHistory)
+	decodedPayloads, err := c.decodeNotifications(ctx, resp.NotificationHistory)
 	if err != nil {
 		return nil, err
 	}
 
-	for resp.HasMore != nil && *resp.HasMore {
+	for page := 2; resp.HasMore != nil && *resp.HasMore; page++ {
 		if resp.PaginationToken == nil {
 			break
 		}
 
+		c.logger.LogAttrs(ctx, slog.LevelDebug, "fetching next notification history page",
+			slog.Int("page", page),
+			slog.Int("notifications", len(decodedPayloads)),
+		)
+
 		nextResp, err := c.client.GetNotificationHistory(ctx, *resp.PaginationToken, request)
 		if err != nil {
 			return nil, err
@@ -150,7 +156,7 @@ func (c *AppStoreServerClient) GetNotificationHistory(ctx context.Context, pagin
 			break
 		}
 
-		nextDecoded, err := c.decodeNotifications(nextResp.NotificationHistory)
+		nextDecoded, err := c.decodeNotifications(ctx, nextResp.NotificationHistory)
 		if err != nil {
 			return nil, err
 		}
@@ -162,9 +168,9 @@ func (c *AppStoreServerClient) GetNotificationHistory(ctx context.Context, pagin
 	return decodedPayloads, nil
 }
 
-func (c *AppStoreServerClient) TestNotification() (*models.ResponseBodyV2DecodedPayload, error) {
+func (c *AppStoreServerClient) TestNotification(ctx context.Context) (*models.ResponseBodyV2DecodedPayload, error) {
 	// 1. Request Test Notification
-	resp, err := c.client.RequestTestNotification(context.Background())
+	resp, err := c.client.RequestTestNotification(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to request test notification: %w", err)
 	}
@@ -175,7 +181,7 @@ func (c *AppStoreServerClient) TestNotification() (*models.ResponseBodyV2Decoded
 	}
 
 	// 2. Request Test Notification Status with token
-	notifyResp, err := c.client.GetTestNotificationStatus(context.Background(), *token)
+	notifyResp, err := c.client.GetTestNotificationStatus(ctx, *token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get test notification status: %w", err)
 	}
@@ -186,7 +192,7 @@ func (c *AppStoreServerClient) TestNotification() (*models.ResponseBodyV2Decoded
 	}
 
 	// 验证并解码通知
-	responseBodyV2, err := c.verifier.VerifyAndDecodeNotification(*notifyResp.SignedPayload)
+	responseBodyV2, err := c.verifier.VerifyAndDecodeNotificationContext(ctx, *notifyResp.SignedPayload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to verify and decode notification: %w", err)
 	}
@@ -3,6 +3,7 @@ package appstore
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/DotNetAge/appstore/models"
@@ -87,7 +88,7 @@ func WithVersion(version GetTransactionHistoryVersion) TransactionHistoryOption
 }
 
 // GetTransactionHistory gets a customer's in-app purchase transaction history for your app.
-func (c *AppStoreServerClient) GetTransactionHistory(transactionID string, options ...TransactionHistoryOption) ([]*models.JWSTransactionDecodedPayload, error) {
+func (c *AppStoreServerClient) GetTransactionHistory(ctx context.Context, transactionID string, options ...TransactionHistoryOption) ([]*models.JWSTransactionDecodedPayload, error) {
 	// Set default options
 	opts := &transactionHistoryOptions{
 		version: GetTransactionHistoryVersionV1,
@@ -109,15 +110,21 @@ func (c *AppStoreServerClient) GetTransactionHistory(transactionID string, optio
 		Revoked:                      &opts.revoked,
 	}
 
-	resp, err := c.client.GetTransactionHistory(context.Background(), transactionID, opts.revision, request, opts.version)
+	resp, err := c.client.GetTransactionHistory(ctx, transactionID, opts.revision, request, opts.version)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get transaction history: %w", err)
 	}
 
+	c.logger.LogAttrs(ctx, slog.LevelDebug, "fetched transaction history page",
+		slog.String("transaction_id", transactionID),
+		slog.Int("transactions", len(resp.SignedTransactions)),
+		slog.Bool("has_more", resp.HasMore != nil && *resp.HasMore),
+	)
+
 	// 验证并解码所有签名的交易
 	var decodedTransactions []*models.JWSTransactionDecodedPayload
 	for _, signedTransaction := range resp.SignedTransactions {
-		playload, err := c.verifier.VerifyAndDecodeSignedTransaction(signedTransaction)
+		playload, err := c.verifier.VerifyAndDecodeSignedTransactionContext(ctx, signedTransaction)
 		if err != nil {
 			return nil, fmt.Errorf("failed to verify and decode transaction: %w", err)
 		}
@@ -128,8 +135,8 @@ func (c *AppStoreServerClient) GetTransactionHistory(transactionID string, optio
 }
 
 // GetTransactionInfo gets information about a single transaction for your app.
-func (c *AppStoreServerClient) GetTransactionInfo(transactionID string) (*models.JWSTransactionDecodedPayload, error) {
-	resp, err := c.client.GetTransactionInfo(context.Background(), transactionID)
+func (c *AppStoreServerClient) GetTransactionInfo(ctx context.Context, transactionID string) (*models.JWSTransactionDecodedPayload, error) {
+	resp, err := c.client.GetTransactionInfo(ctx, transactionID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get transaction info: %w", err)
 	}
@@ -139,7 +146,7 @@ func (c *AppStoreServerClient) GetTransactionInfo(transactionID string) (*models
 	}
 
 	// 验证并解码签名的交易
-	playload, err := c.verifier.VerifyAndDecodeSignedTransaction(*resp.SignedTransactionInfo)
+	playload, err := c.verifier.VerifyAndDecodeSignedTransactionContext(ctx, *resp.SignedTransactionInfo)
 	if err != nil {
 		return nil, fmt.Errorf("failed to verify and decode transaction: %w", err)
 	}
@@ -147,9 +154,28 @@ func (c *AppStoreServerClient) GetTransactionInfo(transactionID string) (*models
 	return playload, nil
 }
 
+// GetAppTransactionInfo gets the customer's verified app transaction, which records when they originally purchased the app.
+func (c *AppStoreServerClient) GetAppTransactionInfo(ctx context.Context, transactionID string) (*models.AppTransaction, error) {
+	resp, err := c.client.GetAppTransactionInfo(ctx, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get app transaction info: %w", err)
+	}
+
+	if resp.SignedAppTransactionInfo == nil {
+		return nil, fmt.Errorf("signed app transaction info is nil")
+	}
+
+	appTransaction, err := c.verifier.VerifyAndDecodeAppTransactionContext(ctx, *resp.SignedAppTransactionInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify and decode app transaction: %w", err)
+	}
+
+	return appTransaction, nil
+}
+
 // LookUpOrderID gets a customer's in-app purchases from a receipt using the order ID.
-func (c *AppStoreServerClient) LookUpOrderID(orderID string) ([]*models.JWSTransactionDecodedPayload, error) {
-	resp, err := c.client.LookUpOrderID(context.Background(), orderID)
+func (c *AppStoreServerClient) LookUpOrderID(ctx context.Context, orderID string) ([]*models.JWSTransactionDecodedPayload, error) {
+	resp, err := c.client.LookUpOrderID(ctx, orderID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to look up order ID: %w", err)
 	}
@@ -158,7 +184,7 @@ func (c *AppStoreServerClient) LookUpOrderID(orderID string) ([]*models.JWSTrans
 
 	// 验证并解码所有签名的交易
 	for _, signedTransaction := range resp.SignedTransactions {
-		playload, err := c.verifier.VerifyAndDecodeSignedTransaction(signedTransaction)
+		playload, err := c.verifier.VerifyAndDecodeSignedTransactionContext(ctx, signedTransaction)
 		if err != nil {
 			return nil, fmt.Errorf("failed to verify and decode transaction: %w", err)
 		}
@@ -168,9 +194,18 @@ func (c *AppStoreServerClient) LookUpOrderID(orderID string) ([]*models.JWSTrans
 	return decodedTransactions, nil
 }
 
-// SendConsumptionData sends consumption information about a consumable in-app purchase to the App Store.
-func (c *AppStoreServerClient) SendConsumptionData(transactionID string, request *models.ConsumptionRequest) error {
-	err := c.client.SendConsumptionData(context.Background(), transactionID, request)
+// SendConsumptionDataV2 sends consumption information about an in-app purchase to the App Store, using version 2 of the endpoint.
+func (c *AppStoreServerClient) SendConsumptionDataV2(ctx context.Context, transactionID string, request *models.ConsumptionRequestV2) error {
+	if err := c.client.SendConsumptionDataV2(ctx, transactionID, request); err != nil {
+		return fmt.Errorf("failed to send consumption data: %w", err)
+	}
+	return nil
+}
+
+// SendConsumptionData sends consumption information about a consumable in-app purchase to the App Store,
+// using version 1 of the endpoint.
+func (c *AppStoreServerClient) SendConsumptionData(ctx context.Context, transactionID string, request *models.ConsumptionRequest) error {
+	err := c.client.SendConsumptionData(ctx, transactionID, request)
 	if err != nil {
 		return fmt.Errorf("failed to send consumption data: %w", err)
 	}
@@ -179,6 +214,19 @@ func (c *AppStoreServerClient) SendConsumptionData(transactionID string, request
 	return nil
 }
 
+// SetAppAccountToken sets the app account token of a purchase, identified by its original transaction identifier.
+func (c *AppStoreServerClient) SetAppAccountToken(ctx context.Context, originalTransactionID string, request *models.UpdateAppAccountTokenRequest) error {
+	if err := c.client.SetAppAccountToken(ctx, originalTransactionID, request); err != nil {
+		return fmt.Errorf("failed to set app account token: %w", err)
+	}
+	return nil
+}
+
+// BackfillAppAccountTokens sets the app account tokens of many purchases; see AppStoreServerAPIClient.BackfillAppAccountTokens.
+func (c *AppStoreServerClient) BackfillAppAccountTokens(ctx context.Context, assignments []AppAccountTokenAssignment, concurrency int) ([]AppAccountTokenResult, error) {
+	return c.client.BackfillAppAccountTokens(ctx, assignments, concurrency)
+}
+
 func (c *AppStoreServerClient) GetTransaction(response *ResponseBodyV2) (*models.JWSTransactionDecodedPayload, error) {
 	playload, err := c.verifier.VerifyAndDecodeSignedTransaction(response.SignedPayload)
 	if err != nil {
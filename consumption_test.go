@@ -0,0 +1,130 @@
+package appstore_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/DotNetAge/appstore"
+	"github.com/DotNetAge/appstore/models"
+)
+
+func TestSendConsumptionInformation(t *testing.T) {
+	server, requests := newInterceptorTestServer(t, &eventLog{})
+	var endpoints []appstore.Endpoint
+	recordEndpoint := appstore.InterceptorFunc(func(ctx context.Context, call *appstore.Call, next appstore.Invoker) error {
+		endpoints = append(endpoints, call.Endpoint)
+		return next(ctx, call)
+	})
+	client := newInterceptorTestClient(t, server, appstore.WithInterceptors(recordEndpoint))
+
+	tests := []struct {
+		name         string
+		request      interface{}
+		wantPath     string
+		wantEndpoint appstore.Endpoint
+		wantBody     map[string]interface{}
+	}{
+		{
+			name: "version 1",
+			request: &models.ConsumptionRequest{
+				CustomerConsented:        ptr(true),
+				ConsumptionStatus:        ptr(models.ConsumptionStatusPartiallyConsumed),
+				Platform:                 ptr(models.PlatformApple),
+				SampleContentProvided:    ptr(false),
+				DeliveryStatus:           ptr(models.DeliveryStatusDeliveredAndWorkingProperly),
+				AppAccountToken:          ptr(""),
+				AccountTenure:            ptr(models.AccountTenureUndeclared),
+				PlayTime:                 ptr(models.PlayTimeUndeclared),
+				LifetimeDollarsRefunded:  ptr(models.LifetimeDollarsRefundedUndeclared),
+				LifetimeDollarsPurchased: ptr(models.LifetimeDollarsPurchasedUndeclared),
+				UserStatus:               ptr(models.UserStatusActive),
+			},
+			wantPath:     "/inApps/v1/transactions/consumption/1000",
+			wantEndpoint: appstore.EndpointSendConsumptionData,
+			wantBody: map[string]interface{}{
+				"customerConsented":        true,
+				"consumptionStatus":        float64(models.ConsumptionStatusPartiallyConsumed),
+				"platform":                 float64(models.PlatformApple),
+				"sampleContentProvided":    false,
+				"deliveryStatus":           float64(models.DeliveryStatusDeliveredAndWorkingProperly),
+				"appAccountToken":          "",
+				"accountTenure":            float64(models.AccountTenureUndeclared),
+				"playTime":                 float64(models.PlayTimeUndeclared),
+				"lifetimeDollarsRefunded":  float64(models.LifetimeDollarsRefundedUndeclared),
+				"lifetimeDollarsPurchased": float64(models.LifetimeDollarsPurchasedUndeclared),
+				"userStatus":               float64(models.UserStatusActive),
+			},
+		},
+		{
+			name: "version 2",
+			request: &models.ConsumptionRequestV2{
+				CustomerConsented:     ptr(true),
+				ConsumptionPercentage: ptr(25000),
+				DeliveryStatus:        ptr(models.DeliveryStatusV2Delivered),
+				RefundPreference:      ptr(models.RefundPreferenceV2GrantProrated),
+				SampleContentProvided: ptr(true),
+			},
+			wantPath:     "/inApps/v2/transactions/consumption/1000",
+			wantEndpoint: appstore.EndpointSendConsumptionDataV2,
+			wantBody: map[string]interface{}{
+				"customerConsented":     true,
+				"consumptionPercentage": float64(25000),
+				"deliveryStatus":        "DELIVERED",
+				"refundPreference":      "GRANT_PRORATED",
+				"sampleContentProvided": true,
+			},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := client.SendConsumptionInformation(context.Background(), "1000", tt.request); err != nil {
+				t.Fatal(err)
+			}
+			received := requests()
+			if len(received) != i+1 {
+				t.Fatalf("sent %d requests, want %d", len(received), i+1)
+			}
+			request := received[i]
+			if request.method != http.MethodPut || request.path != tt.wantPath {
+				t.Errorf("sent %s %s, want PUT %s", request.method, request.path, tt.wantPath)
+			}
+			if got := request.header.Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %s", got)
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal(request.body, &body); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(body, tt.wantBody) {
+				t.Errorf("body = %v, want %v", body, tt.wantBody)
+			}
+			if got := endpoints[len(endpoints)-1]; got != tt.wantEndpoint {
+				t.Errorf("endpoint = %s, want %s", got, tt.wantEndpoint)
+			}
+		})
+	}
+
+	sent := len(requests())
+	invalid := []struct {
+		name    string
+		request interface{}
+	}{
+		{name: "unsupported type", request: models.ConsumptionRequestV2{}},
+		{name: "nil", request: nil},
+		{name: "invalid version 2 request", request: &models.ConsumptionRequestV2{CustomerConsented: ptr(false)}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := client.SendConsumptionInformation(context.Background(), "1000", tt.request); !errors.Is(err, models.ErrInvalidRequest) {
+				t.Errorf("error = %v, want ErrInvalidRequest", err)
+			}
+		})
+	}
+	if got := len(requests()); got != sent {
+		t.Errorf("sent %d requests for invalid consumption information", got-sent)
+	}
+}
@@ -0,0 +1,130 @@
+package appstore
+
+// Endpoint identifies an App Store Server API endpoint
+type Endpoint string
+
+const (
+	// EndpointGetTransactionHistory is the Get Transaction History endpoint
+	EndpointGetTransactionHistory Endpoint = "GetTransactionHistory"
+	// EndpointGetTransactionInfo is the Get Transaction Info endpoint
+	EndpointGetTransactionInfo Endpoint = "GetTransactionInfo"
+	// EndpointGetAppTransactionInfo is the Get App Transaction Info endpoint
+	EndpointGetAppTransactionInfo Endpoint = "GetAppTransactionInfo"
+	// EndpointGetAllSubscriptionStatuses is the Get All Subscription Statuses endpoint
+	EndpointGetAllSubscriptionStatuses Endpoint = "GetAllSubscriptionStatuses"
+	// EndpointLookUpOrderID is the Look Up Order ID endpoint
+	EndpointLookUpOrderID Endpoint = "LookUpOrderID"
+	// EndpointGetRefundHistory is the Get Refund History endpoint
+	EndpointGetRefundHistory Endpoint = "GetRefundHistory"
+	// EndpointExtendSubscriptionRenewalDate is the Extend a Subscription Renewal Date endpoint
+	EndpointExtendSubscriptionRenewalDate Endpoint = "ExtendSubscriptionRenewalDate"
+	// EndpointExtendRenewalDateForAllActiveSubscribers is the Extend Subscription Renewal Dates for All Active Subscribers endpoint
+	EndpointExtendRenewalDateForAllActiveSubscribers Endpoint = "ExtendRenewalDateForAllActiveSubscribers"
+	// EndpointGetStatusOfSubscriptionRenewalDateExtensions is the Get Status of Subscription Renewal Date Extensions endpoint
+	EndpointGetStatusOfSubscriptionRenewalDateExtensions Endpoint = "GetStatusOfSubscriptionRenewalDateExtensions"
+	// EndpointSendConsumptionData is the Send Consumption Information endpoint
+	EndpointSendConsumptionData Endpoint = "SendConsumptionData"
+	// EndpointSendConsumptionDataV2 is version 2 of the Send Consumption Information endpoint
+	EndpointSendConsumptionDataV2 Endpoint = "SendConsumptionDataV2"
+	// EndpointSetAppAccountToken is the Set App Account Token endpoint
+	EndpointSetAppAccountToken Endpoint = "SetAppAccountToken"
+	// EndpointGetNotificationHistory is the Get Notification History endpoint
+	EndpointGetNotificationHistory Endpoint = "GetNotificationHistory"
+	// EndpointRequestTestNotification is the Request a Test Notification endpoint
+	EndpointRequestTestNotification Endpoint = "RequestTestNotification"
+	// EndpointGetTestNotificationStatus is the Get Test Notification Status endpoint
+	EndpointGetTestNotificationStatus Endpoint = "GetTestNotificationStatus"
+	// EndpointUploadImage is the Retention Messaging API's Upload Image endpoint
+	EndpointUploadImage Endpoint = "UploadImage"
+	// EndpointDeleteImage is the Retention Messaging API's Delete Image endpoint
+	EndpointDeleteImage Endpoint = "DeleteImage"
+	// EndpointGetImageList is the Retention Messaging API's Get Image List endpoint
+	EndpointGetImageList Endpoint = "GetImageList"
+	// EndpointUploadMessage is the Retention Messaging API's Upload Message endpoint
+	EndpointUploadMessage Endpoint = "UploadMessage"
+	// EndpointDeleteMessage is the Retention Messaging API's Delete Message endpoint
+	EndpointDeleteMessage Endpoint = "DeleteMessage"
+	// EndpointGetMessageList is the Retention Messaging API's Get Message List endpoint
+	EndpointGetMessageList Endpoint = "GetMessageList"
+	// EndpointConfigureDefaultMessage is the Retention Messaging API's Configure Default Message endpoint
+	EndpointConfigureDefaultMessage Endpoint = "ConfigureDefaultMessage"
+	// EndpointDeleteDefaultMessage is the Retention Messaging API's Delete Default Message endpoint
+	EndpointDeleteDefaultMessage Endpoint = "DeleteDefaultMessage"
+	// EndpointCancelSubscription is the Advanced Commerce API's Cancel a Subscription endpoint
+	EndpointCancelSubscription Endpoint = "CancelSubscription"
+	// EndpointChangeSubscriptionMetadata is the Advanced Commerce API's Change Subscription Metadata endpoint
+	EndpointChangeSubscriptionMetadata Endpoint = "ChangeSubscriptionMetadata"
+	// EndpointChangeSubscriptionPrice is the Advanced Commerce API's Change Subscription Price endpoint
+	EndpointChangeSubscriptionPrice Endpoint = "ChangeSubscriptionPrice"
+	// EndpointMigrateSubscription is the Advanced Commerce API's Migrate a Subscription endpoint
+	EndpointMigrateSubscription Endpoint = "MigrateSubscription"
+	// EndpointRequestRefund is the Advanced Commerce API's Request a Refund endpoint
+	EndpointRequestRefund Endpoint = "RequestRefund"
+)
+
+// EndpointFamily groups endpoints that share a rate limit quota
+type EndpointFamily string
+
+const (
+	// EndpointFamilyHistory covers both versions of the Get Transaction History endpoint
+	EndpointFamilyHistory EndpointFamily = "history"
+	// EndpointFamilyRefundHistory covers the Get Refund History endpoint
+	EndpointFamilyRefundHistory EndpointFamily = "refundHistory"
+	// EndpointFamilyTransactionInfo covers the Get Transaction Info endpoint
+	EndpointFamilyTransactionInfo EndpointFamily = "transactionInfo"
+	// EndpointFamilyAppTransactionInfo covers the Get App Transaction Info endpoint
+	EndpointFamilyAppTransactionInfo EndpointFamily = "appTransactionInfo"
+	// EndpointFamilyStatuses covers the Get All Subscription Statuses endpoint
+	EndpointFamilyStatuses EndpointFamily = "statuses"
+	// EndpointFamilyLookup covers the Look Up Order ID endpoint
+	EndpointFamilyLookup EndpointFamily = "lookup"
+	// EndpointFamilyExtend covers the subscription renewal date extension endpoints
+	EndpointFamilyExtend EndpointFamily = "extend"
+	// EndpointFamilyConsumption covers both versions of the Send Consumption Information endpoint
+	EndpointFamilyConsumption EndpointFamily = "consumption"
+	// EndpointFamilyAppAccountToken covers the Set App Account Token endpoint
+	EndpointFamilyAppAccountToken EndpointFamily = "appAccountToken"
+	// EndpointFamilyNotificationHistory covers the notification history and test notification endpoints
+	EndpointFamilyNotificationHistory EndpointFamily = "notificationHistory"
+	// EndpointFamilyRetentionMessaging covers the Retention Messaging API endpoints
+	EndpointFamilyRetentionMessaging EndpointFamily = "retentionMessaging"
+	// EndpointFamilyAdvancedCommerce covers the Advanced Commerce API endpoints
+	EndpointFamilyAdvancedCommerce EndpointFamily = "advancedCommerce"
+)
+
+// endpointFamilies maps each endpoint to the family whose quota it consumes
+var endpointFamilies = map[Endpoint]EndpointFamily{
+	EndpointGetTransactionHistory:                        EndpointFamilyHistory,
+	EndpointGetRefundHistory:                             EndpointFamilyRefundHistory,
+	EndpointGetTransactionInfo:                           EndpointFamilyTransactionInfo,
+	EndpointGetAppTransactionInfo:                        EndpointFamilyAppTransactionInfo,
+	EndpointGetAllSubscriptionStatuses:                   EndpointFamilyStatuses,
+	EndpointLookUpOrderID:                                EndpointFamilyLookup,
+	EndpointExtendSubscriptionRenewalDate:                EndpointFamilyExtend,
+	EndpointExtendRenewalDateForAllActiveSubscribers:     EndpointFamilyExtend,
+	EndpointGetStatusOfSubscriptionRenewalDateExtensions: EndpointFamilyExtend,
+	EndpointSendConsumptionData:                          EndpointFamilyConsumption,
+	EndpointSendConsumptionDataV2:                        EndpointFamilyConsumption,
+	EndpointSetAppAccountToken:                           EndpointFamilyAppAccountToken,
+	EndpointGetNotificationHistory:                       EndpointFamilyNotificationHistory,
+	EndpointRequestTestNotification:                      EndpointFamilyNotificationHistory,
+	EndpointGetTestNotificationStatus:                    EndpointFamilyNotificationHistory,
+	EndpointUploadImage:                                  EndpointFamilyRetentionMessaging,
+	EndpointDeleteImage:                                  EndpointFamilyRetentionMessaging,
+	EndpointGetImageList:                                 EndpointFamilyRetentionMessaging,
+	EndpointUploadMessage:                                EndpointFamilyRetentionMessaging,
+	EndpointDeleteMessage:                                EndpointFamilyRetentionMessaging,
+	EndpointGetMessageList:                               EndpointFamilyRetentionMessaging,
+	EndpointConfigureDefaultMessage:                      EndpointFamilyRetentionMessaging,
+	EndpointDeleteDefaultMessage:                         EndpointFamilyRetentionMessaging,
+	EndpointCancelSubscription:                           EndpointFamilyAdvancedCommerce,
+	EndpointChangeSubscriptionMetadata:                   EndpointFamilyAdvancedCommerce,
+	EndpointChangeSubscriptionPrice:                      EndpointFamilyAdvancedCommerce,
+	EndpointMigrateSubscription:                          EndpointFamilyAdvancedCommerce,
+	EndpointRequestRefund:                                EndpointFamilyAdvancedCommerce,
+}
+
+// Family returns the rate limit family of the endpoint
+func (e Endpoint) Family() EndpointFamily {
+	return endpointFamilies[e]
+}
@@ -0,0 +1,190 @@
+package appstore
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/DotNetAge/appstore/models"
+)
+
+// DefaultIdempotencyWindow is how long the default idempotency store remembers a mass renewal extension
+const DefaultIdempotencyWindow = 24 * time.Hour
+
+// ErrDuplicateExtension is matched by the error returned when a mass renewal extension without a request identifier
+// is identical to one Apple already received within the idempotency window
+var ErrDuplicateExtension = errors.New("identical mass renewal extension already received by Apple")
+
+// DuplicateExtensionError is returned instead of sending a mass renewal extension without a request identifier that
+// is identical to one Apple already received. To send it again deliberately, give the request its own identifier.
+type DuplicateExtensionError struct {
+	// RequestIdentifier identifies the extension Apple already received
+	RequestIdentifier string
+}
+
+// Error implements error
+func (e *DuplicateExtensionError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrDuplicateExtension, e.RequestIdentifier)
+}
+
+// Is reports whether target is ErrDuplicateExtension
+func (e *DuplicateExtensionError) Is(target error) bool {
+	return target == ErrDuplicateExtension
+}
+
+// IdempotencyStore records the request identifiers of mass renewal extensions, so a request that may already
+// have reached Apple isn't sent again. Implementations backed by a shared database protect across processes.
+type IdempotencyStore interface {
+	// Reserve returns the request identifier recorded under key and true, or records requestIdentifier under key
+	// and returns it and false if there is none
+	Reserve(ctx context.Context, key, requestIdentifier string) (string, bool, error)
+}
+
+// WithIdempotencyStore sets the store that records mass renewal extension requests before they are sent.
+// By default each client uses an in-memory store with the DefaultIdempotencyWindow; a nil store disables the check.
+func WithIdempotencyStore(store IdempotencyStore) ClientOption {
+	return func(opts *clientOptions) {
+		opts.idempotencyStore = store
+		opts.idempotencyStoreSet = true
+	}
+}
+
+// MemoryIdempotencyStore is an in-memory IdempotencyStore that forgets requests after a time window
+type MemoryIdempotencyStore struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	records map[string]idempotencyRecord
+}
+
+// idempotencyRecord is a request identifier recorded by a MemoryIdempotencyStore
+type idempotencyRecord struct {
+	requestIdentifier string
+	expiresAt         time.Time
+}
+
+// NewMemoryIdempotencyStore creates a MemoryIdempotencyStore that remembers requests for window,
+// or for the DefaultIdempotencyWindow if window isn't positive
+func NewMemoryIdempotencyStore(window time.Duration) *MemoryIdempotencyStore {
+	if window <= 0 {
+		window = DefaultIdempotencyWindow
+	}
+	return &MemoryIdempotencyStore{
+		window:  window,
+		records: make(map[string]idempotencyRecord),
+	}
+}
+
+// Reserve implements IdempotencyStore
+func (s *MemoryIdempotencyStore) Reserve(ctx context.Context, key, requestIdentifier string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if record, ok := s.records[key]; ok && now.Before(record.expiresAt) {
+		return record.requestIdentifier, true, nil
+	}
+
+	// Drop expired records so the map doesn't grow without bound
+	for k, record := range s.records {
+		if !now.Before(record.expiresAt) {
+			delete(s.records, k)
+		}
+	}
+	s.records[key] = idempotencyRecord{requestIdentifier: requestIdentifier, expiresAt: now.Add(s.window)}
+	return requestIdentifier, false, nil
+}
+
+// prepareMassExtension gives the request a request identifier, generating one if needed, and records it in the
+// client's idempotency store, if it has one. It returns the request to send and whether Apple already received a
+// request with the same identifier, or a *DuplicateExtensionError if the request had no identifier and Apple received
+// an identical one.
+func (c *AppStoreServerAPIClient) prepareMassExtension(ctx context.Context, request *models.MassExtendRenewalDateRequest) (*models.MassExtendRenewalDateRequest, bool, error) {
+	if request == nil {
+		return nil, false, request.Validate()
+	}
+
+	key, err := c.massExtensionKey(request)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Work on a copy so the caller's request isn't modified
+	prepared := *request
+	generated := prepared.RequestIdentifier == nil || *prepared.RequestIdentifier == ""
+	if generated {
+		requestIdentifier, err := newRequestIdentifier()
+		if err != nil {
+			return nil, false, err
+		}
+		prepared.RequestIdentifier = &requestIdentifier
+	}
+	if err := prepared.Validate(); err != nil {
+		return nil, false, err
+	}
+	if c.idempotencyStore == nil {
+		return &prepared, false, nil
+	}
+
+	requestIdentifier, found, err := c.idempotencyStore.Reserve(ctx, key, *prepared.RequestIdentifier)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to record the mass renewal extension request: %w", err)
+	}
+	prepared.RequestIdentifier = &requestIdentifier
+	if !found {
+		return &prepared, false, nil
+	}
+
+	// The request was recorded before; ask Apple whether it arrived
+	_, err = c.GetStatusOfSubscriptionRenewalDateExtensions(ctx, requestIdentifier, *prepared.ProductId)
+	switch {
+	case err == nil && generated:
+		// Matched by content only, so the caller may mean a second extension; let them decide
+		return nil, false, &DuplicateExtensionError{RequestIdentifier: requestIdentifier}
+	case err == nil:
+		c.logger.LogAttrs(ctx, slog.LevelInfo, "mass renewal extension already received by Apple, not sending it again",
+			slog.String("request_identifier", requestIdentifier),
+			slog.String("product_id", *prepared.ProductId),
+		)
+		return &prepared, true, nil
+	case errors.Is(err, models.APIErrorStatusRequestNotFound):
+		return &prepared, false, nil
+	default:
+		return nil, false, fmt.Errorf("failed to check whether mass renewal extension %s was already sent: %w", requestIdentifier, err)
+	}
+}
+
+// massExtensionKey returns the idempotency key of a mass renewal extension: its request identifier if it has one,
+// otherwise a digest of its contents
+func (c *AppStoreServerAPIClient) massExtensionKey(request *models.MassExtendRenewalDateRequest) (string, error) {
+	prefix := "appstore:mass-extend:" + c.bundleID + ":" + string(c.environment) + ":"
+	if request.RequestIdentifier != nil && *request.RequestIdentifier != "" {
+		return prefix + "id:" + *request.RequestIdentifier, nil
+	}
+
+	content, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(content)
+	return prefix + "sha256:" + hex.EncodeToString(digest[:]), nil
+}
+
+// newRequestIdentifier returns a random (version 4) UUID
+func newRequestIdentifier() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate a request identifier: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
@@ -0,0 +1,228 @@
+package appstore_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/DotNetAge/appstore"
+	"github.com/DotNetAge/appstore/appstoretest"
+	"github.com/DotNetAge/appstore/models"
+)
+
+// requestLog is an http.RoundTripper that records the method and path of each request it sends
+type requestLog struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+// RoundTrip implements http.RoundTripper
+func (l *requestLog) RoundTrip(req *http.Request) (*http.Response, error) {
+	l.mu.Lock()
+	l.requests = append(l.requests, req.Method+" "+req.URL.Path)
+	l.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// take returns the requests sent since the last call
+func (l *requestLog) take() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	requests := l.requests
+	l.requests = nil
+	return requests
+}
+
+// failingStore is an IdempotencyStore that can't record anything
+type failingStore struct{}
+
+// Reserve implements appstore.IdempotencyStore
+func (failingStore) Reserve(context.Context, string, string) (string, bool, error) {
+	return "", false, errors.New("store unavailable")
+}
+
+// uuidV4Pattern matches the random UUIDs the client generates
+var uuidV4Pattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+const (
+	massExtend        = "POST /inApps/v1/subscriptions/extend/mass"
+	massExtendProduct = "com.example.monthly"
+)
+
+// newMassExtensionClient returns a fake App Store, a client for it without retries and the log of the client's requests
+func newMassExtensionClient(t *testing.T, options ...appstore.ClientOption) (*appstoretest.Server, *appstore.AppStoreServerAPIClient, *requestLog) {
+	t.Helper()
+	server := appstoretest.NewServer()
+	t.Cleanup(server.Close)
+	log := &requestLog{}
+	options = append([]appstore.ClientOption{
+		appstore.WithHTTPClient(&http.Client{Transport: log}),
+		appstore.WithRetryPolicy(nil),
+	}, options...)
+	client, err := server.NewClient(options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, client, log
+}
+
+// massExtension returns a valid mass renewal extension, with requestIdentifier unless it's empty
+func massExtension(requestIdentifier string) *models.MassExtendRenewalDateRequest {
+	days, reason, productID := 3, models.ExtendReasonCodeCustomerSatisfaction, massExtendProduct
+	request := &models.MassExtendRenewalDateRequest{ExtendByDays: &days, ExtendReasonCode: &reason, ProductId: &productID}
+	if requestIdentifier != "" {
+		request.RequestIdentifier = &requestIdentifier
+	}
+	return request
+}
+
+// statusLookup is the request that asks whether the extension requestIdentifier arrived
+func statusLookup(requestIdentifier string) string {
+	return "GET /inApps/v1/subscriptions/extend/mass/" + massExtendProduct + "/" + requestIdentifier
+}
+
+func TestMassExtensionGeneratesRequestIdentifier(t *testing.T) {
+	_, client, log := newMassExtensionClient(t)
+	request := massExtension("")
+
+	response, err := client.ExtendRenewalDateForAllActiveSubscribers(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !uuidV4Pattern.MatchString(*response.RequestIdentifier) {
+		t.Errorf("generated request identifier %q isn't a UUID", *response.RequestIdentifier)
+	}
+	if request.RequestIdentifier != nil {
+		t.Error("the caller's request was modified")
+	}
+	if got := log.take(); !reflect.DeepEqual(got, []string{massExtend}) {
+		t.Errorf("sent %v", got)
+	}
+
+	// A different extension isn't a duplicate
+	days := 5
+	other := massExtension("")
+	other.ExtendByDays = &days
+	second, err := client.ExtendRenewalDateForAllActiveSubscribers(context.Background(), other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *second.RequestIdentifier == *response.RequestIdentifier {
+		t.Error("two extensions got the same request identifier")
+	}
+}
+
+func TestMassExtensionReceivedWithSameIdentifier(t *testing.T) {
+	_, client, log := newMassExtensionClient(t)
+	const requestIdentifier = "9b2b6f0e-4a57-4e53-8f0c-52f9f3a1c0d4"
+
+	for range 2 {
+		response, err := client.ExtendRenewalDateForAllActiveSubscribers(context.Background(), massExtension(requestIdentifier))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *response.RequestIdentifier != requestIdentifier {
+			t.Errorf("request identifier = %s, want %s", *response.RequestIdentifier, requestIdentifier)
+		}
+	}
+	if got, want := log.take(), []string{massExtend, statusLookup(requestIdentifier)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sent %v, want %v", got, want)
+	}
+}
+
+func TestMassExtensionDuplicateContent(t *testing.T) {
+	_, client, log := newMassExtensionClient(t)
+
+	first, err := client.ExtendRenewalDateForAllActiveSubscribers(context.Background(), massExtension(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.ExtendRenewalDateForAllActiveSubscribers(context.Background(), massExtension(""))
+	if !errors.Is(err, appstore.ErrDuplicateExtension) {
+		t.Fatalf("got %v, want ErrDuplicateExtension", err)
+	}
+	var duplicate *appstore.DuplicateExtensionError
+	if !errors.As(err, &duplicate) || duplicate.RequestIdentifier != *first.RequestIdentifier {
+		t.Errorf("got %v, want the identifier of the first extension %s", err, *first.RequestIdentifier)
+	}
+	if got, want := log.take(), []string{massExtend, statusLookup(*first.RequestIdentifier)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sent %v, want %v", got, want)
+	}
+}
+
+func TestMassExtensionResendsLostRequest(t *testing.T) {
+	for _, requestIdentifier := range []string{"", "3c0d0f8e-2a41-4d7e-9a55-61a0b4e2c7f9"} {
+		name := "with identifier"
+		if requestIdentifier == "" {
+			name = "generated identifier"
+		}
+		t.Run(name, func(t *testing.T) {
+			server, client, log := newMassExtensionClient(t)
+
+			// The first attempt never reaches the extension logic, so Apple has no record of it
+			server.FailNext(models.APIErrorGeneralInternal)
+			if _, err := client.ExtendRenewalDateForAllActiveSubscribers(context.Background(), massExtension(requestIdentifier)); !errors.Is(err, models.APIErrorGeneralInternal) {
+				t.Fatalf("got %v, want the injected error", err)
+			}
+			log.take()
+
+			response, err := client.ExtendRenewalDateForAllActiveSubscribers(context.Background(), massExtension(requestIdentifier))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if requestIdentifier != "" && *response.RequestIdentifier != requestIdentifier {
+				t.Errorf("request identifier = %s, want %s", *response.RequestIdentifier, requestIdentifier)
+			}
+			if got, want := log.take(), []string{statusLookup(*response.RequestIdentifier), massExtend}; !reflect.DeepEqual(got, want) {
+				t.Errorf("sent %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMassExtensionErrors(t *testing.T) {
+	t.Run("status lookup fails", func(t *testing.T) {
+		server, client, log := newMassExtensionClient(t)
+		const requestIdentifier = "5e8a1b2c-3d4f-4a6b-8c9d-0e1f2a3b4c5d"
+		if _, err := client.ExtendRenewalDateForAllActiveSubscribers(context.Background(), massExtension(requestIdentifier)); err != nil {
+			t.Fatal(err)
+		}
+		log.take()
+
+		server.FailNext(models.APIErrorGeneralInternal)
+		_, err := client.ExtendRenewalDateForAllActiveSubscribers(context.Background(), massExtension(requestIdentifier))
+		if !errors.Is(err, models.APIErrorGeneralInternal) {
+			t.Fatalf("got %v, want the status lookup error", err)
+		}
+		if got, want := log.take(), []string{statusLookup(requestIdentifier)}; !reflect.DeepEqual(got, want) {
+			t.Errorf("sent %v, want %v", got, want)
+		}
+	})
+
+	t.Run("store fails", func(t *testing.T) {
+		_, client, log := newMassExtensionClient(t, appstore.WithIdempotencyStore(failingStore{}))
+		if _, err := client.ExtendRenewalDateForAllActiveSubscribers(context.Background(), massExtension("")); err == nil {
+			t.Fatal("expected an error")
+		}
+		if got := log.take(); len(got) != 0 {
+			t.Errorf("sent %v without recording the request", got)
+		}
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		_, client, log := newMassExtensionClient(t)
+		request := massExtension("")
+		request.ProductId = nil
+		var validationErr *models.ValidationError
+		if _, err := client.ExtendRenewalDateForAllActiveSubscribers(context.Background(), request); !errors.As(err, &validationErr) {
+			t.Fatalf("got %v, want a validation error", err)
+		}
+		if got := log.take(); len(got) != 0 {
+			t.Errorf("sent %v", got)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package appstore
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Call describes a single App Store Server API call as seen by interceptors.
+// Interceptors may change Query, Body and Header before calling the next invoker;
+// StatusCode, Attempts, Duration and Response are filled in once it returns.
+type Call struct {
+	// Endpoint is the endpoint being called
+	Endpoint Endpoint
+	// Method is the HTTP method of the request
+	Method string
+	// Path is the URL path of the request
+	Path string
+	// Query contains the query parameters of the request
+	Query url.Values
+	// Body is the encoded request body, or nil if the request has none. It is JSON unless Header sets a Content-Type.
+	Body []byte
+	// Header contains additional headers to send with the request
+	Header http.Header
+	// StatusCode is the HTTP status code of the last response, or 0 if no response was received
+	StatusCode int
+	// Attempts is the number of requests sent, including retries
+	Attempts int
+	// Duration is the time spent on the call, including retries and rate limit waits
+	Duration time.Duration
+	// Response is the parsed response, or nil if the endpoint returns no content
+	Response interface{}
+}
+
+// Invoker sends a call to the App Store Server API and returns its error
+type Invoker func(ctx context.Context, call *Call) error
+
+// Interceptor observes or alters every call an AppStoreServerAPIClient makes.
+// Implementations must call next to continue the chain and return its error, or return an error to abort the call.
+type Interceptor interface {
+	Intercept(ctx context.Context, call *Call, next Invoker) error
+}
+
+// InterceptorFunc is an adapter to use an ordinary function as an Interceptor
+type InterceptorFunc func(ctx context.Context, call *Call, next Invoker) error
+
+// Intercept calls f(ctx, call, next)
+func (f InterceptorFunc) Intercept(ctx context.Context, call *Call, next Invoker) error {
+	return f(ctx, call, next)
+}
+
+// WithInterceptors appends interceptors to the client. The first interceptor registered is the outermost one.
+func WithInterceptors(interceptors ...Interceptor) ClientOption {
+	return func(opts *clientOptions) {
+		opts.interceptors = append(opts.interceptors, interceptors...)
+	}
+}
+
+// chainInterceptors wraps invoker with the interceptors, the first one being the outermost
+func chainInterceptors(interceptors []Interceptor, invoker Invoker) Invoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+		invoker = func(ctx context.Context, call *Call) error {
+			return interceptor.Intercept(ctx, call, next)
+		}
+	}
+	return invoker
+}
@@ -0,0 +1,286 @@
+package appstore_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/DotNetAge/appstore"
+	"github.com/DotNetAge/appstore/models"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// receivedRequest is a request as the test server saw it
+type receivedRequest struct {
+	method string
+	path   string
+	query  string
+	header http.Header
+	body   []byte
+}
+
+// eventLog is an ordered, concurrency-safe list of events
+type eventLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+// add appends an event
+func (l *eventLog) add(event string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+}
+
+// list returns the events so far
+func (l *eventLog) list() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.events...)
+}
+
+// eventHandler is a slog.Handler that adds an event for every record
+type eventHandler struct {
+	events *eventLog
+}
+
+// Enabled implements slog.Handler
+func (h eventHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle implements slog.Handler
+func (h eventHandler) Handle(_ context.Context, record slog.Record) error {
+	h.events.add("log: " + record.Message)
+	return nil
+}
+
+// WithAttrs implements slog.Handler
+func (h eventHandler) WithAttrs([]slog.Attr) slog.Handler {
+	return h
+}
+
+// WithGroup implements slog.Handler
+func (h eventHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// newInterceptorTestServer starts a server that records each request as an event and answers 200 with an empty object
+func newInterceptorTestServer(t *testing.T, events *eventLog) (*httptest.Server, func() []receivedRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []receivedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, receivedRequest{method: r.Method, path: r.URL.Path, query: r.URL.RawQuery, header: r.Header.Clone(), body: body})
+		mu.Unlock()
+		events.add("request")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"signedTransactionInfo":"signed"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []receivedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]receivedRequest(nil), requests...)
+	}
+}
+
+// newInterceptorTestClient creates a client for the server with the given options, without retries or rate limiting
+func newInterceptorTestClient(t *testing.T, server *httptest.Server, options ...appstore.ClientOption) *appstore.AppStoreServerAPIClient {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options = append([]appstore.ClientOption{
+		appstore.WithBaseURL(server.URL),
+		appstore.WithSigner(key),
+		appstore.WithRateLimiter(nil),
+		appstore.WithRetryPolicy(nil),
+	}, options...)
+	client, err := appstore.NewAppStoreServerAPIClientWithOptions(nil, "KEY_ID", "ISSUER_ID", "com.example", models.EnvironmentSandbox, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// recordingInterceptor adds an event before and after calling the next invoker
+func recordingInterceptor(name string, events *eventLog) appstore.Interceptor {
+	return appstore.InterceptorFunc(func(ctx context.Context, call *appstore.Call, next appstore.Invoker) error {
+		events.add(name + " before")
+		err := next(ctx, call)
+		events.add(name + " after")
+		return err
+	})
+}
+
+func TestInterceptorOrder(t *testing.T) {
+	events := &eventLog{}
+	server, requests := newInterceptorTestServer(t, events)
+	provider, recorder := newRecordingProvider()
+	metrics, registry := newTestMetrics(t)
+
+	// Tracing is outermost: the span is already in the context of the first user interceptor
+	inSpan := appstore.InterceptorFunc(func(ctx context.Context, call *appstore.Call, next appstore.Invoker) error {
+		if trace.SpanFromContext(ctx).SpanContext().IsValid() {
+			events.add("span started")
+		}
+		return next(ctx, call)
+	})
+	// Metrics are innermost: nothing is counted before the last user interceptor calls next, the call is counted after
+	counted := appstore.InterceptorFunc(func(ctx context.Context, call *appstore.Call, next appstore.Invoker) error {
+		before := testutil.CollectAndCount(registry, "appstore_api_requests_total")
+		err := next(ctx, call)
+		if after := testutil.CollectAndCount(registry, "appstore_api_requests_total"); before == 0 && after == 1 {
+			events.add("metrics recorded")
+		}
+		return err
+	})
+	client := newInterceptorTestClient(t, server,
+		appstore.WithTracerProvider(provider),
+		appstore.WithLogger(slog.New(eventHandler{events: events})),
+		appstore.WithMetrics(metrics),
+		appstore.WithInterceptors(inSpan, recordingInterceptor("outer", events)),
+		appstore.WithInterceptors(recordingInterceptor("inner", events), counted))
+
+	if _, err := client.GetTransactionInfo(context.Background(), "1000"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"span started",
+		"outer before",
+		"inner before",
+		"request",
+		"metrics recorded",
+		"inner after",
+		"outer after",
+		"log: App Store Server API request succeeded",
+	}
+	if got := events.list(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+	if len(requests()) != 1 {
+		t.Errorf("sent %d requests, want 1", len(requests()))
+	}
+	if spans := recorder.Ended(); len(spans) != 1 {
+		t.Errorf("ended %d spans, want 1", len(spans))
+	}
+}
+
+func TestInterceptorShortCircuit(t *testing.T) {
+	events := &eventLog{}
+	server, requests := newInterceptorTestServer(t, events)
+	metrics, registry := newTestMetrics(t)
+
+	errDenied := errors.New("denied by policy")
+	deny := appstore.InterceptorFunc(func(ctx context.Context, call *appstore.Call, next appstore.Invoker) error {
+		events.add("deny")
+		return errDenied
+	})
+	client := newInterceptorTestClient(t, server,
+		appstore.WithLogger(slog.New(eventHandler{events: events})),
+		appstore.WithMetrics(metrics),
+		appstore.WithInterceptors(recordingInterceptor("outer", events), deny, recordingInterceptor("unreached", events)))
+
+	_, err := client.GetTransactionInfo(context.Background(), "1000")
+	if !errors.Is(err, errDenied) {
+		t.Fatalf("error = %v, want the interceptor's error", err)
+	}
+
+	// The logging interceptor wraps user interceptors, so it still reports the failure; metrics sit inside them
+	want := []string{"outer before", "deny", "outer after", "log: App Store Server API request failed"}
+	if got := events.list(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+	if len(requests()) != 0 {
+		t.Errorf("sent %d requests, want 0", len(requests()))
+	}
+	if count := testutil.CollectAndCount(registry, "appstore_api_requests_total"); count != 0 {
+		t.Errorf("metrics counted %d calls, want 0", count)
+	}
+}
+
+func TestInterceptorChangesRequest(t *testing.T) {
+	events := &eventLog{}
+	server, requests := newInterceptorTestServer(t, events)
+
+	var seen []*appstore.Call
+	rewrite := appstore.InterceptorFunc(func(ctx context.Context, call *appstore.Call, next appstore.Invoker) error {
+		call.Header.Set("X-Request-Id", "request-1")
+		if call.Query != nil {
+			call.Query.Set("revision", "rewritten")
+		}
+		if call.Body != nil {
+			call.Body = bytes.ReplaceAll(call.Body, []byte(`"extendByDays":30`), []byte(`"extendByDays":7`))
+		}
+		err := next(ctx, call)
+		seen = append(seen, call)
+		return err
+	})
+	client := newInterceptorTestClient(t, server, appstore.WithInterceptors(rewrite))
+
+	ctx := context.Background()
+	if _, err := client.GetRefundHistory(ctx, "1000", "original"); err != nil {
+		t.Fatal(err)
+	}
+	reasonCode := models.ExtendReasonCodeCustomerSatisfaction
+	requestIdentifier := "11111111-1111-4111-8111-111111111111"
+	if _, err := client.ExtendSubscriptionRenewalDate(ctx, "1000", &models.ExtendRenewalDateRequest{
+		ExtendByDays:      ptr(30),
+		ExtendReasonCode:  &reasonCode,
+		RequestIdentifier: &requestIdentifier,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetTransactionInfo(ctx, "1000"); err != nil {
+		t.Fatal(err)
+	}
+
+	received := requests()
+	if len(received) != 3 {
+		t.Fatalf("sent %d requests, want 3", len(received))
+	}
+	for _, request := range received {
+		if got := request.header.Get("X-Request-Id"); got != "request-1" {
+			t.Errorf("%s %s: X-Request-Id = %q", request.method, request.path, got)
+		}
+		// Interceptor headers can't replace the bearer token
+		if request.header.Get("Authorization") == "" {
+			t.Errorf("%s %s: no Authorization header", request.method, request.path)
+		}
+	}
+	if got := received[0].query; got != "revision=rewritten" {
+		t.Errorf("GetRefundHistory query = %s", got)
+	}
+	if !bytes.Contains(received[1].body, []byte(`"extendByDays":7`)) {
+		t.Errorf("ExtendSubscriptionRenewalDate body = %s", received[1].body)
+	}
+	if got := received[1].header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("ExtendSubscriptionRenewalDate Content-Type = %s", got)
+	}
+
+	// Once next returns, the call carries the outcome
+	last := seen[2]
+	if last.Endpoint != appstore.EndpointGetTransactionInfo || last.StatusCode != http.StatusOK || last.Attempts != 1 || last.Duration <= 0 {
+		t.Errorf("call = %+v", last)
+	}
+	response, ok := last.Response.(*models.TransactionInfoResponse)
+	if !ok || response.SignedTransactionInfo == nil || *response.SignedTransactionInfo != "signed" {
+		t.Errorf("response = %#v", last.Response)
+	}
+}
@@ -0,0 +1,24 @@
+// Package apple holds the identifier formats and certificate markers defined by Apple. The client, its request
+// validation and the appstoretest fake share them so they can't drift apart.
+package apple
+
+import (
+	"encoding/asn1"
+	"regexp"
+)
+
+var (
+	// TransactionIDPattern matches transaction and original transaction identifiers
+	TransactionIDPattern = regexp.MustCompile(`^[0-9]{1,32}$`)
+	// StorefrontCountryCodePattern matches ISO 3166-1 alpha-3 storefront country codes
+	StorefrontCountryCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+	// UUIDPattern matches UUIDs, in either case, such as app account tokens and message identifiers
+	UUIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+var (
+	// LeafCertificateOID marks the certificates Apple signs App Store data with
+	LeafCertificateOID = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 11, 1}
+	// IntermediateCertificateOID marks Apple's WWDR intermediate certificate
+	IntermediateCertificateOID = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 2, 1}
+)
@@ -0,0 +1,283 @@
+package appstore
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/DotNetAge/appstore/models"
+)
+
+// SigningKey is an App Store Connect API key the client can sign bearer tokens with
+type SigningKey struct {
+	// KeyID is the identifier of the key in App Store Connect
+	KeyID string
+	// Signer signs with the private key; it must hold a P-256 ECDSA key
+	Signer crypto.Signer
+}
+
+// KeySource provides the signing keys of a client, in order of preference
+type KeySource interface {
+	SigningKeys(ctx context.Context) ([]SigningKey, error)
+}
+
+// KeySourceFunc is an adapter to use an ordinary function as a KeySource
+type KeySourceFunc func(ctx context.Context) ([]SigningKey, error)
+
+// SigningKeys calls f(ctx)
+func (f KeySourceFunc) SigningKeys(ctx context.Context) ([]SigningKey, error) {
+	return f(ctx)
+}
+
+// StaticKeySource returns a KeySource that always provides the given keys
+func StaticKeySource(keys ...SigningKey) KeySource {
+	return KeySourceFunc(func(ctx context.Context) ([]SigningKey, error) {
+		return keys, nil
+	})
+}
+
+// DirectoryKeySource returns a KeySource that loads every AuthKey_<KeyID>.p8 file in dir, as downloaded from App Store Connect.
+// The most recently modified key is preferred.
+func DirectoryKeySource(dir string) KeySource {
+	return KeySourceFunc(func(ctx context.Context) ([]SigningKey, error) {
+		paths, err := filepath.Glob(filepath.Join(dir, "AuthKey_*.p8"))
+		if err != nil {
+			return nil, err
+		}
+
+		type keyFile struct {
+			key     SigningKey
+			modTime int64
+		}
+		var files []keyFile
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to stat signing key file %s: %w", path, err)
+			}
+			key, err := LoadSigningKeyFromFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load signing key file %s: %w", path, err)
+			}
+			keyID := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "AuthKey_"), ".p8")
+			files = append(files, keyFile{key: SigningKey{KeyID: keyID, Signer: key}, modTime: info.ModTime().UnixNano()})
+		}
+
+		sort.SliceStable(files, func(i, j int) bool {
+			return files[i].modTime > files[j].modTime
+		})
+		keys := make([]SigningKey, len(files))
+		for i, file := range files {
+			keys[i] = file.key
+		}
+		return keys, nil
+	})
+}
+
+// KeyFailover describes a switch to the next signing key after Apple rejected the previous one
+type KeyFailover struct {
+	// FromKeyID is the identifier of the rejected key
+	FromKeyID string
+	// ToKeyID is the identifier of the key now in use
+	ToKeyID string
+	// Reason is the error returned for the request signed with the rejected key
+	Reason error
+}
+
+// WithKeySource loads the client's signing keys from source, replacing the signingKey and keyID arguments.
+// When Apple rejects a request with 401 Unauthorized, the client switches to the next key; once all keys
+// have been rejected it reloads the source. Keys can also be reloaded at any time with ReloadKeys.
+func WithKeySource(source KeySource) ClientOption {
+	return func(opts *clientOptions) {
+		opts.keySource = source
+	}
+}
+
+// WithKeyFailoverHandler sets a function called every time the client switches to another signing key
+func WithKeyFailoverHandler(handler func(ctx context.Context, failover KeyFailover)) ClientOption {
+	return func(opts *clientOptions) {
+		opts.keyFailoverHandler = handler
+	}
+}
+
+// ReloadKeys reloads the signing keys from the key source and makes the first one active
+func (c *AppStoreServerAPIClient) ReloadKeys(ctx context.Context) error {
+	if c.keys.source == nil {
+		return errors.New("the client has no key source; configure one with WithKeySource")
+	}
+	_, err := c.keys.reload(ctx)
+	return err
+}
+
+// ActiveKeyID returns the identifier of the signing key currently used to sign requests
+func (c *AppStoreServerAPIClient) ActiveKeyID() string {
+	c.keys.mu.RLock()
+	defer c.keys.mu.RUnlock()
+	return c.keys.keys[c.keys.active].KeyID
+}
+
+// failoverKey switches to the next signing key if err shows that the key of the given generation was rejected.
+// It reports whether the request should be sent again.
+func (c *AppStoreServerAPIClient) failoverKey(ctx context.Context, generation uint64, err error) bool {
+	var apiErr *models.APIException
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != http.StatusUnauthorized {
+		return false
+	}
+
+	failover, retry := c.keys.failover(ctx, generation)
+	if failover != nil {
+		failover.Reason = err
+		c.logger.LogAttrs(ctx, slog.LevelWarn, "App Store Server API rejected the signing key, switching keys",
+			slog.String("from_key_id", failover.FromKeyID),
+			slog.String("to_key_id", failover.ToKeyID),
+		)
+		if c.keyFailoverHandler != nil {
+			c.keyFailoverHandler(ctx, *failover)
+		}
+	}
+	return retry
+}
+
+// keyRing holds the ordered signing keys of a client, the active one and its token cache
+type keyRing struct {
+	source KeySource
+
+	mu         sync.RWMutex
+	keys       []SigningKey
+	active     int
+	generation uint64
+	tokens     *tokenCache
+
+	// pendingKey is parsed into keys[0] on first use when the client was given raw key bytes
+	pendingOnce sync.Once
+	pendingKey  []byte
+	pendingErr  error
+}
+
+// newKeyRing creates a key ring holding a single key, given either as a signer or as raw key bytes
+func newKeyRing(keyID string, signingKey []byte, signer crypto.Signer) *keyRing {
+	ring := &keyRing{
+		keys:   []SigningKey{{KeyID: keyID, Signer: signer}},
+		tokens: &tokenCache{},
+	}
+	switch {
+	case signer == nil && len(signingKey) == 0:
+		ring.pendingErr = fmt.Errorf("%w: no signing key or signer given", ErrInvalidSigningKey)
+	case signer == nil:
+		ring.pendingKey = signingKey
+	default:
+		ring.pendingErr = checkSigner(signer)
+	}
+	return ring
+}
+
+// newKeyRingFromSource creates a key ring holding the keys provided by source
+func newKeyRingFromSource(ctx context.Context, source KeySource) (*keyRing, error) {
+	ring := &keyRing{source: source}
+	if _, err := ring.reload(ctx); err != nil {
+		return nil, err
+	}
+	return ring, nil
+}
+
+// current returns the active key, the generation of the ring and the token cache of the key
+func (r *keyRing) current() (SigningKey, uint64, *tokenCache, error) {
+	if r.pendingKey != nil {
+		r.pendingOnce.Do(func() {
+			signer, err := LoadSigningKey(r.pendingKey)
+			r.mu.Lock()
+			r.keys[0].Signer, r.pendingErr = signer, err
+			r.mu.Unlock()
+		})
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.pendingErr != nil {
+		return SigningKey{}, 0, nil, r.pendingErr
+	}
+	return r.keys[r.active], r.generation, r.tokens, nil
+}
+
+// size returns the number of keys in the ring
+func (r *keyRing) size() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.keys)
+}
+
+// failover moves to the next key if the key of the given generation is still active, reloading the source
+// once every key has been rejected. It returns the switch it made, if any, and whether to send the request again.
+func (r *keyRing) failover(ctx context.Context, generation uint64) (*KeyFailover, bool) {
+	r.mu.Lock()
+	if generation != r.generation {
+		// Another request already switched keys
+		r.mu.Unlock()
+		return nil, true
+	}
+
+	from := r.keys[r.active].KeyID
+	if r.active+1 < len(r.keys) {
+		r.active++
+		r.generation++
+		r.tokens = &tokenCache{}
+		to := r.keys[r.active].KeyID
+		r.mu.Unlock()
+		return &KeyFailover{FromKeyID: from, ToKeyID: to}, true
+	}
+	r.mu.Unlock()
+
+	if r.source == nil {
+		return nil, false
+	}
+	changed, err := r.reload(ctx)
+	if err != nil || !changed {
+		return nil, false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return &KeyFailover{FromKeyID: from, ToKeyID: r.keys[r.active].KeyID}, true
+}
+
+// reload replaces the keys with those of the source and reports whether the set of key identifiers changed
+func (r *keyRing) reload(ctx context.Context) (bool, error) {
+	keys, err := r.source.SigningKeys(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to load signing keys: %w", err)
+	}
+	if len(keys) == 0 {
+		return false, errors.New("the key source provided no signing keys")
+	}
+	for _, key := range keys {
+		if key.Signer == nil {
+			return false, fmt.Errorf("signing key %s has no signer", key.KeyID)
+		}
+		if err := checkSigner(key.Signer); err != nil {
+			return false, fmt.Errorf("signing key %s: %w", key.KeyID, err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed := len(keys) != len(r.keys)
+	for i := 0; !changed && i < len(keys); i++ {
+		changed = keys[i].KeyID != r.keys[i].KeyID
+	}
+
+	r.keys = append([]SigningKey(nil), keys...)
+	r.active = 0
+	r.generation++
+	r.tokens = &tokenCache{}
+	return changed, nil
+}
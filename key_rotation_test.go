@@ -0,0 +1,181 @@
+package appstore_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/DotNetAge/appstore"
+	"github.com/DotNetAge/appstore/appstoretest"
+	"github.com/DotNetAge/appstore/models"
+)
+
+// newSigningKeys returns a freshly generated signing key for each key ID
+func newSigningKeys(t *testing.T, keyIDs ...string) []appstore.SigningKey {
+	t.Helper()
+	keys := make([]appstore.SigningKey, len(keyIDs))
+	for i, keyID := range keyIDs {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = appstore.SigningKey{KeyID: keyID, Signer: key}
+	}
+	return keys
+}
+
+// failoverLog records the key switches of a client
+type failoverLog struct {
+	mu        sync.Mutex
+	failovers []string
+}
+
+// handle is a key failover handler
+func (l *failoverLog) handle(ctx context.Context, failover appstore.KeyFailover) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.failovers = append(l.failovers, failover.FromKeyID+"->"+failover.ToKeyID)
+}
+
+// newKeyRotationClient returns a fake App Store and a client for it that loads its keys from source, along with
+// the logs of the client's requests and key switches
+func newKeyRotationClient(t *testing.T, source appstore.KeySource) (*appstoretest.Server, *appstore.AppStoreServerAPIClient, *requestLog, *failoverLog) {
+	t.Helper()
+	server := appstoretest.NewServer()
+	t.Cleanup(server.Close)
+	requests, failovers := &requestLog{}, &failoverLog{}
+	client, err := server.NewClient(
+		appstore.WithKeySource(source),
+		appstore.WithKeyFailoverHandler(failovers.handle),
+		appstore.WithHTTPClient(&http.Client{Transport: requests}),
+		appstore.WithRetryPolicy(nil),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, client, requests, failovers
+}
+
+// lookUpTransaction sends a request for a transaction of server
+func lookUpTransaction(server *appstoretest.Server, client *appstore.AppStoreServerAPIClient) error {
+	transaction := server.AddTransaction("customer", models.JWSTransactionDecodedPayload{})
+	_, err := client.GetTransactionInfo(context.Background(), *transaction.TransactionId)
+	return err
+}
+
+func TestKeyFailoverOrder(t *testing.T) {
+	server, client, requests, failovers := newKeyRotationClient(t, appstore.StaticKeySource(newSigningKeys(t, "A", "B", "C")...))
+	server.RejectKey("A")
+	server.RejectKey("B")
+
+	if err := lookUpTransaction(server, client); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"A->B", "B->C"}; !reflect.DeepEqual(failovers.failovers, want) {
+		t.Errorf("switched keys %v, want %v", failovers.failovers, want)
+	}
+	if got := len(requests.take()); got != 3 {
+		t.Errorf("sent %d requests, want one per key", got)
+	}
+	if got := client.ActiveKeyID(); got != "C" {
+		t.Errorf("active key = %s, want C", got)
+	}
+
+	// Later requests start with the key that worked
+	if err := lookUpTransaction(server, client); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(requests.take()); got != 1 {
+		t.Errorf("sent %d requests, want 1", got)
+	}
+}
+
+func TestKeyFailoverAllKeysRejected(t *testing.T) {
+	server, client, requests, failovers := newKeyRotationClient(t, appstore.StaticKeySource(newSigningKeys(t, "A", "B")...))
+	server.RejectKey("A")
+	server.RejectKey("B")
+
+	err := lookUpTransaction(server, client)
+	var apiErr *models.APIException
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != http.StatusUnauthorized {
+		t.Fatalf("got %v, want 401 Unauthorized", err)
+	}
+	if want := []string{"A->B"}; !reflect.DeepEqual(failovers.failovers, want) {
+		t.Errorf("switched keys %v, want %v", failovers.failovers, want)
+	}
+	if got := len(requests.take()); got != 2 {
+		t.Errorf("sent %d requests, want one per key", got)
+	}
+}
+
+func TestKeyFailoverReloadsSource(t *testing.T) {
+	keys := newSigningKeys(t, "A", "B")
+	var loads int
+	var mu sync.Mutex
+	source := appstore.KeySourceFunc(func(ctx context.Context) ([]appstore.SigningKey, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		loads++
+		if loads == 1 {
+			return keys[:1], nil
+		}
+		return keys[1:], nil
+	})
+	server, client, _, failovers := newKeyRotationClient(t, source)
+	server.RejectKey("A")
+
+	if err := lookUpTransaction(server, client); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"A->B"}; !reflect.DeepEqual(failovers.failovers, want) {
+		t.Errorf("switched keys %v, want %v", failovers.failovers, want)
+	}
+	if got := client.ActiveKeyID(); got != "B" {
+		t.Errorf("active key = %s, want B", got)
+	}
+}
+
+func TestKeyFailoverIsBounded(t *testing.T) {
+	// Every load provides a new key, and Apple rejects them all
+	var loads int
+	var mu sync.Mutex
+	source := appstore.KeySourceFunc(func(ctx context.Context) ([]appstore.SigningKey, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		loads++
+		return newSigningKeys(t, fmt.Sprintf("K%d", loads)), nil
+	})
+	server, client, requests, _ := newKeyRotationClient(t, source)
+	for i := 1; i <= 10; i++ {
+		server.RejectKey(fmt.Sprintf("K%d", i))
+	}
+
+	if err := lookUpTransaction(server, client); err == nil {
+		t.Fatal("expected an error")
+	}
+	if got := len(requests.take()); got != 2 {
+		t.Errorf("sent %d requests, want 2: the first key and one reload of a ring holding a single key", got)
+	}
+}
+
+func TestKeyFailoverOnlyOnUnauthorized(t *testing.T) {
+	server, client, requests, failovers := newKeyRotationClient(t, appstore.StaticKeySource(newSigningKeys(t, "A", "B")...))
+	server.FailNext(models.APIErrorGeneralInternal)
+
+	if err := lookUpTransaction(server, client); !errors.Is(err, models.APIErrorGeneralInternal) {
+		t.Fatalf("got %v, want GeneralInternalError", err)
+	}
+	if len(failovers.failovers) != 0 || client.ActiveKeyID() != "A" {
+		t.Errorf("switched keys %v on a server error", failovers.failovers)
+	}
+	if got := len(requests.take()); got != 1 {
+		t.Errorf("sent %d requests, want 1", got)
+	}
+}
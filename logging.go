@@ -0,0 +1,72 @@
+package appstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/DotNetAge/appstore/models"
+)
+
+// WithLogger sets the structured logger of the client, and of the verifier of an AppStoreServerClient.
+// Bearer tokens, signing keys and signed payloads are never logged; transaction identifiers in request paths are.
+func WithLogger(logger *slog.Logger) ClientOption {
+	return func(opts *clientOptions) {
+		opts.logger = logger
+	}
+}
+
+// WithVerifierLogger sets the structured logger used to report verification failures
+func WithVerifierLogger(logger *slog.Logger) VerifierOption {
+	return func(v *SignedDataVerifier) {
+		v.logger = logger
+	}
+}
+
+// loggerOrDiscard returns the logger, or one that discards everything if it is nil
+func loggerOrDiscard(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return logger
+}
+
+// redactedJWS is a signed payload that only logs its size, never its content
+type redactedJWS string
+
+// LogValue implements slog.LogValuer
+func (s redactedJWS) LogValue() slog.Value {
+	return slog.StringValue(fmt.Sprintf("[REDACTED JWS, %d bytes]", len(s)))
+}
+
+// loggingInterceptor logs the outcome of every App Store Server API call
+type loggingInterceptor struct {
+	logger *slog.Logger
+}
+
+// Intercept implements Interceptor
+func (l *loggingInterceptor) Intercept(ctx context.Context, call *Call, next Invoker) error {
+	err := next(ctx, call)
+
+	attrs := []slog.Attr{
+		slog.String("endpoint", string(call.Endpoint)),
+		slog.String("method", call.Method),
+		slog.String("path", call.Path),
+		slog.Int("status", call.StatusCode),
+		slog.Int("attempts", call.Attempts),
+		slog.Duration("duration", call.Duration),
+	}
+	if err == nil {
+		l.logger.LogAttrs(ctx, slog.LevelDebug, "App Store Server API request succeeded", attrs...)
+		return nil
+	}
+
+	var apiErr *models.APIException
+	if errors.As(err, &apiErr) && apiErr.RawAPIError != nil {
+		attrs = append(attrs, slog.Int("api_error", int(apiErr.APIError)))
+	}
+	attrs = append(attrs, slog.Any("error", err))
+	l.logger.LogAttrs(ctx, slog.LevelWarn, "App Store Server API request failed", attrs...)
+	return err
+}
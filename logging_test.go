@@ -0,0 +1,269 @@
+package appstore_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/DotNetAge/appstore"
+	"github.com/DotNetAge/appstore/appstoretest"
+	"github.com/DotNetAge/appstore/models"
+)
+
+// recordingHandler is a slog.Handler that keeps every record and renders it as JSON, resolving LogValuers
+type recordingHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+	output  bytes.Buffer
+}
+
+// Enabled implements slog.Handler
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle implements slog.Handler
+func (h *recordingHandler) Handle(ctx context.Context, record slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, record.Clone())
+	return slog.NewJSONHandler(&h.output, &slog.HandlerOptions{Level: slog.LevelDebug}).Handle(ctx, record)
+}
+
+// WithAttrs implements slog.Handler
+func (h *recordingHandler) WithAttrs([]slog.Attr) slog.Handler {
+	return h
+}
+
+// WithGroup implements slog.Handler
+func (h *recordingHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// attrs returns the attributes of a record, resolved, by key
+func attrs(record slog.Record) map[string]slog.Value {
+	values := map[string]slog.Value{}
+	record.Attrs(func(attr slog.Attr) bool {
+		values[attr.Key] = attr.Value.Resolve()
+		return true
+	})
+	return values
+}
+
+// authorizationRecorder is an http.RoundTripper that remembers the Authorization headers it sends
+type authorizationRecorder struct {
+	mu     sync.Mutex
+	values []string
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *authorizationRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	r.values = append(r.values, req.Header.Get("Authorization"))
+	r.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// assertNotLogged fails the test if any of the secrets appears in the log output
+func assertNotLogged(t *testing.T, output string, secrets map[string]string) {
+	t.Helper()
+	for name, secret := range secrets {
+		if secret != "" && strings.Contains(output, secret) {
+			t.Errorf("the %s was logged: %s", name, output)
+		}
+	}
+}
+
+func TestAPICallLogging(t *testing.T) {
+	server := appstoretest.NewServer()
+	defer server.Close()
+	transaction := server.AddTransaction("customer", models.JWSTransactionDecodedPayload{})
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingKey := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	tests := []struct {
+		name          string
+		transactionID string
+		level         slog.Level
+		message       string
+		status        int64
+		apiError      models.APIError
+	}{
+		{name: "success", transactionID: *transaction.TransactionId, level: slog.LevelDebug, message: "App Store Server API request succeeded", status: 200},
+		{name: "failure", transactionID: "999999", level: slog.LevelWarn, message: "App Store Server API request failed", status: 404, apiError: models.APIErrorTransactionIDNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &recordingHandler{}
+			transport := &authorizationRecorder{}
+			client, err := appstore.NewAppStoreServerAPIClientWithOptions(signingKey, appstoretest.DefaultKeyID, appstoretest.DefaultIssuerID,
+				server.BundleID(), server.Environment(),
+				appstore.WithBaseURL(server.URL),
+				appstore.WithRateLimiter(nil),
+				appstore.WithHTTPClient(&http.Client{Transport: transport}),
+				appstore.WithLogger(slog.New(handler)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			response, err := client.GetTransactionInfo(context.Background(), tt.transactionID)
+			if (err != nil) != (tt.apiError != 0) {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if len(handler.records) != 1 {
+				t.Fatalf("got %d records, want 1", len(handler.records))
+			}
+			record := handler.records[0]
+			if record.Level != tt.level || record.Message != tt.message {
+				t.Errorf("got %s %q, want %s %q", record.Level, record.Message, tt.level, tt.message)
+			}
+			values := attrs(record)
+			if got := values["endpoint"].String(); got != string(appstore.EndpointGetTransactionInfo) {
+				t.Errorf("endpoint = %s", got)
+			}
+			if got := values["method"].String(); got != http.MethodGet {
+				t.Errorf("method = %s", got)
+			}
+			if got := values["path"].String(); got != "/inApps/v1/transactions/"+tt.transactionID {
+				t.Errorf("path = %s", got)
+			}
+			if got := values["status"].Int64(); got != tt.status {
+				t.Errorf("status = %d, want %d", got, tt.status)
+			}
+			if got := values["attempts"].Int64(); got != 1 {
+				t.Errorf("attempts = %d, want 1", got)
+			}
+			if _, ok := values["duration"]; !ok {
+				t.Error("no duration")
+			}
+			if tt.apiError != 0 {
+				if got := values["api_error"].Int64(); got != int64(tt.apiError) {
+					t.Errorf("api_error = %d, want %d", got, tt.apiError)
+				}
+				if _, ok := values["error"]; !ok {
+					t.Error("no error")
+				}
+			} else if _, ok := values["error"]; ok {
+				t.Error("error logged on success")
+			}
+
+			if len(transport.values) != 1 {
+				t.Fatalf("sent %d requests, want 1", len(transport.values))
+			}
+			token := strings.TrimPrefix(transport.values[0], "Bearer ")
+			secrets := map[string]string{
+				"Authorization header": transport.values[0],
+				"bearer token":         token,
+				"token signature":      token[strings.LastIndex(token, ".")+1:],
+				"signing key":          base64.StdEncoding.EncodeToString(der),
+				"signing key PEM line": strings.Split(string(signingKey), "\n")[1],
+			}
+			if response != nil && response.SignedTransactionInfo != nil {
+				secrets["signed transaction"] = *response.SignedTransactionInfo
+			}
+			output := handler.output.String()
+			assertNotLogged(t, output, secrets)
+			if strings.Contains(output, "Authorization") || strings.Contains(output, "Bearer") {
+				t.Errorf("the Authorization header was logged: %s", output)
+			}
+		})
+	}
+}
+
+func TestVerificationLogging(t *testing.T) {
+	ca, err := appstoretest.NewCertificateAuthority()
+	if err != nil {
+		t.Fatal(err)
+	}
+	environment := models.EnvironmentSandbox
+	signed, err := ca.SignTransaction(models.JWSTransactionDecodedPayload{
+		TransactionId: ptr("1000"),
+		BundleId:      ptr("com.example"),
+		Environment:   &environment,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := tamper(t, signed, "transactionId", "2000")
+
+	tests := []struct {
+		name       string
+		signed     string
+		wantRecord bool
+		status     string
+	}{
+		{name: "valid", signed: signed},
+		{name: "tampered", signed: tampered, wantRecord: true, status: appstore.VerificationStatusVerificationFailure.String()},
+		// {"alg":"none"} with a payload that isn't JSON
+		{name: "malformed", signed: "eyJhbGciOiJub25lIn0.bm90IGpzb24gYXQgYWxs.c2lnbmF0dXJl", wantRecord: true, status: appstore.VerificationStatusVerificationFailure.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &recordingHandler{}
+			verifier, err := ca.NewVerifier(environment, "com.example", nil, appstore.WithVerifierLogger(slog.New(handler)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = verifier.VerifyAndDecodeSignedTransaction(tt.signed)
+			if (err != nil) != tt.wantRecord {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !tt.wantRecord {
+				if len(handler.records) != 0 {
+					t.Errorf("got %d records for a valid payload, want 0", len(handler.records))
+				}
+				return
+			}
+
+			if len(handler.records) != 1 {
+				t.Fatalf("got %d records, want 1", len(handler.records))
+			}
+			record := handler.records[0]
+			if record.Level != slog.LevelWarn || record.Message != "signed data verification failed" {
+				t.Errorf("got %s %q", record.Level, record.Message)
+			}
+			values := attrs(record)
+			if got := values["type"].String(); got != "transaction" {
+				t.Errorf("type = %s", got)
+			}
+			if got := values["environment"].String(); got != string(environment) {
+				t.Errorf("environment = %s", got)
+			}
+			if got, want := values["payload"].String(), "[REDACTED JWS, "; !strings.HasPrefix(got, want) {
+				t.Errorf("payload = %s, want it redacted", got)
+			}
+			if got := values["status"].String(); got != tt.status {
+				t.Errorf("status = %s, want %s", got, tt.status)
+			}
+
+			parts := strings.Split(tt.signed, ".")
+			secrets := map[string]string{"signed payload": tt.signed}
+			if len(parts) == 3 {
+				secrets["JWS header"] = parts[0]
+				secrets["JWS payload"] = parts[1]
+				secrets["JWS signature"] = parts[2]
+			}
+			assertNotLogged(t, handler.output.String(), secrets)
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package appstore
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/DotNetAge/appstore/models"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics collects Prometheus metrics for App Store Server API calls and signed data verification.
+// A single Metrics can be shared by several clients and verifiers.
+type Metrics struct {
+	requests      *prometheus.CounterVec
+	duration      *prometheus.HistogramVec
+	apiErrors     *prometheus.CounterVec
+	retries       *prometheus.CounterVec
+	rateLimitWait *prometheus.HistogramVec
+	verifications *prometheus.CounterVec
+}
+
+// NewMetrics creates the metrics and registers them with the given registerer
+func NewMetrics(registerer prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "appstore",
+			Subsystem: "api",
+			Name:      "requests_total",
+			Help:      "App Store Server API calls by endpoint and final HTTP status code (0 when no response was received).",
+		}, []string{"endpoint", "status_code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "appstore",
+			Subsystem: "api",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of App Store Server API calls, including retries and rate limit waits.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"endpoint"}),
+		apiErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "appstore",
+			Subsystem: "api",
+			Name:      "errors_total",
+			Help:      "Failed App Store Server API calls by endpoint and APIError code.",
+		}, []string{"endpoint", "api_error"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "appstore",
+			Subsystem: "api",
+			Name:      "retries_total",
+			Help:      "Retried App Store Server API requests by endpoint.",
+		}, []string{"endpoint"}),
+		rateLimitWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "appstore",
+			Subsystem: "api",
+			Name:      "rate_limit_wait_seconds",
+			Help:      "Time requests waited for client-side rate limit quota, by endpoint family.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"family"}),
+		verifications: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "appstore",
+			Subsystem: "verifier",
+			Name:      "verifications_total",
+			Help:      "Signed data verifications by payload type and VerificationStatus.",
+		}, []string{"type", "status"}),
+	}
+
+	for _, collector := range []prometheus.Collector{m.requests, m.duration, m.apiErrors, m.retries, m.rateLimitWait, m.verifications} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// WithMetrics records metrics for the client's API calls, and for the verifier of an AppStoreServerClient
+func WithMetrics(metrics *Metrics) ClientOption {
+	return func(opts *clientOptions) {
+		opts.metrics = metrics
+	}
+}
+
+// WithVerifierMetrics records verification outcomes in the given metrics
+func WithVerifierMetrics(metrics *Metrics) VerifierOption {
+	return func(v *SignedDataVerifier) {
+		v.metrics = metrics
+	}
+}
+
+// Intercept implements Interceptor by recording the outcome of each call
+func (m *Metrics) Intercept(ctx context.Context, call *Call, next Invoker) error {
+	err := next(ctx, call)
+
+	endpoint := string(call.Endpoint)
+	m.requests.WithLabelValues(endpoint, strconv.Itoa(call.StatusCode)).Inc()
+	m.duration.WithLabelValues(endpoint).Observe(call.Duration.Seconds())
+	if err != nil {
+		m.apiErrors.WithLabelValues(endpoint, apiErrorLabel(err)).Inc()
+	}
+	return err
+}
+
+// observeRetry records a retried request
+func (m *Metrics) observeRetry(endpoint Endpoint) {
+	if m != nil {
+		m.retries.WithLabelValues(string(endpoint)).Inc()
+	}
+}
+
+// observeRateLimitWait records the time a request waited for rate limit quota
+func (m *Metrics) observeRateLimitWait(family EndpointFamily, wait time.Duration) {
+	if m != nil && wait > 0 {
+		m.rateLimitWait.WithLabelValues(string(family)).Observe(wait.Seconds())
+	}
+}
+
+// observeVerification records the outcome of a verification
+func (m *Metrics) observeVerification(payloadType string, err error) {
+	if m == nil {
+		return
+	}
+
+	status := VerificationStatusOK
+	if err != nil {
+		status = VerificationStatusVerificationFailure
+		var verificationErr *VerificationException
+		if errors.As(err, &verificationErr) {
+			status = verificationErr.Status
+		}
+	}
+	m.verifications.WithLabelValues(payloadType, status.String()).Inc()
+}
+
+// apiErrorLabel returns the label value identifying the cause of a failed call
+func apiErrorLabel(err error) string {
+	var apiErr *models.APIException
+	if !errors.As(err, &apiErr) {
+		return "transport"
+	}
+	if apiErr.RawAPIError == nil {
+		return "http_" + strconv.Itoa(apiErr.HTTPStatusCode)
+	}
+	return strconv.Itoa(int(apiErr.APIError))
+}
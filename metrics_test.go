@@ -0,0 +1,202 @@
+package appstore_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DotNetAge/appstore"
+	"github.com/DotNetAge/appstore/appstoretest"
+	"github.com/DotNetAge/appstore/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// newTestMetrics returns Metrics registered with a fresh registry
+func newTestMetrics(t *testing.T) (*appstore.Metrics, *prometheus.Registry) {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	metrics, err := appstore.NewMetrics(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return metrics, registry
+}
+
+// histogram returns the histogram of the named metric whose labels include the given ones
+func histogram(t *testing.T, registry *prometheus.Registry, name string, labels map[string]string) *dto.Histogram {
+	t.Helper()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if want, ok := labels[label.GetName()]; ok && want != label.GetValue() {
+					continue metrics
+				}
+			}
+			return metric.GetHistogram()
+		}
+	}
+	t.Fatalf("no %s histogram with labels %v", name, labels)
+	return nil
+}
+
+func TestRequestMetrics(t *testing.T) {
+	server := appstoretest.NewServer()
+	defer server.Close()
+	transaction := server.AddTransaction("customer", models.JWSTransactionDecodedPayload{})
+
+	metrics, registry := newTestMetrics(t)
+	// A burst of one makes every request after the first wait for quota
+	limiter := appstore.NewRateLimiter(map[appstore.EndpointFamily]appstore.RateLimit{
+		appstore.EndpointFamilyTransactionInfo: {RequestsPerSecond: 20, Burst: 1},
+	})
+	client, err := server.NewClient(
+		appstore.WithMetrics(metrics),
+		appstore.WithRateLimiter(limiter),
+		appstore.WithRetryPolicy(&appstore.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if _, err := client.GetTransactionInfo(ctx, *transaction.TransactionId); err != nil {
+		t.Fatal(err)
+	}
+	// Retried once, then succeeds
+	server.FailNext(models.APIErrorGeneralInternal)
+	if _, err := client.GetTransactionInfo(ctx, *transaction.TransactionId); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetTransactionInfo(ctx, "999999"); err == nil {
+		t.Fatal("found an unknown transaction")
+	}
+	if _, err := client.GetAllSubscriptionStatuses(ctx, "999999", nil); err == nil {
+		t.Fatal("found statuses of an unknown transaction")
+	}
+
+	expected := `
+# HELP appstore_api_requests_total App Store Server API calls by endpoint and final HTTP status code (0 when no response was received).
+# TYPE appstore_api_requests_total counter
+appstore_api_requests_total{endpoint="GetAllSubscriptionStatuses",status_code="404"} 1
+appstore_api_requests_total{endpoint="GetTransactionInfo",status_code="200"} 2
+appstore_api_requests_total{endpoint="GetTransactionInfo",status_code="404"} 1
+# HELP appstore_api_errors_total Failed App Store Server API calls by endpoint and APIError code.
+# TYPE appstore_api_errors_total counter
+appstore_api_errors_total{api_error="4040010",endpoint="GetAllSubscriptionStatuses"} 1
+appstore_api_errors_total{api_error="4040010",endpoint="GetTransactionInfo"} 1
+# HELP appstore_api_retries_total Retried App Store Server API requests by endpoint.
+# TYPE appstore_api_retries_total counter
+appstore_api_retries_total{endpoint="GetTransactionInfo"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"appstore_api_requests_total", "appstore_api_errors_total", "appstore_api_retries_total"); err != nil {
+		t.Error(err)
+	}
+
+	if got := histogram(t, registry, "appstore_api_request_duration_seconds", map[string]string{"endpoint": "GetTransactionInfo"}).GetSampleCount(); got != 3 {
+		t.Errorf("GetTransactionInfo duration samples = %d, want 3", got)
+	}
+	if got := histogram(t, registry, "appstore_api_request_duration_seconds", map[string]string{"endpoint": "GetAllSubscriptionStatuses"}).GetSampleCount(); got != 1 {
+		t.Errorf("GetAllSubscriptionStatuses duration samples = %d, want 1", got)
+	}
+
+	// Every GetTransactionInfo attempt after the first waited for quota
+	wait := histogram(t, registry, "appstore_api_rate_limit_wait_seconds", map[string]string{"family": string(appstore.EndpointFamilyTransactionInfo)})
+	if wait.GetSampleCount() == 0 || wait.GetSampleSum() <= 0 {
+		t.Errorf("rate limit waits = %d samples, %f seconds, want some", wait.GetSampleCount(), wait.GetSampleSum())
+	}
+	if got := int64(wait.GetSampleCount()); got != limiter.State()[appstore.EndpointFamilyTransactionInfo].Waits {
+		t.Errorf("rate limit wait samples = %d, want the limiter's %d waits", got, limiter.State()[appstore.EndpointFamilyTransactionInfo].Waits)
+	}
+	if count := testutil.CollectAndCount(registry, "appstore_api_rate_limit_wait_seconds"); count != 1 {
+		t.Errorf("rate limit wait series = %d, want 1", count)
+	}
+}
+
+func TestRequestMetricsWithoutResponse(t *testing.T) {
+	server := appstoretest.NewServer()
+	server.Close()
+
+	metrics, registry := newTestMetrics(t)
+	client, err := server.NewClient(appstore.WithMetrics(metrics), appstore.WithRetryPolicy(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetTransactionInfo(context.Background(), "1000"); err == nil {
+		t.Fatal("reached a closed server")
+	}
+
+	expected := `
+# HELP appstore_api_requests_total App Store Server API calls by endpoint and final HTTP status code (0 when no response was received).
+# TYPE appstore_api_requests_total counter
+appstore_api_requests_total{endpoint="GetTransactionInfo",status_code="0"} 1
+# HELP appstore_api_errors_total Failed App Store Server API calls by endpoint and APIError code.
+# TYPE appstore_api_errors_total counter
+appstore_api_errors_total{api_error="transport",endpoint="GetTransactionInfo"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "appstore_api_requests_total", "appstore_api_errors_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVerificationMetrics(t *testing.T) {
+	ca, err := appstoretest.NewCertificateAuthority()
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics, registry := newTestMetrics(t)
+	verifier, err := ca.NewVerifier(models.EnvironmentSandbox, "com.example", nil, appstore.WithVerifierMetrics(metrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	environment := models.EnvironmentSandbox
+	signed, err := ca.SignTransaction(models.JWSTransactionDecodedPayload{
+		TransactionId: ptr("1000"),
+		BundleId:      ptr("com.example"),
+		Environment:   &environment,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherBundle, err := ca.SignTransaction(models.JWSTransactionDecodedPayload{
+		TransactionId: ptr("1000"),
+		BundleId:      ptr("com.example.other"),
+		Environment:   &environment,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, payload := range []string{signed, signed, tamper(t, signed, "transactionId", "2000"), otherBundle} {
+		_, _ = verifier.VerifyAndDecodeSignedTransaction(payload)
+	}
+
+	expected := `
+# HELP appstore_verifier_verifications_total Signed data verifications by payload type and VerificationStatus.
+# TYPE appstore_verifier_verifications_total counter
+appstore_verifier_verifications_total{status="InvalidAppIdentifier",type="transaction"} 1
+appstore_verifier_verifications_total{status="OK",type="transaction"} 2
+appstore_verifier_verifications_total{status="VerificationFailure",type="transaction"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "appstore_verifier_verifications_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNewMetricsRegistersOnce(t *testing.T) {
+	_, registry := newTestMetrics(t)
+	if _, err := appstore.NewMetrics(registry); err == nil {
+		t.Error("registered the metrics twice with one registry")
+	}
+}
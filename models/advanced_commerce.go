@@ -0,0 +1,269 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+import (
+	"regexp"
+
+	"github.com/DotNetAge/appstore/internal/apple"
+)
+
+const (
+	// MaxAdvancedCommerceSKULength is the maximum length of an Advanced Commerce API item SKU
+	MaxAdvancedCommerceSKULength = 128
+	// MaxAdvancedCommerceDescriptionLength is the maximum length of an Advanced Commerce API item or subscription description
+	MaxAdvancedCommerceDescriptionLength = 45
+	// MaxAdvancedCommerceDisplayNameLength is the maximum length of an Advanced Commerce API item or subscription display name
+	MaxAdvancedCommerceDisplayNameLength = 30
+	// MinAdvancedCommerceOfferPeriodCount is the smallest number of periods an Advanced Commerce API offer lasts
+	MinAdvancedCommerceOfferPeriodCount = 1
+	// MaxAdvancedCommerceOfferPeriodCount is the largest number of periods an Advanced Commerce API offer lasts
+	MaxAdvancedCommerceOfferPeriodCount = 12
+)
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// AdvancedCommerceInAppRequest is an Advanced Commerce API request your app passes to StoreKit, after your server
+// signs it with an AdvancedCommerceInAppSignatureCreator
+type AdvancedCommerceInAppRequest interface {
+	// Validate checks the request before it is signed
+	Validate() error
+	// Operation returns the name of the operation the request performs, such as "CREATE_SUBSCRIPTION"
+	Operation() string
+}
+
+// AdvancedCommerceRequestInfo represents the metadata every Advanced Commerce API request carries
+// https://developer.apple.com/documentation/advancedcommerceapi/requestinfo
+type AdvancedCommerceRequestInfo struct {
+	// RequestReferenceId is a UUID you generate to identify the request
+	RequestReferenceId *string `json:"requestReferenceId,omitempty"`
+
+	// AppAccountToken is the UUID that associates the purchase with an account on your system
+	// https://developer.apple.com/documentation/appstoreserverapi/appaccounttoken
+	AppAccountToken *string `json:"appAccountToken,omitempty"`
+
+	// ConsistencyToken is the token Apple returned for a previous change, to make sure this request sees it
+	ConsistencyToken *string `json:"consistencyToken,omitempty"`
+}
+
+// AdvancedCommerceDescriptors represents the display name and description of a subscription
+// https://developer.apple.com/documentation/advancedcommerceapi/descriptors
+type AdvancedCommerceDescriptors struct {
+	// Description is the description of the subscription, at most MaxAdvancedCommerceDescriptionLength characters
+	Description *string `json:"description,omitempty"`
+
+	// DisplayName is the name of the subscription, at most MaxAdvancedCommerceDisplayNameLength characters
+	DisplayName *string `json:"displayName,omitempty"`
+}
+
+// AdvancedCommerceOffer represents a discounted price for a number of subscription periods
+// https://developer.apple.com/documentation/advancedcommerceapi/offer
+type AdvancedCommerceOffer struct {
+	// Period is the length of one offer period
+	Period *AdvancedCommercePeriod `json:"period,omitempty"`
+
+	// PeriodCount is the number of periods the offer lasts
+	PeriodCount *int `json:"periodCount,omitempty"`
+
+	// Price is the offer price, in milliunits of the currency
+	Price *int64 `json:"price,omitempty"`
+
+	// Reason is why the offer is made
+	Reason *AdvancedCommerceOfferReason `json:"reason,omitempty"`
+}
+
+// AdvancedCommercePeriod represents the renewal period of a subscription
+// https://developer.apple.com/documentation/advancedcommerceapi/period
+type AdvancedCommercePeriod string
+
+const (
+	// AdvancedCommercePeriodOneWeek indicates a subscription that renews every week
+	AdvancedCommercePeriodOneWeek AdvancedCommercePeriod = "P1W"
+	// AdvancedCommercePeriodOneMonth indicates a subscription that renews every month
+	AdvancedCommercePeriodOneMonth AdvancedCommercePeriod = "P1M"
+	// AdvancedCommercePeriodTwoMonths indicates a subscription that renews every two months
+	AdvancedCommercePeriodTwoMonths AdvancedCommercePeriod = "P2M"
+	// AdvancedCommercePeriodThreeMonths indicates a subscription that renews every three months
+	AdvancedCommercePeriodThreeMonths AdvancedCommercePeriod = "P3M"
+	// AdvancedCommercePeriodSixMonths indicates a subscription that renews every six months
+	AdvancedCommercePeriodSixMonths AdvancedCommercePeriod = "P6M"
+	// AdvancedCommercePeriodOneYear indicates a subscription that renews every year
+	AdvancedCommercePeriodOneYear AdvancedCommercePeriod = "P1Y"
+)
+
+// AdvancedCommerceOfferReason represents why an offer is made
+// https://developer.apple.com/documentation/advancedcommerceapi/offerreason
+type AdvancedCommerceOfferReason string
+
+const (
+	// AdvancedCommerceOfferReasonAcquisition indicates an offer that attracts a new subscriber
+	AdvancedCommerceOfferReasonAcquisition AdvancedCommerceOfferReason = "ACQUISITION"
+	// AdvancedCommerceOfferReasonWinBack indicates an offer that wins back a former subscriber
+	AdvancedCommerceOfferReasonWinBack AdvancedCommerceOfferReason = "WIN_BACK"
+	// AdvancedCommerceOfferReasonRetention indicates an offer that keeps a current subscriber
+	AdvancedCommerceOfferReasonRetention AdvancedCommerceOfferReason = "RETENTION"
+)
+
+// AdvancedCommerceEffective represents when a subscription change takes effect
+// https://developer.apple.com/documentation/advancedcommerceapi/effective
+type AdvancedCommerceEffective string
+
+const (
+	// AdvancedCommerceEffectiveImmediately indicates a change that takes effect at once
+	AdvancedCommerceEffectiveImmediately AdvancedCommerceEffective = "IMMEDIATELY"
+	// AdvancedCommerceEffectiveNextBillCycle indicates a change that takes effect when the subscription next renews
+	AdvancedCommerceEffectiveNextBillCycle AdvancedCommerceEffective = "NEXT_BILL_CYCLE"
+)
+
+// AdvancedCommerceRefundReason represents why a refund is requested
+// https://developer.apple.com/documentation/advancedcommerceapi/refundreason
+type AdvancedCommerceRefundReason string
+
+const (
+	// AdvancedCommerceRefundReasonUnintendedPurchase indicates the customer didn't mean to buy
+	AdvancedCommerceRefundReasonUnintendedPurchase AdvancedCommerceRefundReason = "UNINTENDED_PURCHASE"
+	// AdvancedCommerceRefundReasonFulfillmentIssue indicates the purchase wasn't delivered as expected
+	AdvancedCommerceRefundReasonFulfillmentIssue AdvancedCommerceRefundReason = "FULFILLMENT_ISSUE"
+	// AdvancedCommerceRefundReasonUnsatisfiedWithPurchase indicates the customer is unhappy with the purchase
+	AdvancedCommerceRefundReasonUnsatisfiedWithPurchase AdvancedCommerceRefundReason = "UNSATISFIED_WITH_PURCHASE"
+	// AdvancedCommerceRefundReasonLegal indicates the refund is required by law
+	AdvancedCommerceRefundReasonLegal AdvancedCommerceRefundReason = "LEGAL"
+	// AdvancedCommerceRefundReasonOther indicates a reason not listed
+	AdvancedCommerceRefundReasonOther AdvancedCommerceRefundReason = "OTHER"
+	// AdvancedCommerceRefundReasonModifyItemsRefund indicates items were removed from a subscription
+	AdvancedCommerceRefundReasonModifyItemsRefund AdvancedCommerceRefundReason = "MODIFY_ITEMS_REFUND"
+	// AdvancedCommerceRefundReasonSimulateRefundDecline indicates a declined refund, in the sandbox
+	AdvancedCommerceRefundReasonSimulateRefundDecline AdvancedCommerceRefundReason = "SIMULATE_REFUND_DECLINE"
+)
+
+// AdvancedCommerceRefundType represents how much of a purchase is refunded
+// https://developer.apple.com/documentation/advancedcommerceapi/refundtype
+type AdvancedCommerceRefundType string
+
+const (
+	// AdvancedCommerceRefundTypeFull refunds the whole price
+	AdvancedCommerceRefundTypeFull AdvancedCommerceRefundType = "FULL"
+	// AdvancedCommerceRefundTypeProrated refunds the unused part of the period
+	AdvancedCommerceRefundTypeProrated AdvancedCommerceRefundType = "PRORATED"
+	// AdvancedCommerceRefundTypeCustom refunds the amount you set
+	AdvancedCommerceRefundTypeCustom AdvancedCommerceRefundType = "CUSTOM"
+)
+
+// AdvancedCommerceSubscriptionResponse represents the response to an Advanced Commerce API request that changes a
+// subscription; each operation names its own response type with these fields
+type AdvancedCommerceSubscriptionResponse struct {
+	// SignedRenewalInfo is the subscription renewal information, signed by Apple, in JSON Web Signature (JWS) format
+	// https://developer.apple.com/documentation/appstoreserverapi/jwsrenewalinfo
+	SignedRenewalInfo *string `json:"signedRenewalInfo,omitempty"`
+
+	// SignedTransactionInfo is the transaction information, signed by Apple, in JSON Web Signature (JWS) format
+	// https://developer.apple.com/documentation/appstoreserverapi/jwstransaction
+	SignedTransactionInfo *string `json:"signedTransactionInfo,omitempty"`
+}
+
+// validateRequestInfo checks the required request metadata
+func validateRequestInfo(v *validator, info *AdvancedCommerceRequestInfo) {
+	if !v.required("requestInfo", info != nil) {
+		return
+	}
+	if v.required("requestInfo.requestReferenceId", info.RequestReferenceId != nil) {
+		v.uuid("requestInfo.requestReferenceId", *info.RequestReferenceId)
+	}
+	if info.AppAccountToken != nil {
+		v.uuid("requestInfo.appAccountToken", *info.AppAccountToken)
+	}
+}
+
+// validateSKU checks a required item SKU
+func validateSKU(v *validator, field string, sku *string) {
+	if v.required(field, sku != nil && *sku != "") {
+		v.maxLength(field, *sku, MaxAdvancedCommerceSKULength)
+	}
+}
+
+// validateDescriptors checks a required description and display name
+func validateDescriptors(v *validator, prefix string, description, displayName *string) {
+	if v.required(prefix+"description", description != nil) {
+		v.maxLength(prefix+"description", *description, MaxAdvancedCommerceDescriptionLength)
+	}
+	if v.required(prefix+"displayName", displayName != nil) {
+		v.maxLength(prefix+"displayName", *displayName, MaxAdvancedCommerceDisplayNameLength)
+	}
+}
+
+// validatePrice checks a required price in milliunits
+func validatePrice(v *validator, field string, price *int64) {
+	if v.required(field, price != nil) && *price < 0 {
+		v.addf(field, "must not be negative, got %d", *price)
+	}
+}
+
+// validateOffer checks an optional offer
+func validateOffer(v *validator, field string, offer *AdvancedCommerceOffer) {
+	if offer == nil {
+		return
+	}
+	if v.required(field+".period", offer.Period != nil) {
+		validatePeriod(v, field+".period", *offer.Period)
+	}
+	if v.required(field+".periodCount", offer.PeriodCount != nil) {
+		v.between(field+".periodCount", *offer.PeriodCount, MinAdvancedCommerceOfferPeriodCount, MaxAdvancedCommerceOfferPeriodCount)
+	}
+	validatePrice(v, field+".price", offer.Price)
+	if v.required(field+".reason", offer.Reason != nil) {
+		v.oneOf(field+".reason", string(*offer.Reason),
+			string(AdvancedCommerceOfferReasonAcquisition),
+			string(AdvancedCommerceOfferReasonWinBack),
+			string(AdvancedCommerceOfferReasonRetention))
+	}
+}
+
+// validatePeriod checks a subscription period
+func validatePeriod(v *validator, field string, period AdvancedCommercePeriod) {
+	v.oneOf(field, string(period),
+		string(AdvancedCommercePeriodOneWeek),
+		string(AdvancedCommercePeriodOneMonth),
+		string(AdvancedCommercePeriodTwoMonths),
+		string(AdvancedCommercePeriodThreeMonths),
+		string(AdvancedCommercePeriodSixMonths),
+		string(AdvancedCommercePeriodOneYear))
+}
+
+// validateEffective checks when a change takes effect
+func validateEffective(v *validator, field string, effective AdvancedCommerceEffective) {
+	v.oneOf(field, string(effective), string(AdvancedCommerceEffectiveImmediately), string(AdvancedCommerceEffectiveNextBillCycle))
+}
+
+// validateCurrency checks an ISO 4217 currency code
+func validateCurrency(v *validator, field string, currency string) {
+	if !currencyPattern.MatchString(currency) {
+		v.addf(field, "must be an ISO 4217 currency code, got %q", currency)
+	}
+}
+
+// validateStorefront checks an optional ISO 3166-1 alpha-3 storefront country code
+func validateStorefront(v *validator, storefront *string) {
+	if storefront != nil && !apple.StorefrontCountryCodePattern.MatchString(*storefront) {
+		v.addf("storefront", "must be an ISO 3166-1 alpha-3 country code, got %q", *storefront)
+	}
+}
+
+// validateRefund checks a refund reason and type
+func validateRefund(v *validator, prefix string, reason *AdvancedCommerceRefundReason, refundType *AdvancedCommerceRefundType) {
+	if v.required(prefix+"refundReason", reason != nil) {
+		v.oneOf(prefix+"refundReason", string(*reason),
+			string(AdvancedCommerceRefundReasonUnintendedPurchase),
+			string(AdvancedCommerceRefundReasonFulfillmentIssue),
+			string(AdvancedCommerceRefundReasonUnsatisfiedWithPurchase),
+			string(AdvancedCommerceRefundReasonLegal),
+			string(AdvancedCommerceRefundReasonOther),
+			string(AdvancedCommerceRefundReasonModifyItemsRefund),
+			string(AdvancedCommerceRefundReasonSimulateRefundDecline))
+	}
+	if v.required(prefix+"refundType", refundType != nil) {
+		v.oneOf(prefix+"refundType", string(*refundType),
+			string(AdvancedCommerceRefundTypeFull),
+			string(AdvancedCommerceRefundTypeProrated),
+			string(AdvancedCommerceRefundTypeCustom))
+	}
+}
@@ -2,6 +2,8 @@
 
 package models
 
+import "fmt"
+
 // APIError represents the error codes returned by the App Store Server API
 // https://developer.apple.com/documentation/appstoreserverapi/error_codes
 type APIError int
@@ -87,12 +89,38 @@ const (
 	APIErrorInvalidTransactionNotConsumable APIError = 4000043
 	// APIErrorInvalidTransactionTypeNotSupported indicates the transaction identifier represents an unsupported in-app purchase type
 	APIErrorInvalidTransactionTypeNotSupported APIError = 4000047
+	// APIErrorInvalidImage indicates the uploaded image isn't a PNG of the required size
+	APIErrorInvalidImage APIError = 4000161
+	// APIErrorHeaderTooLong indicates the header of a retention message is too long
+	APIErrorHeaderTooLong APIError = 4000162
+	// APIErrorBodyTooLong indicates the body of a retention message is too long
+	APIErrorBodyTooLong APIError = 4000163
+	// APIErrorInvalidLocale indicates the locale is invalid
+	APIErrorInvalidLocale APIError = 4000164
+	// APIErrorAltTextTooLong indicates the alternative text of a retention message image is too long
+	APIErrorAltTextTooLong APIError = 4000175
+	// APIErrorInvalidAppAccountTokenUUID indicates the app account token isn't a valid UUID
+	APIErrorInvalidAppAccountTokenUUID APIError = 4000183
+	// APIErrorFamilyTransactionNotSupported indicates the transaction was obtained through Family Sharing, which the endpoint doesn't support
+	APIErrorFamilyTransactionNotSupported APIError = 4000185
+	// APIErrorTransactionIDIsNotOriginalTransactionID indicates the endpoint expects an original transaction identifier but received another transaction identifier
+	APIErrorTransactionIDIsNotOriginalTransactionID APIError = 4000187
 	// APIErrorSubscriptionExtensionIneligible indicates the subscription doesn't qualify for a renewal-date extension due to its subscription state
 	APIErrorSubscriptionExtensionIneligible APIError = 4030004
 	// APIErrorSubscriptionMaxExtension indicates the subscription doesn't qualify for a renewal-date extension because it has already received the maximum extensions
 	APIErrorSubscriptionMaxExtension APIError = 4030005
 	// APIErrorFamilySharedSubscriptionExtensionIneligible indicates a subscription isn't directly eligible for a renewal date extension because the user obtained it through Family Sharing
 	APIErrorFamilySharedSubscriptionExtensionIneligible APIError = 4030007
+	// APIErrorMaximumNumberOfImagesReached indicates the app already has the maximum number of retention message images
+	APIErrorMaximumNumberOfImagesReached APIError = 4030014
+	// APIErrorMaximumNumberOfMessagesReached indicates the app already has the maximum number of retention messages
+	APIErrorMaximumNumberOfMessagesReached APIError = 4030016
+	// APIErrorMessageNotApproved indicates the retention message isn't approved, so it can't be configured as a default
+	APIErrorMessageNotApproved APIError = 4030017
+	// APIErrorImageNotApproved indicates the image isn't approved, so a message can't use it
+	APIErrorImageNotApproved APIError = 4030018
+	// APIErrorImageInUse indicates the image can't be deleted because a message uses it
+	APIErrorImageInUse APIError = 4030019
 	// APIErrorAccountNotFound indicates the App Store account wasn't found
 	APIErrorAccountNotFound APIError = 4040001
 	// APIErrorAccountNotFoundRetryable indicates the App Store account wasn't found, but you can try again
@@ -113,8 +141,136 @@ const (
 	APIErrorStatusRequestNotFound APIError = 4040009
 	// APIErrorTransactionIDNotFound indicates a transaction identifier wasn't found
 	APIErrorTransactionIDNotFound APIError = 4040010
+	// APIErrorImageNotFound indicates the retention message image wasn't found
+	APIErrorImageNotFound APIError = 4040014
+	// APIErrorMessageNotFound indicates the retention message wasn't found
+	APIErrorMessageNotFound APIError = 4040015
+	// APIErrorAppTransactionDoesNotExist indicates the customer doesn't have an app transaction for the app
+	APIErrorAppTransactionDoesNotExist APIError = 4040019
+	// APIErrorImageAlreadyExists indicates an image with the identifier was already uploaded
+	APIErrorImageAlreadyExists APIError = 4090000
+	// APIErrorMessageAlreadyExists indicates a message with the identifier was already uploaded
+	APIErrorMessageAlreadyExists APIError = 4090001
 	// APIErrorRateLimitExceeded indicates that the request exceeded the rate limit
 	APIErrorRateLimitExceeded APIError = 4290000
 	// APIErrorGeneralInternal indicates a general internal error
 	APIErrorGeneralInternal APIError = 5000000
 )
+
+// apiErrorNames contains the name Apple's documentation uses for each APIError
+var apiErrorNames = map[APIError]string{
+	APIErrorGeneralBadRequest:                           "GeneralBadRequestError",
+	APIErrorInvalidAppIdentifier:                        "InvalidAppIdentifierError",
+	APIErrorInvalidRequestRevision:                      "InvalidRequestRevisionError",
+	APIErrorInvalidTransactionID:                        "InvalidTransactionIdError",
+	APIErrorInvalidOriginalTransactionID:                "InvalidOriginalTransactionIdError",
+	APIErrorInvalidExtendByDays:                         "InvalidExtendByDaysError",
+	APIErrorInvalidExtendReasonCode:                     "InvalidExtendReasonCodeError",
+	APIErrorInvalidRequestIdentifier:                    "InvalidRequestIdentifierError",
+	APIErrorStartDateTooFarInPast:                       "StartDateTooFarInPastError",
+	APIErrorStartDateAfterEndDate:                       "StartDateAfterEndDateError",
+	APIErrorInvalidPaginationToken:                      "InvalidPaginationTokenError",
+	APIErrorInvalidStartDate:                            "InvalidStartDateError",
+	APIErrorInvalidEndDate:                              "InvalidEndDateError",
+	APIErrorPaginationTokenExpired:                      "PaginationTokenExpiredError",
+	APIErrorInvalidNotificationType:                     "InvalidNotificationTypeError",
+	APIErrorMultipleFiltersSupplied:                     "MultipleFiltersSuppliedError",
+	APIErrorInvalidTestNotificationToken:                "InvalidTestNotificationTokenError",
+	APIErrorInvalidSort:                                 "InvalidSortError",
+	APIErrorInvalidProductType:                          "InvalidProductTypeError",
+	APIErrorInvalidProductID:                            "InvalidProductIdError",
+	APIErrorInvalidSubscriptionGroupIdentifier:          "InvalidSubscriptionGroupIdentifierError",
+	APIErrorInvalidExcludeRevoked:                       "InvalidExcludeRevokedError",
+	APIErrorInvalidInAppOwnershipType:                   "InvalidInAppOwnershipTypeError",
+	APIErrorInvalidEmptyStorefrontCountryCodeList:       "InvalidEmptyStorefrontCountryCodeListError",
+	APIErrorInvalidStorefrontCountryCode:                "InvalidStorefrontCountryCodeError",
+	APIErrorInvalidRevoked:                              "InvalidRevokedError",
+	APIErrorInvalidStatus:                               "InvalidStatusError",
+	APIErrorInvalidAccountTenure:                        "InvalidAccountTenureError",
+	APIErrorInvalidAppAccountToken:                      "InvalidAppAccountTokenError",
+	APIErrorInvalidConsumptionStatus:                    "InvalidConsumptionStatusError",
+	APIErrorInvalidCustomerConsented:                    "InvalidCustomerConsentedError",
+	APIErrorInvalidDeliveryStatus:                       "InvalidDeliveryStatusError",
+	APIErrorInvalidLifetimeDollarsPurchased:             "InvalidLifetimeDollarsPurchasedError",
+	APIErrorInvalidLifetimeDollarsRefunded:              "InvalidLifetimeDollarsRefundedError",
+	APIErrorInvalidPlatform:                             "InvalidPlatformError",
+	APIErrorInvalidPlayTime:                             "InvalidPlayTimeError",
+	APIErrorInvalidSampleContentProvided:                "InvalidSampleContentProvidedError",
+	APIErrorInvalidUserStatus:                           "InvalidUserStatusError",
+	APIErrorInvalidTransactionNotConsumable:             "InvalidTransactionNotConsumableError",
+	APIErrorInvalidTransactionTypeNotSupported:          "InvalidTransactionTypeNotSupportedError",
+	APIErrorInvalidImage:                                "InvalidImageError",
+	APIErrorHeaderTooLong:                               "HeaderTooLongError",
+	APIErrorBodyTooLong:                                 "BodyTooLongError",
+	APIErrorInvalidLocale:                               "InvalidLocaleError",
+	APIErrorAltTextTooLong:                              "AltTextTooLongError",
+	APIErrorInvalidAppAccountTokenUUID:                  "InvalidAppAccountTokenUUIDError",
+	APIErrorFamilyTransactionNotSupported:               "FamilyTransactionNotSupportedError",
+	APIErrorTransactionIDIsNotOriginalTransactionID:     "TransactionIdIsNotOriginalTransactionIdError",
+	APIErrorSubscriptionExtensionIneligible:             "SubscriptionExtensionIneligibleError",
+	APIErrorSubscriptionMaxExtension:                    "SubscriptionMaxExtensionError",
+	APIErrorFamilySharedSubscriptionExtensionIneligible: "FamilySharedSubscriptionExtensionIneligibleError",
+	APIErrorMaximumNumberOfImagesReached:                "MaximumNumberOfImagesReachedError",
+	APIErrorMaximumNumberOfMessagesReached:              "MaximumNumberOfMessagesReachedError",
+	APIErrorMessageNotApproved:                          "MessageNotApprovedError",
+	APIErrorImageNotApproved:                            "ImageNotApprovedError",
+	APIErrorImageInUse:                                  "ImageInUseError",
+	APIErrorAccountNotFound:                             "AccountNotFoundError",
+	APIErrorAccountNotFoundRetryable:                    "AccountNotFoundRetryableError",
+	APIErrorAppNotFound:                                 "AppNotFoundError",
+	APIErrorAppNotFoundRetryable:                        "AppNotFoundRetryableError",
+	APIErrorOriginalTransactionIDNotFound:               "OriginalTransactionIdNotFoundError",
+	APIErrorOriginalTransactionIDNotFoundRetryable:      "OriginalTransactionIdNotFoundRetryableError",
+	APIErrorServerNotificationURLNotFound:               "ServerNotificationUrlNotFoundError",
+	APIErrorTestNotificationNotFound:                    "TestNotificationNotFoundError",
+	APIErrorStatusRequestNotFound:                       "StatusRequestNotFoundError",
+	APIErrorTransactionIDNotFound:                       "TransactionIdNotFoundError",
+	APIErrorImageNotFound:                               "ImageNotFoundError",
+	APIErrorMessageNotFound:                             "MessageNotFoundError",
+	APIErrorAppTransactionDoesNotExist:                  "AppTransactionDoesNotExistError",
+	APIErrorImageAlreadyExists:                          "ImageAlreadyExistsError",
+	APIErrorMessageAlreadyExists:                        "MessageAlreadyExistsError",
+	APIErrorRateLimitExceeded:                           "RateLimitExceededError",
+	APIErrorGeneralInternal:                             "GeneralInternalError",
+}
+
+// String returns the name of the error code, e.g. "TransactionIdNotFoundError"
+func (e APIError) String() string {
+	if name, ok := apiErrorNames[e]; ok {
+		return name
+	}
+	return fmt.Sprintf("APIError(%d)", int(e))
+}
+
+// Error implements the error interface so error codes can be matched with errors.Is
+func (e APIError) Error() string {
+	return fmt.Sprintf("%s (%d)", e.String(), int(e))
+}
+
+// IsRetryable reports whether Apple documents the error as one that may succeed when retried
+func (e APIError) IsRetryable() bool {
+	switch e {
+	case APIErrorAccountNotFoundRetryable,
+		APIErrorAppNotFoundRetryable,
+		APIErrorOriginalTransactionIDNotFoundRetryable,
+		APIErrorRateLimitExceeded,
+		APIErrorGeneralInternal:
+		return true
+	}
+	return false
+}
+
+// IsNotFound reports whether the error indicates that a requested resource doesn't exist
+func (e APIError) IsNotFound() bool {
+	return e/10000 == 404
+}
+
+// IsRateLimited reports whether the error indicates that the request exceeded the rate limit
+func (e APIError) IsRateLimited() bool {
+	return e == APIErrorRateLimitExceeded
+}
+
+// IsInvalidRequest reports whether the error indicates that the request itself is invalid
+func (e APIError) IsInvalidRequest() bool {
+	return e/10000 == 400
+}
@@ -0,0 +1,210 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestAPIErrorString(t *testing.T) {
+	tests := []struct {
+		code      APIError
+		wantName  string
+		wantError string
+	}{
+		{code: APIErrorTransactionIDNotFound, wantName: "TransactionIdNotFoundError", wantError: "TransactionIdNotFoundError (4040010)"},
+		{code: APIErrorRateLimitExceeded, wantName: "RateLimitExceededError", wantError: "RateLimitExceededError (4290000)"},
+		{code: APIErrorGeneralInternal, wantName: "GeneralInternalError", wantError: "GeneralInternalError (5000000)"},
+		{code: APIError(4009999), wantName: "APIError(4009999)", wantError: "APIError(4009999) (4009999)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.wantName, func(t *testing.T) {
+			if got := tt.code.String(); got != tt.wantName {
+				t.Errorf("String() = %s, want %s", got, tt.wantName)
+			}
+			if got := tt.code.Error(); got != tt.wantError {
+				t.Errorf("Error() = %s, want %s", got, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestAPIErrorNames(t *testing.T) {
+	// Every named code belongs to the HTTP status its first three digits give
+	for code, name := range apiErrorNames {
+		if status := int(code) / 10000; http.StatusText(status) == "" {
+			t.Errorf("%s (%d) has no HTTP status", name, int(code))
+		}
+	}
+}
+
+func TestAPIErrorClassification(t *testing.T) {
+	tests := []struct {
+		code           APIError
+		retryable      bool
+		notFound       bool
+		rateLimited    bool
+		invalidRequest bool
+	}{
+		{code: APIErrorGeneralBadRequest, invalidRequest: true},
+		{code: APIErrorInvalidTransactionID, invalidRequest: true},
+		{code: APIErrorSubscriptionExtensionIneligible},
+		{code: APIErrorAccountNotFound, notFound: true},
+		{code: APIErrorAccountNotFoundRetryable, notFound: true, retryable: true},
+		{code: APIErrorAppNotFoundRetryable, notFound: true, retryable: true},
+		{code: APIErrorOriginalTransactionIDNotFoundRetryable, notFound: true, retryable: true},
+		{code: APIErrorTransactionIDNotFound, notFound: true},
+		{code: APIErrorImageAlreadyExists},
+		{code: APIErrorRateLimitExceeded, retryable: true, rateLimited: true},
+		{code: APIErrorGeneralInternal, retryable: true},
+		{code: APIError(4009999), invalidRequest: true},
+		{code: APIError(4049999), notFound: true},
+		{code: APIError(5000001)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			if got := tt.code.IsRetryable(); got != tt.retryable {
+				t.Errorf("IsRetryable() = %v", got)
+			}
+			if got := tt.code.IsNotFound(); got != tt.notFound {
+				t.Errorf("IsNotFound() = %v", got)
+			}
+			if got := tt.code.IsRateLimited(); got != tt.rateLimited {
+				t.Errorf("IsRateLimited() = %v", got)
+			}
+			if got := tt.code.IsInvalidRequest(); got != tt.invalidRequest {
+				t.Errorf("IsInvalidRequest() = %v", got)
+			}
+		})
+	}
+}
+
+// newAPIException returns the exception the client builds for a response with the status code and, if not zero,
+// the error code
+func newAPIException(statusCode int, code APIError) *APIException {
+	exception := &APIException{HTTPStatusCode: statusCode}
+	if code != 0 {
+		raw := int(code)
+		message := "message"
+		exception.RawAPIError = &raw
+		exception.APIError = code
+		exception.ErrorMessage = &message
+	}
+	return exception
+}
+
+func TestAPIExceptionIs(t *testing.T) {
+	sentinels := []error{ErrRetryable, ErrNotFound, ErrRateLimited, ErrInvalidRequest}
+
+	tests := []struct {
+		name      string
+		exception *APIException
+		want      []error
+	}{
+		{name: "invalid request", exception: newAPIException(400, APIErrorInvalidTransactionID), want: []error{ErrInvalidRequest}},
+		{name: "forbidden", exception: newAPIException(403, APIErrorSubscriptionExtensionIneligible)},
+		{name: "not found", exception: newAPIException(404, APIErrorTransactionIDNotFound), want: []error{ErrNotFound}},
+		{name: "retryable not found", exception: newAPIException(404, APIErrorAccountNotFoundRetryable), want: []error{ErrNotFound, ErrRetryable}},
+		{name: "conflict", exception: newAPIException(409, APIErrorImageAlreadyExists)},
+		{name: "rate limited", exception: newAPIException(429, APIErrorRateLimitExceeded), want: []error{ErrRateLimited, ErrRetryable}},
+		{name: "internal", exception: newAPIException(500, APIErrorGeneralInternal), want: []error{ErrRetryable}},
+		{name: "unknown 400 code", exception: newAPIException(400, APIError(4009999)), want: []error{ErrInvalidRequest}},
+		{name: "unknown 404 code", exception: newAPIException(404, APIError(4049999)), want: []error{ErrNotFound}},
+		{name: "unknown 500 code", exception: newAPIException(500, APIError(5000001)), want: []error{ErrRetryable}},
+		{name: "400 without code", exception: newAPIException(400, 0), want: []error{ErrInvalidRequest}},
+		{name: "401 without code", exception: newAPIException(401, 0)},
+		{name: "404 without code", exception: newAPIException(404, 0), want: []error{ErrNotFound}},
+		{name: "429 without code", exception: newAPIException(429, 0), want: []error{ErrRateLimited, ErrRetryable}},
+		{name: "502 without code", exception: newAPIException(502, 0), want: []error{ErrRetryable}},
+		{name: "503 without code", exception: newAPIException(503, 0), want: []error{ErrRetryable}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := map[string]error{
+				"exception": tt.exception,
+				"wrapped":   fmt.Errorf("GetTransactionInfo: %w", tt.exception),
+				"joined":    errors.Join(io.EOF, tt.exception),
+			}
+			for form, err := range errs {
+				for _, sentinel := range sentinels {
+					want := false
+					for _, w := range tt.want {
+						want = want || w == sentinel
+					}
+					if got := errors.Is(err, sentinel); got != want {
+						t.Errorf("%s: errors.Is(%v) = %v, want %v", form, sentinel, got, want)
+					}
+				}
+			}
+
+			wantRetryable := false
+			for _, w := range tt.want {
+				wantRetryable = wantRetryable || w == ErrRetryable
+			}
+			if got := tt.exception.IsRetryable(); got != wantRetryable {
+				t.Errorf("IsRetryable() = %v, want %v", got, wantRetryable)
+			}
+		})
+	}
+}
+
+func TestAPIExceptionIsCode(t *testing.T) {
+	exception := newAPIException(404, APIErrorTransactionIDNotFound)
+	wrapped := fmt.Errorf("GetTransactionInfo: %w", exception)
+
+	if !errors.Is(wrapped, APIErrorTransactionIDNotFound) {
+		t.Error("doesn't match its own code")
+	}
+	if errors.Is(wrapped, APIErrorOriginalTransactionIDNotFound) {
+		t.Error("matches another code")
+	}
+	var apiErr *APIException
+	if !errors.As(wrapped, &apiErr) || apiErr != exception {
+		t.Error("errors.As doesn't find the exception")
+	}
+
+	// Without an error code in the response, no code matches, not even the zero one
+	if errors.Is(newAPIException(404, 0), APIError(0)) {
+		t.Error("an exception without an error code matches APIError(0)")
+	}
+}
+
+func TestAPIExceptionUnwrap(t *testing.T) {
+	cause := errors.New("response body too large")
+	exception := newAPIException(500, 0)
+	exception.Err = cause
+
+	if !errors.Is(exception, cause) {
+		t.Error("doesn't unwrap to the body error")
+	}
+	if !errors.Is(exception, ErrRetryable) {
+		t.Error("lost the status classification")
+	}
+	if got, want := exception.Error(), "API error with status code 500: response body too large"; got != want {
+		t.Errorf("Error() = %s, want %s", got, want)
+	}
+}
+
+func TestAPIExceptionError(t *testing.T) {
+	message := "Transaction id not found."
+	tests := []struct {
+		name      string
+		exception *APIException
+		want      string
+	}{
+		{name: "code and message", exception: &APIException{HTTPStatusCode: 404, APIError: APIErrorTransactionIDNotFound, RawAPIError: ptr(int(APIErrorTransactionIDNotFound)), ErrorMessage: &message}, want: "TransactionIdNotFoundError (4040010): Transaction id not found."},
+		{name: "message only", exception: &APIException{HTTPStatusCode: 404, ErrorMessage: &message}, want: "Transaction id not found."},
+		{name: "status only", exception: &APIException{HTTPStatusCode: 502}, want: "API error with status code 502"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.exception.Error(); got != tt.want {
+				t.Errorf("Error() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
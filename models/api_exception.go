@@ -3,7 +3,20 @@
 package models
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrRetryable matches, with errors.Is, API exceptions for requests that may succeed when retried
+	ErrRetryable = errors.New("retryable App Store Server API error")
+	// ErrNotFound matches, with errors.Is, API exceptions indicating that a requested resource doesn't exist
+	ErrNotFound = errors.New("App Store Server API resource not found")
+	// ErrRateLimited matches, with errors.Is, API exceptions indicating that the request exceeded the rate limit
+	ErrRateLimited = errors.New("App Store Server API rate limit exceeded")
+	// ErrInvalidRequest matches, with errors.Is, API exceptions indicating that the request itself is invalid
+	ErrInvalidRequest = errors.New("invalid App Store Server API request")
 )
 
 // APIException represents an exception returned by the App Store Server API
@@ -16,12 +29,84 @@ type APIException struct {
 	RawAPIError *int `json:"errorCode,omitempty"`
 	// ErrorMessage is the error message from the API response
 	ErrorMessage *string `json:"errorMessage,omitempty"`
+	// RequestMethod is the HTTP method of the failed request
+	RequestMethod string `json:"-"`
+	// RequestPath is the URL path of the failed request
+	RequestPath string `json:"-"`
+	// ResponseHeader contains the headers of the API response
+	ResponseHeader http.Header `json:"-"`
+	// RawBody is the unparsed body of the API response, truncated if it couldn't be read in full
+	RawBody []byte `json:"-"`
+	// Err is the error that stopped the response body from being read in full, such as a size limit being exceeded
+	Err error `json:"-"`
 }
 
 // Error implements the error interface for APIException
 func (e *APIException) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("API error with status code %d: %v", e.HTTPStatusCode, e.Err)
+	}
+	if e.RawAPIError != nil && e.ErrorMessage != nil {
+		return fmt.Sprintf("%s: %s", e.APIError.Error(), *e.ErrorMessage)
+	}
 	if e.ErrorMessage != nil {
 		return *e.ErrorMessage
 	}
 	return fmt.Sprintf("API error with status code %d", e.HTTPStatusCode)
 }
+
+// Unwrap returns the error that stopped the response body from being read, if any
+func (e *APIException) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the exception matches target, which may be one of the Err sentinels or an APIError code
+func (e *APIException) Is(target error) bool {
+	switch target {
+	case ErrRetryable:
+		return e.IsRetryable()
+	case ErrNotFound:
+		return e.IsNotFound()
+	case ErrRateLimited:
+		return e.IsRateLimited()
+	case ErrInvalidRequest:
+		return e.IsInvalidRequest()
+	}
+
+	if code, ok := target.(APIError); ok {
+		return e.RawAPIError != nil && e.APIError == code
+	}
+	return false
+}
+
+// IsRetryable reports whether the request may succeed when retried
+func (e *APIException) IsRetryable() bool {
+	if e.RawAPIError != nil && e.APIError.IsRetryable() {
+		return true
+	}
+	return e.HTTPStatusCode >= 500 || e.HTTPStatusCode == http.StatusTooManyRequests
+}
+
+// IsNotFound reports whether a requested resource doesn't exist
+func (e *APIException) IsNotFound() bool {
+	if e.RawAPIError != nil {
+		return e.APIError.IsNotFound()
+	}
+	return e.HTTPStatusCode == http.StatusNotFound
+}
+
+// IsRateLimited reports whether the request exceeded the rate limit
+func (e *APIException) IsRateLimited() bool {
+	if e.RawAPIError != nil {
+		return e.APIError.IsRateLimited()
+	}
+	return e.HTTPStatusCode == http.StatusTooManyRequests
+}
+
+// IsInvalidRequest reports whether the request itself is invalid
+func (e *APIException) IsInvalidRequest() bool {
+	if e.RawAPIError != nil {
+		return e.APIError.IsInvalidRequest()
+	}
+	return e.HTTPStatusCode == http.StatusBadRequest
+}
@@ -0,0 +1,11 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+// AppTransactionInfoResponse represents a response that contains the customer's signed app transaction
+// https://developer.apple.com/documentation/appstoreserverapi/apptransactioninforesponse
+type AppTransactionInfoResponse struct {
+	// SignedAppTransactionInfo is the customer's app transaction, signed by Apple, in JSON Web Signature (JWS) format
+	// https://developer.apple.com/documentation/appstoreserverapi/jwsapptransaction
+	SignedAppTransactionInfo *string `json:"signedAppTransactionInfo,omitempty"`
+}
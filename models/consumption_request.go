@@ -2,6 +2,8 @@
 
 package models
 
+import "github.com/DotNetAge/appstore/internal/apple"
+
 // ConsumptionRequest represents the request body containing consumption information.
 // https://developer.apple.com/documentation/appstoreserverapi/consumptionrequest
 type ConsumptionRequest struct {
@@ -30,3 +32,47 @@ type ConsumptionRequest struct {
 	// RefundPreference is a value that indicates your preference, based on your operational logic, as to whether Apple should grant the refund
 	RefundPreference *RefundPreference `json:"refundPreference,omitempty"`
 }
+
+// Validate checks the request before it is sent and returns a *ValidationError listing every violation
+func (r *ConsumptionRequest) Validate() error {
+	v := validator{request: "ConsumptionRequest"}
+	if r == nil {
+		return v.missing()
+	}
+	if v.required("customerConsented", r.CustomerConsented != nil) && !*r.CustomerConsented {
+		v.addf("customerConsented", "must be true; don't send consumption data without the customer's consent")
+	}
+	if v.required("consumptionStatus", r.ConsumptionStatus != nil) {
+		v.between("consumptionStatus", int(*r.ConsumptionStatus), int(ConsumptionStatusUndeclared), int(ConsumptionStatusFullyConsumed))
+	}
+	if v.required("platform", r.Platform != nil) {
+		v.between("platform", int(*r.Platform), int(PlatformUndeclared), int(PlatformNonApple))
+	}
+	v.required("sampleContentProvided", r.SampleContentProvided != nil)
+	if v.required("deliveryStatus", r.DeliveryStatus != nil) {
+		v.between("deliveryStatus", int(*r.DeliveryStatus), int(DeliveryStatusDeliveredAndWorkingProperly), int(DeliveryStatusDidNotDeliverForOtherReason))
+	}
+	// appAccountToken is required but may be empty when the purchase has no app account token
+	if v.required("appAccountToken", r.AppAccountToken != nil) && *r.AppAccountToken != "" && !apple.UUIDPattern.MatchString(*r.AppAccountToken) {
+		v.addf("appAccountToken", "must be a UUID or an empty string")
+	}
+	if v.required("accountTenure", r.AccountTenure != nil) {
+		v.between("accountTenure", int(*r.AccountTenure), int(AccountTenureUndeclared), int(AccountTenureGreaterThanThreeHundredSixtyFiveDays))
+	}
+	if v.required("playTime", r.PlayTime != nil) {
+		v.between("playTime", int(*r.PlayTime), int(PlayTimeUndeclared), int(PlayTimeOverSixteenDays))
+	}
+	if v.required("lifetimeDollarsRefunded", r.LifetimeDollarsRefunded != nil) {
+		v.between("lifetimeDollarsRefunded", int(*r.LifetimeDollarsRefunded), int(LifetimeDollarsRefundedUndeclared), int(LifetimeDollarsRefundedTwoThousandDollarsOrGreater))
+	}
+	if v.required("lifetimeDollarsPurchased", r.LifetimeDollarsPurchased != nil) {
+		v.between("lifetimeDollarsPurchased", int(*r.LifetimeDollarsPurchased), int(LifetimeDollarsPurchasedUndeclared), int(LifetimeDollarsPurchasedTwoThousandDollarsOrGreater))
+	}
+	if v.required("userStatus", r.UserStatus != nil) {
+		v.between("userStatus", int(*r.UserStatus), int(UserStatusUndeclared), int(UserStatusLimitedAccess))
+	}
+	if r.RefundPreference != nil {
+		v.between("refundPreference", int(*r.RefundPreference), int(RefundPreferenceUndeclared), int(RefundPreferenceNoPreference))
+	}
+	return v.err()
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+// MaxConsumptionPercentage is the consumption percentage, in milliunits, of a fully consumed in-app purchase
+const MaxConsumptionPercentage = 100000
+
+// ConsumptionRequestV2 represents the request body containing consumption information, for version 2 of the
+// Send Consumption Information endpoint.
+// https://developer.apple.com/documentation/appstoreserverapi/consumptionrequest
+type ConsumptionRequestV2 struct {
+	// CustomerConsented is a Boolean value that indicates whether the customer consented to provide consumption data to the App Store
+	CustomerConsented *bool `json:"customerConsented,omitempty"`
+	// ConsumptionPercentage is the percentage, in milliunits from 0 to 100000, of the in-app purchase the customer consumed
+	ConsumptionPercentage *int `json:"consumptionPercentage,omitempty"`
+	// DeliveryStatus is a value that indicates whether the app successfully delivered an in-app purchase that works properly
+	DeliveryStatus *DeliveryStatusV2 `json:"deliveryStatus,omitempty"`
+	// RefundPreference is a value that indicates your preference, based on your operational logic, as to whether Apple should grant the refund
+	RefundPreference *RefundPreferenceV2 `json:"refundPreference,omitempty"`
+	// SampleContentProvided is a Boolean value that indicates whether you provided, prior to its purchase, a free sample or trial of the content
+	SampleContentProvided *bool `json:"sampleContentProvided,omitempty"`
+}
+
+// Validate checks the request before it is sent and returns a *ValidationError listing every violation
+func (r *ConsumptionRequestV2) Validate() error {
+	v := validator{request: "ConsumptionRequestV2"}
+	if r == nil {
+		return v.missing()
+	}
+	if v.required("customerConsented", r.CustomerConsented != nil) && !*r.CustomerConsented {
+		v.addf("customerConsented", "must be true; don't send consumption data without the customer's consent")
+	}
+	if r.ConsumptionPercentage != nil {
+		v.between("consumptionPercentage", *r.ConsumptionPercentage, 0, MaxConsumptionPercentage)
+	}
+	if v.required("deliveryStatus", r.DeliveryStatus != nil) {
+		v.oneOf("deliveryStatus", string(*r.DeliveryStatus),
+			string(DeliveryStatusV2Delivered),
+			string(DeliveryStatusV2UndeliveredQualityIssue),
+			string(DeliveryStatusV2UndeliveredWrongItem),
+			string(DeliveryStatusV2UndeliveredServerOutage),
+			string(DeliveryStatusV2UndeliveredOther))
+	}
+	if r.RefundPreference != nil {
+		v.oneOf("refundPreference", string(*r.RefundPreference),
+			string(RefundPreferenceV2Decline),
+			string(RefundPreferenceV2GrantFull),
+			string(RefundPreferenceV2GrantProrated))
+	}
+	v.required("sampleContentProvided", r.SampleContentProvided != nil)
+	return v.err()
+}
@@ -0,0 +1,22 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+// DefaultConfigurationRequest represents the request body that sets the default retention message of a product and locale
+// https://developer.apple.com/documentation/retentionmessaging/defaultconfigurationrequest
+type DefaultConfigurationRequest struct {
+	// MessageIdentifier is the UUID of an approved message
+	MessageIdentifier *string `json:"messageIdentifier,omitempty"`
+}
+
+// Validate checks the request before it is sent and returns a *ValidationError listing every violation
+func (r *DefaultConfigurationRequest) Validate() error {
+	v := validator{request: "DefaultConfigurationRequest"}
+	if r == nil {
+		return v.missing()
+	}
+	if v.required("messageIdentifier", r.MessageIdentifier != nil) {
+		v.uuid("messageIdentifier", *r.MessageIdentifier)
+	}
+	return v.err()
+}
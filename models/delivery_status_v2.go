@@ -0,0 +1,21 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+// DeliveryStatusV2 represents whether the app successfully delivered an in-app purchase that works properly,
+// in version 2 of the Send Consumption Information endpoint.
+// https://developer.apple.com/documentation/appstoreserverapi/deliverystatus
+type DeliveryStatusV2 string
+
+const (
+	// DeliveryStatusV2Delivered indicates the app delivered the in-app purchase and it works properly
+	DeliveryStatusV2Delivered DeliveryStatusV2 = "DELIVERED"
+	// DeliveryStatusV2UndeliveredQualityIssue indicates the app didn't deliver the in-app purchase due to a quality issue
+	DeliveryStatusV2UndeliveredQualityIssue DeliveryStatusV2 = "UNDELIVERED_QUALITY_ISSUE"
+	// DeliveryStatusV2UndeliveredWrongItem indicates the app delivered the wrong item
+	DeliveryStatusV2UndeliveredWrongItem DeliveryStatusV2 = "UNDELIVERED_WRONG_ITEM"
+	// DeliveryStatusV2UndeliveredServerOutage indicates the app didn't deliver the in-app purchase due to a server outage
+	DeliveryStatusV2UndeliveredServerOutage DeliveryStatusV2 = "UNDELIVERED_SERVER_OUTAGE"
+	// DeliveryStatusV2UndeliveredOther indicates the app didn't deliver the in-app purchase for another reason
+	DeliveryStatusV2UndeliveredOther DeliveryStatusV2 = "UNDELIVERED_OTHER"
+)
@@ -18,3 +18,13 @@ type ExtendRenewalDateRequest struct {
 	// https://developer.apple.com/documentation/appstoreserverapi/requestidentifier
 	RequestIdentifier *string `json:"requestIdentifier,omitempty"`
 }
+
+// Validate checks the request before it is sent and returns a *ValidationError listing every violation
+func (r *ExtendRenewalDateRequest) Validate() error {
+	v := validator{request: "ExtendRenewalDateRequest"}
+	if r == nil {
+		return v.missing()
+	}
+	validateExtension(&v, r.ExtendByDays, r.ExtendReasonCode, r.RequestIdentifier)
+	return v.err()
+}
@@ -0,0 +1,19 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+// GetImageListResponse represents a response that contains the images uploaded to the Retention Messaging API
+// https://developer.apple.com/documentation/retentionmessaging/getimagelistresponse
+type GetImageListResponse struct {
+	// ImageIdentifiers lists the uploaded images and their approval states
+	ImageIdentifiers []GetImageListResponseItem `json:"imageIdentifiers,omitempty"`
+}
+
+// GetImageListResponseItem represents an uploaded image and its approval state
+// https://developer.apple.com/documentation/retentionmessaging/getimagelistresponseitem
+type GetImageListResponseItem struct {
+	// ImageIdentifier is the UUID that identifies the image
+	ImageIdentifier *string `json:"imageIdentifier,omitempty"`
+	// ImageState is the approval state of the image
+	ImageState *ImageState `json:"imageState,omitempty"`
+}
@@ -0,0 +1,19 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+// GetMessageListResponse represents a response that contains the messages uploaded to the Retention Messaging API
+// https://developer.apple.com/documentation/retentionmessaging/getmessagelistresponse
+type GetMessageListResponse struct {
+	// MessageIdentifiers lists the uploaded messages and their approval states
+	MessageIdentifiers []GetMessageListResponseItem `json:"messageIdentifiers,omitempty"`
+}
+
+// GetMessageListResponseItem represents an uploaded message and its approval state
+// https://developer.apple.com/documentation/retentionmessaging/getmessagelistresponseitem
+type GetMessageListResponseItem struct {
+	// MessageIdentifier is the UUID that identifies the message
+	MessageIdentifier *string `json:"messageIdentifier,omitempty"`
+	// MessageState is the approval state of the message
+	MessageState *MessageState `json:"messageState,omitempty"`
+}
@@ -0,0 +1,16 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+// ImageState is the approval state of an image uploaded to the Retention Messaging API
+// https://developer.apple.com/documentation/retentionmessaging/imagestate
+type ImageState string
+
+const (
+	// ImageStatePending indicates the image is awaiting approval
+	ImageStatePending ImageState = "PENDING"
+	// ImageStateApproved indicates the image is approved and can be used in messages
+	ImageStateApproved ImageState = "APPROVED"
+	// ImageStateRejected indicates the image was rejected
+	ImageStateRejected ImageState = "REJECTED"
+)
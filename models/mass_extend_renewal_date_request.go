@@ -2,6 +2,8 @@
 
 package models
 
+import "github.com/DotNetAge/appstore/internal/apple"
+
 // MassExtendRenewalDateRequest represents the request body that contains subscription-renewal-extension data to apply for all eligible active subscribers
 // https://developer.apple.com/documentation/appstoreserverapi/massextendrenewaldaterequest
 type MassExtendRenewalDateRequest struct {
@@ -26,3 +28,19 @@ type MassExtendRenewalDateRequest struct {
 	// https://developer.apple.com/documentation/appstoreserverapi/productid
 	ProductId *string `json:"productId,omitempty"`
 }
+
+// Validate checks the request before it is sent and returns a *ValidationError listing every violation
+func (r *MassExtendRenewalDateRequest) Validate() error {
+	v := validator{request: "MassExtendRenewalDateRequest"}
+	if r == nil {
+		return v.missing()
+	}
+	validateExtension(&v, r.ExtendByDays, r.ExtendReasonCode, r.RequestIdentifier)
+	v.required("productId", r.ProductId != nil && *r.ProductId != "")
+	for _, code := range r.StorefrontCountryCodes {
+		if !apple.StorefrontCountryCodePattern.MatchString(code) {
+			v.addf("storefrontCountryCodes", "must contain ISO 3166-1 alpha-3 country codes, got %q", code)
+		}
+	}
+	return v.err()
+}
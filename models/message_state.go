@@ -0,0 +1,16 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+// MessageState is the approval state of a message uploaded to the Retention Messaging API
+// https://developer.apple.com/documentation/retentionmessaging/messagestate
+type MessageState string
+
+const (
+	// MessageStatePending indicates the message is awaiting approval
+	MessageStatePending MessageState = "PENDING"
+	// MessageStateApproved indicates the message is approved and can be shown to customers
+	MessageStateApproved MessageState = "APPROVED"
+	// MessageStateRejected indicates the message was rejected
+	MessageStateRejected MessageState = "REJECTED"
+)
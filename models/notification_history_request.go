@@ -2,6 +2,8 @@
 
 package models
 
+import "time"
+
 // NotificationHistoryRequest represents the request body for notification history
 // https://developer.apple.com/documentation/appstoreserverapi/notificationhistoryrequest
 type NotificationHistoryRequest struct {
@@ -38,3 +40,32 @@ type NotificationHistoryRequest struct {
 	// https://developer.apple.com/documentation/appstoreserverapi/onlyfailures
 	OnlyFailures *bool `json:"onlyFailures,omitempty"`
 }
+
+// Validate checks the request before it is sent and returns a *ValidationError listing every violation
+func (r *NotificationHistoryRequest) Validate() error {
+	return r.validate(time.Now())
+}
+
+// validate checks the request against the notification history window ending at now
+func (r *NotificationHistoryRequest) validate(now time.Time) error {
+	v := validator{request: "NotificationHistoryRequest"}
+	if r == nil {
+		return v.missing()
+	}
+	hasStart := v.required("startDate", r.StartDate != nil)
+	v.required("endDate", r.EndDate != nil)
+	v.dateRange(r.StartDate, r.EndDate)
+	if oldest := now.AddDate(0, 0, -NotificationHistoryMaxAgeDays); hasStart && *r.StartDate < oldest.UnixMilli() {
+		v.addf("startDate", "must be within the past %d days", NotificationHistoryMaxAgeDays)
+	}
+	if r.TransactionId != nil {
+		v.transactionID("transactionId", *r.TransactionId)
+		if r.NotificationType != nil {
+			v.addf("transactionId", "can't be combined with notificationType")
+		}
+	}
+	if r.NotificationSubtype != nil && r.NotificationType == nil {
+		v.addf("notificationSubtype", "requires notificationType")
+	}
+	return v.err()
+}
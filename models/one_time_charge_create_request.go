@@ -0,0 +1,63 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+// OneTimeChargeCreateRequest represents the in-app request that charges a customer once for an item
+// https://developer.apple.com/documentation/advancedcommerceapi/onetimechargecreaterequest
+type OneTimeChargeCreateRequest struct {
+	// RequestInfo is the metadata of the request
+	RequestInfo *AdvancedCommerceRequestInfo `json:"requestInfo,omitempty"`
+
+	// Currency is the ISO 4217 code of the currency of the price
+	Currency *string `json:"currency,omitempty"`
+
+	// Item is the item being sold
+	Item *OneTimeChargeItem `json:"item,omitempty"`
+
+	// TaxCode is the App Store tax code of the item
+	TaxCode *string `json:"taxCode,omitempty"`
+
+	// Storefront is the ISO 3166-1 alpha-3 code of the storefront the price applies to
+	Storefront *string `json:"storefront,omitempty"`
+}
+
+// OneTimeChargeItem represents the item of a one-time charge
+// https://developer.apple.com/documentation/advancedcommerceapi/onetimechargeitem
+type OneTimeChargeItem struct {
+	// SKU is your identifier of the item
+	SKU *string `json:"SKU,omitempty"`
+
+	// Description is the description of the item
+	Description *string `json:"description,omitempty"`
+
+	// DisplayName is the name of the item
+	DisplayName *string `json:"displayName,omitempty"`
+
+	// Price is the price of the item, in milliunits of the currency
+	Price *int64 `json:"price,omitempty"`
+}
+
+// Operation returns the name of the operation the request performs
+func (r *OneTimeChargeCreateRequest) Operation() string {
+	return "CREATE_ONE_TIME_CHARGE"
+}
+
+// Validate checks the request before it is signed and returns a *ValidationError listing every violation
+func (r *OneTimeChargeCreateRequest) Validate() error {
+	v := validator{request: "OneTimeChargeCreateRequest"}
+	if r == nil {
+		return v.missing()
+	}
+	validateRequestInfo(&v, r.RequestInfo)
+	if v.required("currency", r.Currency != nil) {
+		validateCurrency(&v, "currency", *r.Currency)
+	}
+	if v.required("item", r.Item != nil) {
+		validateSKU(&v, "item.SKU", r.Item.SKU)
+		validateDescriptors(&v, "item.", r.Item.Description, r.Item.DisplayName)
+		validatePrice(&v, "item.price", r.Item.Price)
+	}
+	v.required("taxCode", r.TaxCode != nil && *r.TaxCode != "")
+	validateStorefront(&v, r.Storefront)
+	return v.err()
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+// RealtimeRequestBody represents the request body the App Store sends to your server to ask which retention message to show
+// https://developer.apple.com/documentation/retentionmessaging/realtimerequestbody
+type RealtimeRequestBody struct {
+	// SignedPayload is the request, signed by Apple, in JSON Web Signature (JWS) format
+	// https://developer.apple.com/documentation/retentionmessaging/signedpayload
+	SignedPayload *string `json:"signedPayload,omitempty"`
+}
+
+// DecodedRealtimeRequestBody represents the decoded payload of a real-time retention message request
+// https://developer.apple.com/documentation/retentionmessaging/decodedrealtimerequestbody
+type DecodedRealtimeRequestBody struct {
+	// OriginalTransactionId is the original transaction identifier of the subscription the customer is cancelling
+	// https://developer.apple.com/documentation/appstoreserverapi/originaltransactionid
+	OriginalTransactionId *string `json:"originalTransactionId,omitempty"`
+
+	// AppAppleId is the unique identifier of the app in the App Store
+	// https://developer.apple.com/documentation/appstoreserverapi/appappleid
+	AppAppleId *int64 `json:"appAppleId,omitempty"`
+
+	// ProductId is the product identifier of the subscription
+	// https://developer.apple.com/documentation/appstoreserverapi/productid
+	ProductId *string `json:"productId,omitempty"`
+
+	// UserLocale is the locale of the customer's device, such as "en-US"
+	// https://developer.apple.com/documentation/retentionmessaging/userlocale
+	UserLocale *string `json:"userLocale,omitempty"`
+
+	// RequestIdentifier is a UUID that identifies the request
+	// https://developer.apple.com/documentation/retentionmessaging/requestidentifier
+	RequestIdentifier *string `json:"requestIdentifier,omitempty"`
+
+	// Environment is the server environment, either sandbox or production
+	// https://developer.apple.com/documentation/appstoreserverapi/environment
+	Environment *Environment `json:"environment,omitempty"`
+
+	// SignedDate is the UNIX time, in milliseconds, that the App Store signed the JSON Web Signature data
+	// https://developer.apple.com/documentation/appstoreserverapi/signeddate
+	SignedDate *int64 `json:"signedDate,omitempty"`
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+// RealtimeResponseBody represents your server's answer to a real-time retention message request.
+// It contains at most one of Message, AlternateProduct and PromotionalOffer; an empty body shows the default message.
+// https://developer.apple.com/documentation/retentionmessaging/realtimeresponsebody
+type RealtimeResponseBody struct {
+	// Message is a retention message to show
+	// https://developer.apple.com/documentation/retentionmessaging/message
+	Message *RealtimeMessage `json:"message,omitempty"`
+
+	// AlternateProduct is a retention message that suggests switching to another subscription
+	// https://developer.apple.com/documentation/retentionmessaging/alternateproduct
+	AlternateProduct *AlternateProduct `json:"alternateProduct,omitempty"`
+
+	// PromotionalOffer is a retention message that presents a promotional offer
+	// https://developer.apple.com/documentation/retentionmessaging/promotionaloffer
+	PromotionalOffer *RealtimePromotionalOffer `json:"promotionalOffer,omitempty"`
+}
+
+// RealtimeMessage identifies an approved retention message
+// https://developer.apple.com/documentation/retentionmessaging/message
+type RealtimeMessage struct {
+	// MessageIdentifier is the UUID of an approved message
+	MessageIdentifier *string `json:"messageIdentifier,omitempty"`
+}
+
+// AlternateProduct identifies an approved retention message and the subscription it suggests
+// https://developer.apple.com/documentation/retentionmessaging/alternateproduct
+type AlternateProduct struct {
+	// MessageIdentifier is the UUID of an approved message
+	MessageIdentifier *string `json:"messageIdentifier,omitempty"`
+
+	// ProductId is the product identifier of the suggested subscription
+	ProductId *string `json:"productId,omitempty"`
+}
+
+// RealtimePromotionalOffer identifies an approved retention message and the signed promotional offer it presents
+// https://developer.apple.com/documentation/retentionmessaging/promotionaloffer
+type RealtimePromotionalOffer struct {
+	// MessageIdentifier is the UUID of an approved message
+	MessageIdentifier *string `json:"messageIdentifier,omitempty"`
+
+	// PromotionalOfferSignatureV2 is the compact JWS signature of the offer, as created for StoreKit's promotional offers
+	// https://developer.apple.com/documentation/retentionmessaging/promotionaloffersignaturev2
+	PromotionalOfferSignatureV2 *string `json:"promotionalOfferSignatureV2,omitempty"`
+}
+
+// Validate checks the response before your server sends it and returns a *ValidationError listing every violation
+func (r *RealtimeResponseBody) Validate() error {
+	v := validator{request: "RealtimeResponseBody"}
+	if r == nil {
+		return v.missing()
+	}
+
+	count := 0
+	if r.Message != nil {
+		count++
+		if v.required("message.messageIdentifier", r.Message.MessageIdentifier != nil) {
+			v.uuid("message.messageIdentifier", *r.Message.MessageIdentifier)
+		}
+	}
+	if r.AlternateProduct != nil {
+		count++
+		if v.required("alternateProduct.messageIdentifier", r.AlternateProduct.MessageIdentifier != nil) {
+			v.uuid("alternateProduct.messageIdentifier", *r.AlternateProduct.MessageIdentifier)
+		}
+		if v.required("alternateProduct.productId", r.AlternateProduct.ProductId != nil) && *r.AlternateProduct.ProductId == "" {
+			v.addf("alternateProduct.productId", "must not be empty")
+		}
+	}
+	if r.PromotionalOffer != nil {
+		count++
+		if v.required("promotionalOffer.messageIdentifier", r.PromotionalOffer.MessageIdentifier != nil) {
+			v.uuid("promotionalOffer.messageIdentifier", *r.PromotionalOffer.MessageIdentifier)
+		}
+		if v.required("promotionalOffer.promotionalOfferSignatureV2", r.PromotionalOffer.PromotionalOfferSignatureV2 != nil) && *r.PromotionalOffer.PromotionalOfferSignatureV2 == "" {
+			v.addf("promotionalOffer.promotionalOfferSignatureV2", "must not be empty")
+		}
+	}
+	if count > 1 {
+		v.addf("message", "message, alternateProduct and promotionalOffer are mutually exclusive")
+	}
+	return v.err()
+}
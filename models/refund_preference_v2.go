@@ -0,0 +1,17 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+// RefundPreferenceV2 represents your preferred outcome for the refund request, in version 2 of the Send
+// Consumption Information endpoint.
+// https://developer.apple.com/documentation/appstoreserverapi/refundpreference
+type RefundPreferenceV2 string
+
+const (
+	// RefundPreferenceV2Decline indicates you prefer that Apple declines the refund request
+	RefundPreferenceV2Decline RefundPreferenceV2 = "DECLINE"
+	// RefundPreferenceV2GrantFull indicates you prefer that Apple grants a full refund
+	RefundPreferenceV2GrantFull RefundPreferenceV2 = "GRANT_FULL"
+	// RefundPreferenceV2GrantProrated indicates you prefer that Apple grants a prorated refund
+	RefundPreferenceV2GrantProrated RefundPreferenceV2 = "GRANT_PRORATED"
+)
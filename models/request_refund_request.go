@@ -0,0 +1,81 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+import "fmt"
+
+// RequestRefundRequest represents the request body that refunds all or part of an Advanced Commerce transaction
+// https://developer.apple.com/documentation/advancedcommerceapi/requestrefundrequest
+type RequestRefundRequest struct {
+	// RequestInfo is the metadata of the request
+	RequestInfo *AdvancedCommerceRequestInfo `json:"requestInfo,omitempty"`
+
+	// Items are the items to refund
+	Items []RequestRefundItem `json:"items,omitempty"`
+
+	// RefundRiskingPreference indicates whether you accept the risk of refunding without Apple's fraud checks
+	RefundRiskingPreference *bool `json:"refundRiskingPreference,omitempty"`
+
+	// Currency is the ISO 4217 code of the currency of the refund amounts
+	Currency *string `json:"currency,omitempty"`
+
+	// Storefront is the ISO 3166-1 alpha-3 code of the storefront of the transaction
+	Storefront *string `json:"storefront,omitempty"`
+}
+
+// RequestRefundItem represents an item of a refund
+// https://developer.apple.com/documentation/advancedcommerceapi/requestrefunditem
+type RequestRefundItem struct {
+	// SKU is your identifier of the item
+	SKU *string `json:"SKU,omitempty"`
+
+	// RefundAmount is the amount to refund, in milliunits of the currency; required for CUSTOM refunds
+	RefundAmount *int64 `json:"refundAmount,omitempty"`
+
+	// RefundReason is why the item is refunded
+	RefundReason *AdvancedCommerceRefundReason `json:"refundReason,omitempty"`
+
+	// RefundType is how much of the item is refunded
+	RefundType *AdvancedCommerceRefundType `json:"refundType,omitempty"`
+
+	// Revoke indicates whether the customer loses access to the item
+	Revoke *bool `json:"revoke,omitempty"`
+}
+
+// RequestRefundResponse represents the response to a refund request
+// https://developer.apple.com/documentation/advancedcommerceapi/requestrefundresponse
+type RequestRefundResponse struct {
+	// SignedTransactionInfo is the refunded transaction, signed by Apple, in JSON Web Signature (JWS) format
+	// https://developer.apple.com/documentation/appstoreserverapi/jwstransaction
+	SignedTransactionInfo *string `json:"signedTransactionInfo,omitempty"`
+}
+
+// Validate checks the request before it is sent and returns a *ValidationError listing every violation
+func (r *RequestRefundRequest) Validate() error {
+	v := validator{request: "RequestRefundRequest"}
+	if r == nil {
+		return v.missing()
+	}
+	validateRequestInfo(&v, r.RequestInfo)
+	custom := false
+	if v.required("items", len(r.Items) > 0) {
+		for i, item := range r.Items {
+			field := fmt.Sprintf("items[%d]", i)
+			validateSKU(&v, field+".SKU", item.SKU)
+			validateRefund(&v, field+".", item.RefundReason, item.RefundType)
+			if item.RefundType != nil && *item.RefundType == AdvancedCommerceRefundTypeCustom {
+				custom = true
+				validatePrice(&v, field+".refundAmount", item.RefundAmount)
+			}
+			v.required(field+".revoke", item.Revoke != nil)
+		}
+	}
+	v.required("refundRiskingPreference", r.RefundRiskingPreference != nil)
+	if r.Currency != nil {
+		validateCurrency(&v, "currency", *r.Currency)
+	} else if custom {
+		v.addf("currency", "is required for CUSTOM refunds")
+	}
+	validateStorefront(&v, r.Storefront)
+	return v.err()
+}
@@ -0,0 +1,28 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+// SubscriptionCancelRequest represents the request body that turns off the automatic renewal of a subscription
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptioncancelrequest
+type SubscriptionCancelRequest struct {
+	// RequestInfo is the metadata of the request
+	RequestInfo *AdvancedCommerceRequestInfo `json:"requestInfo,omitempty"`
+
+	// Storefront is the ISO 3166-1 alpha-3 code of the storefront of the subscription
+	Storefront *string `json:"storefront,omitempty"`
+}
+
+// SubscriptionCancelResponse represents the response to a request that cancels a subscription
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptioncancelresponse
+type SubscriptionCancelResponse AdvancedCommerceSubscriptionResponse
+
+// Validate checks the request before it is sent and returns a *ValidationError listing every violation
+func (r *SubscriptionCancelRequest) Validate() error {
+	v := validator{request: "SubscriptionCancelRequest"}
+	if r == nil {
+		return v.missing()
+	}
+	validateRequestInfo(&v, r.RequestInfo)
+	validateStorefront(&v, r.Storefront)
+	return v.err()
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+import "fmt"
+
+// SubscriptionChangeMetadataRequest represents the request body that changes the names, descriptions, SKUs or tax
+// code of a subscription without changing its price
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptionchangemetadatarequest
+type SubscriptionChangeMetadataRequest struct {
+	// RequestInfo is the metadata of the request
+	RequestInfo *AdvancedCommerceRequestInfo `json:"requestInfo,omitempty"`
+
+	// Descriptors are the new name and description of the subscription
+	Descriptors *SubscriptionChangeMetadataDescriptors `json:"descriptors,omitempty"`
+
+	// Items are the items whose metadata changes
+	Items []SubscriptionChangeMetadataItem `json:"items,omitempty"`
+
+	// TaxCode is the new App Store tax code of the subscription
+	TaxCode *string `json:"taxCode,omitempty"`
+
+	// Storefront is the ISO 3166-1 alpha-3 code of the storefront of the subscription
+	Storefront *string `json:"storefront,omitempty"`
+}
+
+// SubscriptionChangeMetadataDescriptors represents the new name and description of a subscription
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptionchangemetadatadescriptors
+type SubscriptionChangeMetadataDescriptors struct {
+	// Description is the new description of the subscription
+	Description *string `json:"description,omitempty"`
+
+	// DisplayName is the new name of the subscription
+	DisplayName *string `json:"displayName,omitempty"`
+
+	// Effective is when the change takes effect
+	Effective *AdvancedCommerceEffective `json:"effective,omitempty"`
+}
+
+// SubscriptionChangeMetadataItem represents the new metadata of an item of a subscription
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptionchangemetadataitem
+type SubscriptionChangeMetadataItem struct {
+	// CurrentSKU is your identifier of the item
+	CurrentSKU *string `json:"currentSKU,omitempty"`
+
+	// SKU is your new identifier of the item
+	SKU *string `json:"SKU,omitempty"`
+
+	// Description is the new description of the item
+	Description *string `json:"description,omitempty"`
+
+	// DisplayName is the new name of the item
+	DisplayName *string `json:"displayName,omitempty"`
+
+	// Effective is when the change takes effect
+	Effective *AdvancedCommerceEffective `json:"effective,omitempty"`
+}
+
+// SubscriptionChangeMetadataResponse represents the response to a request that changes the metadata of a subscription
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptionchangemetadataresponse
+type SubscriptionChangeMetadataResponse AdvancedCommerceSubscriptionResponse
+
+// Validate checks the request before it is sent and returns a *ValidationError listing every violation
+func (r *SubscriptionChangeMetadataRequest) Validate() error {
+	v := validator{request: "SubscriptionChangeMetadataRequest"}
+	if r == nil {
+		return v.missing()
+	}
+	validateRequestInfo(&v, r.RequestInfo)
+	if d := r.Descriptors; d != nil {
+		if d.Description != nil {
+			v.maxLength("descriptors.description", *d.Description, MaxAdvancedCommerceDescriptionLength)
+		}
+		if d.DisplayName != nil {
+			v.maxLength("descriptors.displayName", *d.DisplayName, MaxAdvancedCommerceDisplayNameLength)
+		}
+		if v.required("descriptors.effective", d.Effective != nil) {
+			validateEffective(&v, "descriptors.effective", *d.Effective)
+		}
+	}
+	for i, item := range r.Items {
+		field := fmt.Sprintf("items[%d]", i)
+		validateSKU(&v, field+".currentSKU", item.CurrentSKU)
+		if item.SKU != nil {
+			validateSKU(&v, field+".SKU", item.SKU)
+		}
+		if item.Description != nil {
+			v.maxLength(field+".description", *item.Description, MaxAdvancedCommerceDescriptionLength)
+		}
+		if item.DisplayName != nil {
+			v.maxLength(field+".displayName", *item.DisplayName, MaxAdvancedCommerceDisplayNameLength)
+		}
+		if v.required(field+".effective", item.Effective != nil) {
+			validateEffective(&v, field+".effective", *item.Effective)
+		}
+	}
+	if r.Descriptors == nil && len(r.Items) == 0 && r.TaxCode == nil {
+		v.addf("request", "must change the descriptors, the items or the tax code")
+	}
+	validateStorefront(&v, r.Storefront)
+	return v.err()
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+import "fmt"
+
+// SubscriptionCreateRequest represents the in-app request that starts a subscription made of one or more items
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptioncreaterequest
+type SubscriptionCreateRequest struct {
+	// RequestInfo is the metadata of the request
+	RequestInfo *AdvancedCommerceRequestInfo `json:"requestInfo,omitempty"`
+
+	// Currency is the ISO 4217 code of the currency of the prices
+	Currency *string `json:"currency,omitempty"`
+
+	// Descriptors are the name and description of the subscription
+	Descriptors *AdvancedCommerceDescriptors `json:"descriptors,omitempty"`
+
+	// Items are the items of the subscription
+	Items []SubscriptionCreateItem `json:"items,omitempty"`
+
+	// Period is the renewal period of the subscription
+	Period *AdvancedCommercePeriod `json:"period,omitempty"`
+
+	// PreviousTransactionId is the transaction identifier of an earlier subscription the customer is resubscribing to
+	PreviousTransactionId *string `json:"previousTransactionId,omitempty"`
+
+	// TaxCode is the App Store tax code of the subscription
+	TaxCode *string `json:"taxCode,omitempty"`
+
+	// Storefront is the ISO 3166-1 alpha-3 code of the storefront the prices apply to
+	Storefront *string `json:"storefront,omitempty"`
+}
+
+// SubscriptionCreateItem represents an item of a new subscription
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptioncreateitem
+type SubscriptionCreateItem struct {
+	// SKU is your identifier of the item
+	SKU *string `json:"SKU,omitempty"`
+
+	// Description is the description of the item
+	Description *string `json:"description,omitempty"`
+
+	// DisplayName is the name of the item
+	DisplayName *string `json:"displayName,omitempty"`
+
+	// Offer is an optional discount on the first periods
+	Offer *AdvancedCommerceOffer `json:"offer,omitempty"`
+
+	// Price is the price of the item per period, in milliunits of the currency
+	Price *int64 `json:"price,omitempty"`
+}
+
+// Operation returns the name of the operation the request performs
+func (r *SubscriptionCreateRequest) Operation() string {
+	return "CREATE_SUBSCRIPTION"
+}
+
+// Validate checks the request before it is signed and returns a *ValidationError listing every violation
+func (r *SubscriptionCreateRequest) Validate() error {
+	v := validator{request: "SubscriptionCreateRequest"}
+	if r == nil {
+		return v.missing()
+	}
+	validateRequestInfo(&v, r.RequestInfo)
+	if v.required("currency", r.Currency != nil) {
+		validateCurrency(&v, "currency", *r.Currency)
+	}
+	if v.required("descriptors", r.Descriptors != nil) {
+		validateDescriptors(&v, "descriptors.", r.Descriptors.Description, r.Descriptors.DisplayName)
+	}
+	if v.required("items", len(r.Items) > 0) {
+		for i, item := range r.Items {
+			field := fmt.Sprintf("items[%d]", i)
+			validateSKU(&v, field+".SKU", item.SKU)
+			validateDescriptors(&v, field+".", item.Description, item.DisplayName)
+			validateOffer(&v, field+".offer", item.Offer)
+			validatePrice(&v, field+".price", item.Price)
+		}
+	}
+	if v.required("period", r.Period != nil) {
+		validatePeriod(&v, "period", *r.Period)
+	}
+	if r.PreviousTransactionId != nil {
+		v.transactionID("previousTransactionId", *r.PreviousTransactionId)
+	}
+	v.required("taxCode", r.TaxCode != nil && *r.TaxCode != "")
+	validateStorefront(&v, r.Storefront)
+	return v.err()
+}
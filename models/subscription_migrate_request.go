@@ -0,0 +1,68 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+import "fmt"
+
+// SubscriptionMigrateRequest represents the request body that moves a subscription from an auto-renewable
+// subscription product onto the Advanced Commerce API
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptionmigraterequest
+type SubscriptionMigrateRequest struct {
+	// RequestInfo is the metadata of the request
+	RequestInfo *AdvancedCommerceRequestInfo `json:"requestInfo,omitempty"`
+
+	// Descriptors are the name and description of the migrated subscription
+	Descriptors *AdvancedCommerceDescriptors `json:"descriptors,omitempty"`
+
+	// Items are the items of the migrated subscription
+	Items []SubscriptionMigrateItem `json:"items,omitempty"`
+
+	// TargetProductId is the product identifier of the Advanced Commerce subscription to migrate to
+	TargetProductId *string `json:"targetProductId,omitempty"`
+
+	// TaxCode is the App Store tax code of the migrated subscription
+	TaxCode *string `json:"taxCode,omitempty"`
+
+	// Storefront is the ISO 3166-1 alpha-3 code of the storefront of the subscription
+	Storefront *string `json:"storefront,omitempty"`
+}
+
+// SubscriptionMigrateItem represents an item of a migrated subscription
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptionmigrateitem
+type SubscriptionMigrateItem struct {
+	// SKU is your identifier of the item
+	SKU *string `json:"SKU,omitempty"`
+
+	// Description is the description of the item
+	Description *string `json:"description,omitempty"`
+
+	// DisplayName is the name of the item
+	DisplayName *string `json:"displayName,omitempty"`
+}
+
+// SubscriptionMigrateResponse represents the response to a request that migrates a subscription
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptionmigrateresponse
+type SubscriptionMigrateResponse AdvancedCommerceSubscriptionResponse
+
+// Validate checks the request before it is sent and returns a *ValidationError listing every violation
+func (r *SubscriptionMigrateRequest) Validate() error {
+	v := validator{request: "SubscriptionMigrateRequest"}
+	if r == nil {
+		return v.missing()
+	}
+	validateRequestInfo(&v, r.RequestInfo)
+	if v.required("descriptors", r.Descriptors != nil) {
+		validateDescriptors(&v, "descriptors.", r.Descriptors.Description, r.Descriptors.DisplayName)
+	}
+	if v.required("items", len(r.Items) > 0) {
+		for i, item := range r.Items {
+			field := fmt.Sprintf("items[%d]", i)
+			validateSKU(&v, field+".SKU", item.SKU)
+			validateDescriptors(&v, field+".", item.Description, item.DisplayName)
+		}
+	}
+	v.required("targetProductId", r.TargetProductId != nil && *r.TargetProductId != "")
+	v.required("taxCode", r.TaxCode != nil && *r.TaxCode != "")
+	validateStorefront(&v, r.Storefront)
+	return v.err()
+}
@@ -0,0 +1,210 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+import "fmt"
+
+// SubscriptionModifyInAppRequest represents the in-app request that adds, changes or removes the items of a
+// subscription, or changes its period or descriptors
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptionmodifyinapprequest
+type SubscriptionModifyInAppRequest struct {
+	// RequestInfo is the metadata of the request
+	RequestInfo *AdvancedCommerceRequestInfo `json:"requestInfo,omitempty"`
+
+	// TransactionId is the transaction identifier of the subscription
+	TransactionId *string `json:"transactionId,omitempty"`
+
+	// AddItems are the items to add to the subscription
+	AddItems []SubscriptionModifyAddItem `json:"addItems,omitempty"`
+
+	// ChangeItems are the items of the subscription to replace
+	ChangeItems []SubscriptionModifyChangeItem `json:"changeItems,omitempty"`
+
+	// RemoveItems are the items to remove from the subscription
+	RemoveItems []SubscriptionModifyRemoveItem `json:"removeItems,omitempty"`
+
+	// Currency is the ISO 4217 code of the currency of the prices
+	Currency *string `json:"currency,omitempty"`
+
+	// Descriptors are the new name and description of the subscription
+	Descriptors *SubscriptionModifyDescriptors `json:"descriptors,omitempty"`
+
+	// PeriodChange is the new renewal period of the subscription
+	PeriodChange *SubscriptionModifyPeriodChange `json:"periodChange,omitempty"`
+
+	// RetainBillingCycle indicates whether the subscription keeps its renewal date
+	RetainBillingCycle *bool `json:"retainBillingCycle,omitempty"`
+
+	// TaxCode is the App Store tax code of the subscription
+	TaxCode *string `json:"taxCode,omitempty"`
+
+	// Storefront is the ISO 3166-1 alpha-3 code of the storefront the prices apply to
+	Storefront *string `json:"storefront,omitempty"`
+}
+
+// SubscriptionModifyAddItem represents an item added to a subscription
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptionmodifyadditem
+type SubscriptionModifyAddItem struct {
+	// SKU is your identifier of the item
+	SKU *string `json:"SKU,omitempty"`
+
+	// Description is the description of the item
+	Description *string `json:"description,omitempty"`
+
+	// DisplayName is the name of the item
+	DisplayName *string `json:"displayName,omitempty"`
+
+	// Offer is an optional discount on the first periods
+	Offer *AdvancedCommerceOffer `json:"offer,omitempty"`
+
+	// Price is the price of the item per period, in milliunits of the currency
+	Price *int64 `json:"price,omitempty"`
+
+	// ProratedPrice is the price charged for the rest of the current period, in milliunits of the currency
+	ProratedPrice *int64 `json:"proratedPrice,omitempty"`
+}
+
+// SubscriptionModifyChangeItem represents an item of a subscription replaced by another
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptionmodifychangeitem
+type SubscriptionModifyChangeItem struct {
+	// SKU is your identifier of the new item
+	SKU *string `json:"SKU,omitempty"`
+
+	// CurrentSKU is your identifier of the item being replaced
+	CurrentSKU *string `json:"currentSKU,omitempty"`
+
+	// Description is the description of the new item
+	Description *string `json:"description,omitempty"`
+
+	// DisplayName is the name of the new item
+	DisplayName *string `json:"displayName,omitempty"`
+
+	// Effective is when the change takes effect
+	Effective *AdvancedCommerceEffective `json:"effective,omitempty"`
+
+	// Offer is an optional discount on the first periods
+	Offer *AdvancedCommerceOffer `json:"offer,omitempty"`
+
+	// Price is the price of the new item per period, in milliunits of the currency
+	Price *int64 `json:"price,omitempty"`
+
+	// ProratedPrice is the price charged for the rest of the current period, in milliunits of the currency
+	ProratedPrice *int64 `json:"proratedPrice,omitempty"`
+
+	// Reason is why the item changes
+	Reason *SubscriptionModifyChangeReason `json:"reason,omitempty"`
+}
+
+// SubscriptionModifyChangeReason represents why an item of a subscription changes
+// https://developer.apple.com/documentation/advancedcommerceapi/reason
+type SubscriptionModifyChangeReason string
+
+const (
+	// SubscriptionModifyChangeReasonUpgrade indicates the customer moves to a better item
+	SubscriptionModifyChangeReasonUpgrade SubscriptionModifyChangeReason = "UPGRADE"
+	// SubscriptionModifyChangeReasonDowngrade indicates the customer moves to a lesser item
+	SubscriptionModifyChangeReasonDowngrade SubscriptionModifyChangeReason = "DOWNGRADE"
+	// SubscriptionModifyChangeReasonApplyOffer indicates the item stays the same but gets an offer
+	SubscriptionModifyChangeReasonApplyOffer SubscriptionModifyChangeReason = "APPLY_OFFER"
+)
+
+// SubscriptionModifyRemoveItem represents an item removed from a subscription
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptionmodifyremoveitem
+type SubscriptionModifyRemoveItem struct {
+	// SKU is your identifier of the item
+	SKU *string `json:"SKU,omitempty"`
+}
+
+// SubscriptionModifyDescriptors represents the new name and description of a subscription
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptionmodifydescriptors
+type SubscriptionModifyDescriptors struct {
+	// Description is the new description of the subscription
+	Description *string `json:"description,omitempty"`
+
+	// DisplayName is the new name of the subscription
+	DisplayName *string `json:"displayName,omitempty"`
+
+	// Effective is when the change takes effect
+	Effective *AdvancedCommerceEffective `json:"effective,omitempty"`
+}
+
+// SubscriptionModifyPeriodChange represents a new renewal period of a subscription
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptionmodifyperiodchange
+type SubscriptionModifyPeriodChange struct {
+	// Effective is when the change takes effect
+	Effective *AdvancedCommerceEffective `json:"effective,omitempty"`
+
+	// Period is the new renewal period
+	Period *AdvancedCommercePeriod `json:"period,omitempty"`
+}
+
+// Operation returns the name of the operation the request performs
+func (r *SubscriptionModifyInAppRequest) Operation() string {
+	return "MODIFY_SUBSCRIPTION"
+}
+
+// Validate checks the request before it is signed and returns a *ValidationError listing every violation
+func (r *SubscriptionModifyInAppRequest) Validate() error {
+	v := validator{request: "SubscriptionModifyInAppRequest"}
+	if r == nil {
+		return v.missing()
+	}
+	validateRequestInfo(&v, r.RequestInfo)
+	if v.required("transactionId", r.TransactionId != nil) {
+		v.transactionID("transactionId", *r.TransactionId)
+	}
+	for i, item := range r.AddItems {
+		field := fmt.Sprintf("addItems[%d]", i)
+		validateSKU(&v, field+".SKU", item.SKU)
+		validateDescriptors(&v, field+".", item.Description, item.DisplayName)
+		validateOffer(&v, field+".offer", item.Offer)
+		validatePrice(&v, field+".price", item.Price)
+	}
+	for i, item := range r.ChangeItems {
+		field := fmt.Sprintf("changeItems[%d]", i)
+		validateSKU(&v, field+".SKU", item.SKU)
+		validateSKU(&v, field+".currentSKU", item.CurrentSKU)
+		validateDescriptors(&v, field+".", item.Description, item.DisplayName)
+		if v.required(field+".effective", item.Effective != nil) {
+			validateEffective(&v, field+".effective", *item.Effective)
+		}
+		validateOffer(&v, field+".offer", item.Offer)
+		validatePrice(&v, field+".price", item.Price)
+		if v.required(field+".reason", item.Reason != nil) {
+			v.oneOf(field+".reason", string(*item.Reason),
+				string(SubscriptionModifyChangeReasonUpgrade),
+				string(SubscriptionModifyChangeReasonDowngrade),
+				string(SubscriptionModifyChangeReasonApplyOffer))
+		}
+	}
+	for i, item := range r.RemoveItems {
+		validateSKU(&v, fmt.Sprintf("removeItems[%d].SKU", i), item.SKU)
+	}
+	if r.Currency != nil {
+		validateCurrency(&v, "currency", *r.Currency)
+	} else if len(r.AddItems) > 0 || len(r.ChangeItems) > 0 {
+		v.addf("currency", "is required when items are added or changed")
+	}
+	if d := r.Descriptors; d != nil {
+		if d.Description != nil {
+			v.maxLength("descriptors.description", *d.Description, MaxAdvancedCommerceDescriptionLength)
+		}
+		if d.DisplayName != nil {
+			v.maxLength("descriptors.displayName", *d.DisplayName, MaxAdvancedCommerceDisplayNameLength)
+		}
+		if v.required("descriptors.effective", d.Effective != nil) {
+			validateEffective(&v, "descriptors.effective", *d.Effective)
+		}
+	}
+	if p := r.PeriodChange; p != nil {
+		if v.required("periodChange.effective", p.Effective != nil) {
+			validateEffective(&v, "periodChange.effective", *p.Effective)
+		}
+		if v.required("periodChange.period", p.Period != nil) {
+			validatePeriod(&v, "periodChange.period", *p.Period)
+		}
+	}
+	v.required("retainBillingCycle", r.RetainBillingCycle != nil)
+	validateStorefront(&v, r.Storefront)
+	return v.err()
+}
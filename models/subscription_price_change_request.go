@@ -0,0 +1,56 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+import "fmt"
+
+// SubscriptionPriceChangeRequest represents the request body that changes the prices of the items of a subscription
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptionpricechangerequest
+type SubscriptionPriceChangeRequest struct {
+	// RequestInfo is the metadata of the request
+	RequestInfo *AdvancedCommerceRequestInfo `json:"requestInfo,omitempty"`
+
+	// Items are the items whose price changes
+	Items []SubscriptionPriceChangeItem `json:"items,omitempty"`
+
+	// Storefront is the ISO 3166-1 alpha-3 code of the storefront of the subscription
+	Storefront *string `json:"storefront,omitempty"`
+}
+
+// SubscriptionPriceChangeItem represents the new price of an item of a subscription
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptionpricechangeitem
+type SubscriptionPriceChangeItem struct {
+	// SKU is your identifier of the item
+	SKU *string `json:"SKU,omitempty"`
+
+	// DependentSKUs are the SKUs of items whose price depends on this one
+	DependentSKUs []string `json:"dependentSKUs,omitempty"`
+
+	// Price is the new price of the item per period, in milliunits of the currency
+	Price *int64 `json:"price,omitempty"`
+}
+
+// SubscriptionPriceChangeResponse represents the response to a request that changes the price of a subscription
+// https://developer.apple.com/documentation/advancedcommerceapi/subscriptionpricechangeresponse
+type SubscriptionPriceChangeResponse AdvancedCommerceSubscriptionResponse
+
+// Validate checks the request before it is sent and returns a *ValidationError listing every violation
+func (r *SubscriptionPriceChangeRequest) Validate() error {
+	v := validator{request: "SubscriptionPriceChangeRequest"}
+	if r == nil {
+		return v.missing()
+	}
+	validateRequestInfo(&v, r.RequestInfo)
+	if v.required("items", len(r.Items) > 0) {
+		for i, item := range r.Items {
+			field := fmt.Sprintf("items[%d]", i)
+			validateSKU(&v, field+".SKU", item.SKU)
+			for j := range item.DependentSKUs {
+				validateSKU(&v, fmt.Sprintf("%s.dependentSKUs[%d]", field, j), &item.DependentSKUs[j])
+			}
+			validatePrice(&v, field+".price", item.Price)
+		}
+	}
+	validateStorefront(&v, r.Storefront)
+	return v.err()
+}
@@ -68,3 +68,26 @@ type TransactionHistoryRequest struct {
 	// By default, the request doesn't include this parameter
 	Revoked *bool `json:"revoked,omitempty"`
 }
+
+// Validate checks the request before it is sent and returns a *ValidationError listing every violation
+func (r *TransactionHistoryRequest) Validate() error {
+	if r == nil {
+		return nil
+	}
+	v := validator{request: "TransactionHistoryRequest"}
+	v.dateRange(r.StartDate, r.EndDate)
+	for _, productType := range r.ProductTypes {
+		switch productType {
+		case ProductTypeAutoRenewable, ProductTypeNonRenewable, ProductTypeConsumable, ProductTypeNonConsumable:
+		default:
+			v.addf("productType", "unknown product type %q", productType)
+		}
+	}
+	if r.Sort != nil && *r.Sort != OrderAscending && *r.Sort != OrderDescending {
+		v.addf("sort", "must be ASCENDING or DESCENDING, got %q", *r.Sort)
+	}
+	if r.InAppOwnershipType != nil && *r.InAppOwnershipType != InAppOwnershipTypeFamilyShared && *r.InAppOwnershipType != InAppOwnershipTypePurchased {
+		v.addf("inAppOwnershipType", "must be FAMILY_SHARED or PURCHASED, got %q", *r.InAppOwnershipType)
+	}
+	return v.err()
+}
@@ -0,0 +1,25 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+import "github.com/DotNetAge/appstore/internal/apple"
+
+// UpdateAppAccountTokenRequest represents the request body that sets the app account token of a purchase
+// https://developer.apple.com/documentation/appstoreserverapi/updateappaccounttokenrequest
+type UpdateAppAccountTokenRequest struct {
+	// AppAccountToken is the UUID that an app optionally generates to map a customer's in-app purchase with its resulting App Store transaction
+	// https://developer.apple.com/documentation/appstoreserverapi/appaccounttoken
+	AppAccountToken *string `json:"appAccountToken,omitempty"`
+}
+
+// Validate checks the request before it is sent and returns a *ValidationError listing every violation
+func (r *UpdateAppAccountTokenRequest) Validate() error {
+	v := validator{request: "UpdateAppAccountTokenRequest"}
+	if r == nil {
+		return v.missing()
+	}
+	if v.required("appAccountToken", r.AppAccountToken != nil) && !apple.UUIDPattern.MatchString(*r.AppAccountToken) {
+		v.addf("appAccountToken", "must be a UUID")
+	}
+	return v.err()
+}
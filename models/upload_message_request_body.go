@@ -0,0 +1,55 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+const (
+	// MaxRetentionMessageHeaderLength is the maximum length, in characters, of a retention message header
+	MaxRetentionMessageHeaderLength = 66
+	// MaxRetentionMessageBodyLength is the maximum length, in characters, of a retention message body
+	MaxRetentionMessageBodyLength = 144
+	// MaxRetentionImageAltTextLength is the maximum length, in characters, of the alternative text of a retention message image
+	MaxRetentionImageAltTextLength = 150
+)
+
+// UploadMessageRequestBody represents the request body that uploads a retention message
+// https://developer.apple.com/documentation/retentionmessaging/uploadmessagerequestbody
+type UploadMessageRequestBody struct {
+	// Header is the header text of the message
+	Header *string `json:"header,omitempty"`
+	// Body is the body text of the message
+	Body *string `json:"body,omitempty"`
+	// Image is the optional image shown with the message
+	Image *UploadMessageImage `json:"image,omitempty"`
+}
+
+// UploadMessageImage represents an uploaded image to show with a retention message
+// https://developer.apple.com/documentation/retentionmessaging/uploadmessageimage
+type UploadMessageImage struct {
+	// ImageIdentifier is the UUID of an uploaded image
+	ImageIdentifier *string `json:"imageIdentifier,omitempty"`
+	// AltText is the alternative text that describes the image for accessibility
+	AltText *string `json:"altText,omitempty"`
+}
+
+// Validate checks the request before it is sent and returns a *ValidationError listing every violation
+func (r *UploadMessageRequestBody) Validate() error {
+	v := validator{request: "UploadMessageRequestBody"}
+	if r == nil {
+		return v.missing()
+	}
+	if v.required("header", r.Header != nil && *r.Header != "") {
+		v.maxLength("header", *r.Header, MaxRetentionMessageHeaderLength)
+	}
+	if v.required("body", r.Body != nil && *r.Body != "") {
+		v.maxLength("body", *r.Body, MaxRetentionMessageBodyLength)
+	}
+	if r.Image != nil {
+		if v.required("image.imageIdentifier", r.Image.ImageIdentifier != nil) {
+			v.uuid("image.imageIdentifier", *r.Image.ImageIdentifier)
+		}
+		if v.required("image.altText", r.Image.AltText != nil && *r.Image.AltText != "") {
+			v.maxLength("image.altText", *r.Image.AltText, MaxRetentionImageAltTextLength)
+		}
+	}
+	return v.err()
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/DotNetAge/appstore/internal/apple"
+)
+
+const (
+	// MinExtendByDays is the smallest number of days a subscription renewal date can be extended by
+	MinExtendByDays = 1
+	// MaxExtendByDays is the largest number of days a subscription renewal date can be extended by
+	MaxExtendByDays = 90
+	// MaxRequestIdentifierLength is the maximum length of a subscription-renewal-date extension request identifier
+	MaxRequestIdentifierLength = 128
+	// NotificationHistoryMaxAgeDays is how far back, in days, the notification history can be requested
+	NotificationHistoryMaxAgeDays = 180
+)
+
+// Violation describes a request field that fails client-side validation
+type Violation struct {
+	// Field is the JSON name of the invalid field
+	Field string
+	// Message describes what is wrong with the field
+	Message string
+}
+
+// String returns the violation as "field: message"
+func (v Violation) String() string {
+	return v.Field + ": " + v.Message
+}
+
+// ValidationError is returned when a request fails client-side validation, before it is sent to Apple.
+// It lists every violation and matches ErrInvalidRequest with errors.Is.
+type ValidationError struct {
+	// Request is the name of the invalid request type
+	Request string
+	// Violations lists every invalid field
+	Violations []Violation
+}
+
+// Error implements the error interface for ValidationError
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.String()
+	}
+	return fmt.Sprintf("invalid %s: %s", e.Request, strings.Join(messages, "; "))
+}
+
+// Is reports whether target is ErrInvalidRequest
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidRequest
+}
+
+// ValidateTransactionID checks that id is a well-formed transaction or original transaction identifier
+func ValidateTransactionID(id string) error {
+	v := validator{request: "transaction identifier"}
+	v.transactionID("transactionId", id)
+	return v.err()
+}
+
+// ValidateUUID checks that value, the identifier named field, is a UUID
+func ValidateUUID(field, value string) error {
+	v := validator{request: "identifier"}
+	v.uuid(field, value)
+	return v.err()
+}
+
+// validator collects the violations of a request
+type validator struct {
+	request    string
+	violations []Violation
+}
+
+// addf records a violation of field
+func (v *validator) addf(field, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// required records a violation if a required field is missing
+func (v *validator) required(field string, present bool) bool {
+	if !present {
+		v.addf(field, "is required")
+	}
+	return present
+}
+
+// between records a violation if value isn't between min and max, inclusive
+func (v *validator) between(field string, value, min, max int) {
+	if value < min || value > max {
+		v.addf(field, "must be between %d and %d, got %d", min, max, value)
+	}
+}
+
+// oneOf records a violation if value isn't one of allowed
+func (v *validator) oneOf(field, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.addf(field, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+}
+
+// uuid records a violation if value isn't a UUID
+func (v *validator) uuid(field, value string) {
+	if !apple.UUIDPattern.MatchString(value) {
+		v.addf(field, "must be a UUID, got %q", value)
+	}
+}
+
+// maxLength records a violation if value is longer than max characters
+func (v *validator) maxLength(field, value string, max int) {
+	if n := utf8.RuneCountInString(value); n > max {
+		v.addf(field, "must be at most %d characters, got %d", max, n)
+	}
+}
+
+// transactionID records a violation if id isn't a numeric transaction identifier
+func (v *validator) transactionID(field, id string) {
+	if !apple.TransactionIDPattern.MatchString(id) {
+		v.addf(field, "must be a numeric transaction identifier, got %q", id)
+	}
+}
+
+// dateRange records a violation if both dates are set and start doesn't precede end
+func (v *validator) dateRange(start, end *int64) {
+	if start != nil && end != nil && *start >= *end {
+		v.addf("startDate", "must precede endDate")
+	}
+}
+
+// missing returns a ValidationError for a nil request
+func (v *validator) missing() error {
+	v.addf("request", "is required")
+	return v.err()
+}
+
+// err returns a ValidationError listing the violations, or nil if there are none
+func (v *validator) err() error {
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Request: v.request, Violations: v.violations}
+}
+
+// validateExtension checks the fields shared by the renewal-date extension requests
+func validateExtension(v *validator, extendByDays *int, reasonCode *ExtendReasonCode, requestIdentifier *string) {
+	if v.required("extendByDays", extendByDays != nil) {
+		v.between("extendByDays", *extendByDays, MinExtendByDays, MaxExtendByDays)
+	}
+	if v.required("extendReasonCode", reasonCode != nil) {
+		v.between("extendReasonCode", int(*reasonCode), int(ExtendReasonCodeUndeclared), int(ExtendReasonCodeServiceIssueOrOutage))
+	}
+	if v.required("requestIdentifier", requestIdentifier != nil && *requestIdentifier != "") && len(*requestIdentifier) > MaxRequestIdentifierLength {
+		v.addf("requestIdentifier", "must be at most %d characters", MaxRequestIdentifierLength)
+	}
+}
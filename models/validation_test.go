@@ -0,0 +1,395 @@
+// Copyright (c) 2023 Apple Inc. Licensed under MIT License.
+
+package models
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// violatedFields returns the fields err reports as invalid, in order, or nil if err is nil
+func violatedFields(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("got %v, want a ValidationError", err)
+	}
+	if !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("%v doesn't match ErrInvalidRequest", err)
+	}
+	fields := make([]string, len(validationErr.Violations))
+	for i, violation := range validationErr.Violations {
+		fields[i] = violation.Field
+	}
+	return fields
+}
+
+// checkViolations fails the test unless err reports exactly the want fields as invalid
+func checkViolations(t *testing.T, err error, want ...string) {
+	t.Helper()
+	if got := violatedFields(t, err); !slices.Equal(got, want) {
+		t.Errorf("violations of %v, want %v (%v)", got, want, err)
+	}
+}
+
+func TestExtendRenewalDateRequestValidate(t *testing.T) {
+	valid := func() *ExtendRenewalDateRequest {
+		return &ExtendRenewalDateRequest{
+			ExtendByDays:      ptr(30),
+			ExtendReasonCode:  ptr(ExtendReasonCodeCustomerSatisfaction),
+			RequestIdentifier: ptr("3c0d0f8e-2a41-4d7e-9a55-61a0b4e2c7f9"),
+		}
+	}
+	tests := []struct {
+		name   string
+		modify func(*ExtendRenewalDateRequest)
+		want   []string
+	}{
+		{name: "valid", modify: func(r *ExtendRenewalDateRequest) {}},
+		{name: "shortest extension", modify: func(r *ExtendRenewalDateRequest) { r.ExtendByDays = ptr(MinExtendByDays) }},
+		{name: "longest extension", modify: func(r *ExtendRenewalDateRequest) { r.ExtendByDays = ptr(MaxExtendByDays) }},
+		{name: "zero days", modify: func(r *ExtendRenewalDateRequest) { r.ExtendByDays = ptr(0) }, want: []string{"extendByDays"}},
+		{name: "too many days", modify: func(r *ExtendRenewalDateRequest) { r.ExtendByDays = ptr(MaxExtendByDays + 1) }, want: []string{"extendByDays"}},
+		{name: "missing days", modify: func(r *ExtendRenewalDateRequest) { r.ExtendByDays = nil }, want: []string{"extendByDays"}},
+		{name: "undeclared reason", modify: func(r *ExtendRenewalDateRequest) { r.ExtendReasonCode = ptr(ExtendReasonCodeUndeclared) }},
+		{name: "last reason", modify: func(r *ExtendRenewalDateRequest) { r.ExtendReasonCode = ptr(ExtendReasonCodeServiceIssueOrOutage) }},
+		{name: "unknown reason", modify: func(r *ExtendRenewalDateRequest) { r.ExtendReasonCode = ptr(ExtendReasonCodeServiceIssueOrOutage + 1) }, want: []string{"extendReasonCode"}},
+		{name: "missing reason", modify: func(r *ExtendRenewalDateRequest) { r.ExtendReasonCode = nil }, want: []string{"extendReasonCode"}},
+		{name: "missing request identifier", modify: func(r *ExtendRenewalDateRequest) { r.RequestIdentifier = nil }, want: []string{"requestIdentifier"}},
+		{name: "empty request identifier", modify: func(r *ExtendRenewalDateRequest) { r.RequestIdentifier = ptr("") }, want: []string{"requestIdentifier"}},
+		{name: "longest request identifier", modify: func(r *ExtendRenewalDateRequest) {
+			r.RequestIdentifier = ptr(strings.Repeat("a", MaxRequestIdentifierLength))
+		}},
+		{name: "request identifier too long", modify: func(r *ExtendRenewalDateRequest) {
+			r.RequestIdentifier = ptr(strings.Repeat("a", MaxRequestIdentifierLength+1))
+		}, want: []string{"requestIdentifier"}},
+		{name: "every field invalid", modify: func(r *ExtendRenewalDateRequest) { *r = ExtendRenewalDateRequest{} }, want: []string{"extendByDays", "extendReasonCode", "requestIdentifier"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := valid()
+			tt.modify(request)
+			checkViolations(t, request.Validate(), tt.want...)
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		checkViolations(t, (*ExtendRenewalDateRequest)(nil).Validate(), "request")
+	})
+}
+
+func TestMassExtendRenewalDateRequestValidate(t *testing.T) {
+	valid := func() *MassExtendRenewalDateRequest {
+		return &MassExtendRenewalDateRequest{
+			ExtendByDays:      ptr(30),
+			ExtendReasonCode:  ptr(ExtendReasonCodeServiceIssueOrOutage),
+			RequestIdentifier: ptr("3c0d0f8e-2a41-4d7e-9a55-61a0b4e2c7f9"),
+			ProductId:         ptr("com.example.monthly"),
+		}
+	}
+	tests := []struct {
+		name   string
+		modify func(*MassExtendRenewalDateRequest)
+		want   []string
+	}{
+		{name: "valid", modify: func(r *MassExtendRenewalDateRequest) {}},
+		{name: "storefronts", modify: func(r *MassExtendRenewalDateRequest) { r.StorefrontCountryCodes = []string{"USA", "FRA"} }},
+		{name: "two-letter storefront", modify: func(r *MassExtendRenewalDateRequest) { r.StorefrontCountryCodes = []string{"USA", "FR"} }, want: []string{"storefrontCountryCodes"}},
+		{name: "lowercase storefront", modify: func(r *MassExtendRenewalDateRequest) { r.StorefrontCountryCodes = []string{"usa"} }, want: []string{"storefrontCountryCodes"}},
+		{name: "missing product", modify: func(r *MassExtendRenewalDateRequest) { r.ProductId = nil }, want: []string{"productId"}},
+		{name: "empty product", modify: func(r *MassExtendRenewalDateRequest) { r.ProductId = ptr("") }, want: []string{"productId"}},
+		{name: "too many days", modify: func(r *MassExtendRenewalDateRequest) { r.ExtendByDays = ptr(MaxExtendByDays + 1) }, want: []string{"extendByDays"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := valid()
+			tt.modify(request)
+			checkViolations(t, request.Validate(), tt.want...)
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		checkViolations(t, (*MassExtendRenewalDateRequest)(nil).Validate(), "request")
+	})
+}
+
+func TestConsumptionRequestValidate(t *testing.T) {
+	valid := func() *ConsumptionRequest {
+		return &ConsumptionRequest{
+			CustomerConsented:        ptr(true),
+			ConsumptionStatus:        ptr(ConsumptionStatusUndeclared),
+			Platform:                 ptr(PlatformUndeclared),
+			SampleContentProvided:    ptr(false),
+			DeliveryStatus:           ptr(DeliveryStatusDeliveredAndWorkingProperly),
+			AppAccountToken:          ptr(""),
+			AccountTenure:            ptr(AccountTenureUndeclared),
+			PlayTime:                 ptr(PlayTimeUndeclared),
+			LifetimeDollarsRefunded:  ptr(LifetimeDollarsRefundedUndeclared),
+			LifetimeDollarsPurchased: ptr(LifetimeDollarsPurchasedUndeclared),
+			UserStatus:               ptr(UserStatusUndeclared),
+		}
+	}
+	tests := []struct {
+		name   string
+		modify func(*ConsumptionRequest)
+		want   []string
+	}{
+		{name: "valid", modify: func(r *ConsumptionRequest) {}},
+		{name: "without consent", modify: func(r *ConsumptionRequest) { r.CustomerConsented = ptr(false) }, want: []string{"customerConsented"}},
+		{name: "missing consent", modify: func(r *ConsumptionRequest) { r.CustomerConsented = nil }, want: []string{"customerConsented"}},
+		{name: "last consumption status", modify: func(r *ConsumptionRequest) { r.ConsumptionStatus = ptr(ConsumptionStatusFullyConsumed) }},
+		{name: "unknown consumption status", modify: func(r *ConsumptionRequest) { r.ConsumptionStatus = ptr(ConsumptionStatusFullyConsumed + 1) }, want: []string{"consumptionStatus"}},
+		{name: "last platform", modify: func(r *ConsumptionRequest) { r.Platform = ptr(PlatformNonApple) }},
+		{name: "unknown platform", modify: func(r *ConsumptionRequest) { r.Platform = ptr(PlatformNonApple + 1) }, want: []string{"platform"}},
+		{name: "missing sample content", modify: func(r *ConsumptionRequest) { r.SampleContentProvided = nil }, want: []string{"sampleContentProvided"}},
+		{name: "last delivery status", modify: func(r *ConsumptionRequest) { r.DeliveryStatus = ptr(DeliveryStatusDidNotDeliverForOtherReason) }},
+		{name: "unknown delivery status", modify: func(r *ConsumptionRequest) { r.DeliveryStatus = ptr(DeliveryStatusDidNotDeliverForOtherReason + 1) }, want: []string{"deliveryStatus"}},
+		{name: "app account token", modify: func(r *ConsumptionRequest) { r.AppAccountToken = ptr("7e3fb20b-4cdb-47cc-936d-99d65f608138") }},
+		{name: "malformed app account token", modify: func(r *ConsumptionRequest) { r.AppAccountToken = ptr("not-a-uuid") }, want: []string{"appAccountToken"}},
+		{name: "missing app account token", modify: func(r *ConsumptionRequest) { r.AppAccountToken = nil }, want: []string{"appAccountToken"}},
+		{name: "last account tenure", modify: func(r *ConsumptionRequest) { r.AccountTenure = ptr(AccountTenureGreaterThanThreeHundredSixtyFiveDays) }},
+		{name: "unknown account tenure", modify: func(r *ConsumptionRequest) {
+			r.AccountTenure = ptr(AccountTenureGreaterThanThreeHundredSixtyFiveDays + 1)
+		}, want: []string{"accountTenure"}},
+		{name: "last play time", modify: func(r *ConsumptionRequest) { r.PlayTime = ptr(PlayTimeOverSixteenDays) }},
+		{name: "unknown play time", modify: func(r *ConsumptionRequest) { r.PlayTime = ptr(PlayTimeOverSixteenDays + 1) }, want: []string{"playTime"}},
+		{name: "unknown lifetime dollars refunded", modify: func(r *ConsumptionRequest) {
+			r.LifetimeDollarsRefunded = ptr(LifetimeDollarsRefundedTwoThousandDollarsOrGreater + 1)
+		}, want: []string{"lifetimeDollarsRefunded"}},
+		{name: "unknown lifetime dollars purchased", modify: func(r *ConsumptionRequest) {
+			r.LifetimeDollarsPurchased = ptr(LifetimeDollarsPurchasedTwoThousandDollarsOrGreater + 1)
+		}, want: []string{"lifetimeDollarsPurchased"}},
+		{name: "last user status", modify: func(r *ConsumptionRequest) { r.UserStatus = ptr(UserStatusLimitedAccess) }},
+		{name: "unknown user status", modify: func(r *ConsumptionRequest) { r.UserStatus = ptr(UserStatusLimitedAccess + 1) }, want: []string{"userStatus"}},
+		{name: "refund preference", modify: func(r *ConsumptionRequest) { r.RefundPreference = ptr(RefundPreferenceNoPreference) }},
+		{name: "unknown refund preference", modify: func(r *ConsumptionRequest) { r.RefundPreference = ptr(RefundPreferenceNoPreference + 1) }, want: []string{"refundPreference"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := valid()
+			tt.modify(request)
+			checkViolations(t, request.Validate(), tt.want...)
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		checkViolations(t, (*ConsumptionRequest)(nil).Validate(), "request")
+	})
+}
+
+func TestConsumptionRequestV2Validate(t *testing.T) {
+	valid := func() *ConsumptionRequestV2 {
+		return &ConsumptionRequestV2{
+			CustomerConsented:     ptr(true),
+			DeliveryStatus:        ptr(DeliveryStatusV2Delivered),
+			SampleContentProvided: ptr(false),
+		}
+	}
+	tests := []struct {
+		name   string
+		modify func(*ConsumptionRequestV2)
+		want   []string
+	}{
+		{name: "valid", modify: func(r *ConsumptionRequestV2) {}},
+		{name: "without consent", modify: func(r *ConsumptionRequestV2) { r.CustomerConsented = ptr(false) }, want: []string{"customerConsented"}},
+		{name: "missing consent", modify: func(r *ConsumptionRequestV2) { r.CustomerConsented = nil }, want: []string{"customerConsented"}},
+		{name: "nothing consumed", modify: func(r *ConsumptionRequestV2) { r.ConsumptionPercentage = ptr(0) }},
+		{name: "fully consumed", modify: func(r *ConsumptionRequestV2) { r.ConsumptionPercentage = ptr(MaxConsumptionPercentage) }},
+		{name: "negative consumption", modify: func(r *ConsumptionRequestV2) { r.ConsumptionPercentage = ptr(-1) }, want: []string{"consumptionPercentage"}},
+		{name: "consumption over 100%", modify: func(r *ConsumptionRequestV2) { r.ConsumptionPercentage = ptr(MaxConsumptionPercentage + 1) }, want: []string{"consumptionPercentage"}},
+		{name: "undelivered", modify: func(r *ConsumptionRequestV2) { r.DeliveryStatus = ptr(DeliveryStatusV2UndeliveredOther) }},
+		{name: "unknown delivery status", modify: func(r *ConsumptionRequestV2) { r.DeliveryStatus = ptr(DeliveryStatusV2("LOST")) }, want: []string{"deliveryStatus"}},
+		{name: "missing delivery status", modify: func(r *ConsumptionRequestV2) { r.DeliveryStatus = nil }, want: []string{"deliveryStatus"}},
+		{name: "refund preference", modify: func(r *ConsumptionRequestV2) { r.RefundPreference = ptr(RefundPreferenceV2GrantProrated) }},
+		{name: "unknown refund preference", modify: func(r *ConsumptionRequestV2) { r.RefundPreference = ptr(RefundPreferenceV2("GRANT_HALF")) }, want: []string{"refundPreference"}},
+		{name: "missing sample content", modify: func(r *ConsumptionRequestV2) { r.SampleContentProvided = nil }, want: []string{"sampleContentProvided"}},
+		{name: "every field invalid", modify: func(r *ConsumptionRequestV2) {
+			*r = ConsumptionRequestV2{ConsumptionPercentage: ptr(-1), RefundPreference: ptr(RefundPreferenceV2(""))}
+		}, want: []string{"customerConsented", "consumptionPercentage", "deliveryStatus", "refundPreference", "sampleContentProvided"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := valid()
+			tt.modify(request)
+			checkViolations(t, request.Validate(), tt.want...)
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		checkViolations(t, (*ConsumptionRequestV2)(nil).Validate(), "request")
+	})
+}
+
+func TestNotificationHistoryRequestValidate(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	oldest := now.AddDate(0, 0, -NotificationHistoryMaxAgeDays).UnixMilli()
+	valid := func() *NotificationHistoryRequest {
+		return &NotificationHistoryRequest{StartDate: ptr(now.Add(-time.Hour).UnixMilli()), EndDate: ptr(now.UnixMilli())}
+	}
+	tests := []struct {
+		name   string
+		modify func(*NotificationHistoryRequest)
+		want   []string
+	}{
+		{name: "valid", modify: func(r *NotificationHistoryRequest) {}},
+		{name: "start at the oldest allowed date", modify: func(r *NotificationHistoryRequest) { r.StartDate = ptr(oldest) }},
+		{name: "start just before the oldest allowed date", modify: func(r *NotificationHistoryRequest) { r.StartDate = ptr(oldest - 1) }, want: []string{"startDate"}},
+		{name: "start just before end", modify: func(r *NotificationHistoryRequest) { r.StartDate = ptr(*r.EndDate - 1) }},
+		{name: "start equals end", modify: func(r *NotificationHistoryRequest) { r.StartDate = ptr(*r.EndDate) }, want: []string{"startDate"}},
+		{name: "start after end", modify: func(r *NotificationHistoryRequest) { r.StartDate = ptr(*r.EndDate + 1) }, want: []string{"startDate"}},
+		{name: "missing start", modify: func(r *NotificationHistoryRequest) { r.StartDate = nil }, want: []string{"startDate"}},
+		{name: "missing end", modify: func(r *NotificationHistoryRequest) { r.EndDate = nil }, want: []string{"endDate"}},
+		{name: "transaction", modify: func(r *NotificationHistoryRequest) { r.TransactionId = ptr("2000000000000001") }},
+		{name: "malformed transaction", modify: func(r *NotificationHistoryRequest) { r.TransactionId = ptr("abc") }, want: []string{"transactionId"}},
+		{name: "transaction and type", modify: func(r *NotificationHistoryRequest) {
+			r.TransactionId = ptr("2000000000000001")
+			r.NotificationType = ptr(NotificationTypeV2Subscribed)
+		}, want: []string{"transactionId"}},
+		{name: "type and subtype", modify: func(r *NotificationHistoryRequest) {
+			r.NotificationType = ptr(NotificationTypeV2Subscribed)
+			r.NotificationSubtype = ptr(SubtypeInitialBuy)
+		}},
+		{name: "subtype without type", modify: func(r *NotificationHistoryRequest) { r.NotificationSubtype = ptr(SubtypeInitialBuy) }, want: []string{"notificationSubtype"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := valid()
+			tt.modify(request)
+			checkViolations(t, request.validate(now), tt.want...)
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		checkViolations(t, (*NotificationHistoryRequest)(nil).validate(now), "request")
+	})
+}
+
+func TestTransactionHistoryRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *TransactionHistoryRequest
+		want    []string
+	}{
+		{name: "nil", request: nil},
+		{name: "empty", request: &TransactionHistoryRequest{}},
+		{name: "start before end", request: &TransactionHistoryRequest{StartDate: ptr(int64(1)), EndDate: ptr(int64(2))}},
+		{name: "start equals end", request: &TransactionHistoryRequest{StartDate: ptr(int64(2)), EndDate: ptr(int64(2))}, want: []string{"startDate"}},
+		{name: "start only", request: &TransactionHistoryRequest{StartDate: ptr(int64(2))}},
+		{name: "product types", request: &TransactionHistoryRequest{ProductTypes: []ProductType{ProductTypeAutoRenewable, ProductTypeNonRenewable, ProductTypeConsumable, ProductTypeNonConsumable}}},
+		{name: "unknown product type", request: &TransactionHistoryRequest{ProductTypes: []ProductType{"SUBSCRIPTION"}}, want: []string{"productType"}},
+		{name: "sort", request: &TransactionHistoryRequest{Sort: ptr(OrderDescending)}},
+		{name: "unknown sort", request: &TransactionHistoryRequest{Sort: ptr(Order("NEWEST"))}, want: []string{"sort"}},
+		{name: "ownership type", request: &TransactionHistoryRequest{InAppOwnershipType: ptr(InAppOwnershipTypeFamilyShared)}},
+		{name: "unknown ownership type", request: &TransactionHistoryRequest{InAppOwnershipType: ptr(InAppOwnershipType("SHARED"))}, want: []string{"inAppOwnershipType"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkViolations(t, tt.request.Validate(), tt.want...)
+		})
+	}
+}
+
+func TestUploadMessageRequestBodyValidate(t *testing.T) {
+	imageIdentifier := "7e3fb20b-4cdb-47cc-936d-99d65f608138"
+	valid := func() *UploadMessageRequestBody {
+		return &UploadMessageRequestBody{Header: ptr("Stay with us"), Body: ptr("Get a month free when you keep your plan.")}
+	}
+	tests := []struct {
+		name   string
+		modify func(*UploadMessageRequestBody)
+		want   []string
+	}{
+		{name: "valid", modify: func(r *UploadMessageRequestBody) {}},
+		{name: "longest header", modify: func(r *UploadMessageRequestBody) {
+			r.Header = ptr(strings.Repeat("é", MaxRetentionMessageHeaderLength))
+		}},
+		{name: "header too long", modify: func(r *UploadMessageRequestBody) {
+			r.Header = ptr(strings.Repeat("a", MaxRetentionMessageHeaderLength+1))
+		}, want: []string{"header"}},
+		{name: "missing header", modify: func(r *UploadMessageRequestBody) { r.Header = nil }, want: []string{"header"}},
+		{name: "empty header", modify: func(r *UploadMessageRequestBody) { r.Header = ptr("") }, want: []string{"header"}},
+		{name: "longest body", modify: func(r *UploadMessageRequestBody) { r.Body = ptr(strings.Repeat("é", MaxRetentionMessageBodyLength)) }},
+		{name: "body too long", modify: func(r *UploadMessageRequestBody) { r.Body = ptr(strings.Repeat("a", MaxRetentionMessageBodyLength+1)) }, want: []string{"body"}},
+		{name: "missing body", modify: func(r *UploadMessageRequestBody) { r.Body = nil }, want: []string{"body"}},
+		{name: "image", modify: func(r *UploadMessageRequestBody) {
+			r.Image = &UploadMessageImage{ImageIdentifier: ptr(imageIdentifier), AltText: ptr("A gift box")}
+		}},
+		{name: "image with a malformed identifier", modify: func(r *UploadMessageRequestBody) {
+			r.Image = &UploadMessageImage{ImageIdentifier: ptr("gift-box"), AltText: ptr("A gift box")}
+		}, want: []string{"image.imageIdentifier"}},
+		{name: "image without identifier", modify: func(r *UploadMessageRequestBody) {
+			r.Image = &UploadMessageImage{AltText: ptr("A gift box")}
+		}, want: []string{"image.imageIdentifier"}},
+		{name: "image without alt text", modify: func(r *UploadMessageRequestBody) {
+			r.Image = &UploadMessageImage{ImageIdentifier: ptr(imageIdentifier)}
+		}, want: []string{"image.altText"}},
+		{name: "alt text too long", modify: func(r *UploadMessageRequestBody) {
+			r.Image = &UploadMessageImage{ImageIdentifier: ptr(imageIdentifier), AltText: ptr(strings.Repeat("a", MaxRetentionImageAltTextLength+1))}
+		}, want: []string{"image.altText"}},
+		{name: "every field invalid", modify: func(r *UploadMessageRequestBody) {
+			*r = UploadMessageRequestBody{Image: &UploadMessageImage{}}
+		}, want: []string{"header", "body", "image.imageIdentifier", "image.altText"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := valid()
+			tt.modify(request)
+			checkViolations(t, request.Validate(), tt.want...)
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		checkViolations(t, (*UploadMessageRequestBody)(nil).Validate(), "request")
+	})
+}
+
+func TestDefaultConfigurationRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *DefaultConfigurationRequest
+		want    []string
+	}{
+		{name: "valid", request: &DefaultConfigurationRequest{MessageIdentifier: ptr("7e3fb20b-4cdb-47cc-936d-99d65f608138")}},
+		{name: "malformed message identifier", request: &DefaultConfigurationRequest{MessageIdentifier: ptr("welcome-back")}, want: []string{"messageIdentifier"}},
+		{name: "missing message identifier", request: &DefaultConfigurationRequest{}, want: []string{"messageIdentifier"}},
+		{name: "nil", want: []string{"request"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkViolations(t, tt.request.Validate(), tt.want...)
+		})
+	}
+}
+
+func TestValidateIdentifiers(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		valid bool
+	}{
+		{name: "transaction", err: ValidateTransactionID("2000000000000001"), valid: true},
+		{name: "longest transaction", err: ValidateTransactionID(strings.Repeat("1", 32)), valid: true},
+		{name: "transaction too long", err: ValidateTransactionID(strings.Repeat("1", 33))},
+		{name: "empty transaction", err: ValidateTransactionID("")},
+		{name: "transaction with a path", err: ValidateTransactionID("1/../2")},
+		{name: "UUID", err: ValidateUUID("id", "7E3FB20B-4CDB-47CC-936D-99D65F608138"), valid: true},
+		{name: "malformed UUID", err: ValidateUUID("id", "7e3fb20b4cdb47cc936d99d65f608138")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if (tt.err == nil) != tt.valid {
+				t.Errorf("got %v, want valid = %v", tt.err, tt.valid)
+			}
+		})
+	}
+}
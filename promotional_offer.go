@@ -1,11 +1,8 @@
 package appstore
 
 import (
-	"crypto/ecdsa"
-	"crypto/sha256"
-	"crypto/x509"
+	"crypto"
 	"encoding/base64"
-	"encoding/pem"
 	"fmt"
 	"strings"
 )
@@ -13,29 +10,33 @@ import (
 // PromotionalOfferSignatureCreator creates signatures for promotional offers
 // https://developer.apple.com/documentation/storekit/in-app_purchase/original_api_for_in-app_purchase/subscriptions_and_offers/generating_a_signature_for_promotional_offers
 type PromotionalOfferSignatureCreator struct {
-	signingKey *ecdsa.PrivateKey
-	keyID      string
-	bundleID   string
+	signer   crypto.Signer
+	keyID    string
+	bundleID string
 }
 
 // NewPromotionalOfferSignatureCreator creates a new PromotionalOfferSignatureCreator
 func NewPromotionalOfferSignatureCreator(signingKey []byte, keyID, bundleID string) (*PromotionalOfferSignatureCreator, error) {
-	// Parse the PEM-encoded private key
-	block, _ := pem.Decode(signingKey)
-	if block == nil || block.Type != "EC PRIVATE KEY" {
-		return nil, fmt.Errorf("invalid PEM block: expected EC PRIVATE KEY")
+	// Parse the PKCS#8 or SEC1 private key
+	privKey, err := LoadSigningKey(signingKey)
+	if err != nil {
+		return nil, err
 	}
 
-	// Parse the EC private key
-	privKey, err := x509.ParseECPrivateKey(block.Bytes)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse EC private key: %w", err)
+	return NewPromotionalOfferSignatureCreatorWithSigner(privKey, keyID, bundleID)
+}
+
+// NewPromotionalOfferSignatureCreatorWithSigner creates a new PromotionalOfferSignatureCreator that signs with a crypto.Signer,
+// so the private key can stay in a KMS, an HSM or an agent. The signer must hold a P-256 ECDSA key.
+func NewPromotionalOfferSignatureCreatorWithSigner(signer crypto.Signer, keyID, bundleID string) (*PromotionalOfferSignatureCreator, error) {
+	if err := checkSigner(signer); err != nil {
+		return nil, err
 	}
 
 	return &PromotionalOfferSignatureCreator{
-		signingKey: privKey,
-		keyID:      keyID,
-		bundleID:   bundleID,
+		signer:   signer,
+		keyID:    keyID,
+		bundleID: bundleID,
 	}, nil
 }
 
@@ -53,20 +54,12 @@ func (c *PromotionalOfferSignatureCreator) CreateSignature(productIdentifier, su
 		timestamp,
 	)
 
-	// Hash the payload
-	hash := sha256.Sum256([]byte(payload))
-
-	// Sign the hash
-	r, s, err := ecdsa.Sign(nil, c.signingKey, hash[:])
+	// Sign the SHA-256 hash of the payload; the App Store expects the ASN.1 DER-encoded signature
+	signature, err := signES256(c.signer, []byte(payload))
 	if err != nil {
 		return "", fmt.Errorf("failed to sign payload: %w", err)
 	}
 
-	// Combine the r and s values into a single byte slice
-	signature := make([]byte, 64)
-	r.FillBytes(signature[:32])
-	s.FillBytes(signature[32:])
-
 	// Base64 encode the signature
 	return base64.StdEncoding.EncodeToString(signature), nil
 }
@@ -0,0 +1,209 @@
+package appstore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/DotNetAge/appstore/models"
+)
+
+// RateLimit describes the request quota of an endpoint family
+type RateLimit struct {
+	// RequestsPerSecond is the sustained number of requests allowed per second
+	RequestsPerSecond float64
+	// Burst is the number of requests that can be sent at once after a quiet period
+	Burst int
+}
+
+// ConservativeRateLimits returns the per-second limits the client applies to each endpoint family unless overridden.
+// They are this package's estimates of a safe request rate, not Apple's quotas: Apple sets an hourly limit per
+// endpoint, which can differ between accounts, and answers 429 with RateLimitExceededError once it is used up.
+// Look up the limits of your account and pass them to NewRateLimiter.
+// https://developer.apple.com/documentation/appstoreserverapi/identifying_rate_limits
+func ConservativeRateLimits() map[EndpointFamily]RateLimit {
+	return map[EndpointFamily]RateLimit{
+		EndpointFamilyHistory:             {RequestsPerSecond: 100, Burst: 100},
+		EndpointFamilyRefundHistory:       {RequestsPerSecond: 10, Burst: 10},
+		EndpointFamilyTransactionInfo:     {RequestsPerSecond: 100, Burst: 100},
+		EndpointFamilyAppTransactionInfo:  {RequestsPerSecond: 10, Burst: 10},
+		EndpointFamilyStatuses:            {RequestsPerSecond: 50, Burst: 50},
+		EndpointFamilyLookup:              {RequestsPerSecond: 10, Burst: 10},
+		EndpointFamilyExtend:              {RequestsPerSecond: 10, Burst: 10},
+		EndpointFamilyConsumption:         {RequestsPerSecond: 10, Burst: 10},
+		EndpointFamilyAppAccountToken:     {RequestsPerSecond: 10, Burst: 10},
+		EndpointFamilyNotificationHistory: {RequestsPerSecond: 5, Burst: 5},
+		EndpointFamilyRetentionMessaging:  {RequestsPerSecond: 10, Burst: 10},
+		EndpointFamilyAdvancedCommerce:    {RequestsPerSecond: 10, Burst: 10},
+	}
+}
+
+// RateLimiterState reports the state of the limiter for one endpoint family
+type RateLimiterState struct {
+	// Limit is the quota applied to the family
+	Limit RateLimit
+	// Available is the number of requests that can be sent right now without waiting
+	Available float64
+	// Waits is the number of requests that had to wait for quota
+	Waits int64
+	// WaitTime is the total time requests spent waiting for quota
+	WaitTime time.Duration
+}
+
+// RateLimiter throttles App Store Server API requests per endpoint family.
+// A single RateLimiter is safe for concurrent use and can be shared by several clients so they draw from the same budget.
+type RateLimiter struct {
+	mu      sync.Mutex
+	buckets map[EndpointFamily]*tokenBucket
+}
+
+// NewRateLimiter creates a RateLimiter using ConservativeRateLimits, replaced by the given overrides
+func NewRateLimiter(overrides map[EndpointFamily]RateLimit) *RateLimiter {
+	limits := ConservativeRateLimits()
+	for family, limit := range overrides {
+		limits[family] = limit
+	}
+
+	now := time.Now()
+	buckets := make(map[EndpointFamily]*tokenBucket, len(limits))
+	for family, limit := range limits {
+		buckets[family] = newTokenBucket(limit, now)
+	}
+	return &RateLimiter{buckets: buckets}
+}
+
+// WithRateLimiter sets the rate limiter of the client. A nil limiter disables client-side throttling.
+// By default, clients for the same bundle ID and environment share one limiter using ConservativeRateLimits.
+func WithRateLimiter(limiter *RateLimiter) ClientOption {
+	return func(opts *clientOptions) {
+		opts.rateLimiter = limiter
+		opts.rateLimiterSet = true
+	}
+}
+
+// Wait blocks until the endpoint family has quota for one request or the context is done.
+// It returns how long the request waited.
+func (l *RateLimiter) Wait(ctx context.Context, family EndpointFamily) (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+
+	l.mu.Lock()
+	bucket, ok := l.buckets[family]
+	if !ok {
+		l.mu.Unlock()
+		return 0, nil
+	}
+	delay := bucket.reserve(time.Now())
+	if delay == 0 {
+		l.mu.Unlock()
+		return 0, nil
+	}
+
+	// Don't wait for quota the caller can't use
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		bucket.cancel()
+		l.mu.Unlock()
+		return 0, fmt.Errorf("rate limit for %s requires waiting %s, which exceeds the context deadline: %w", family, delay, context.DeadlineExceeded)
+	}
+	l.mu.Unlock()
+
+	if err := sleepContext(ctx, delay); err != nil {
+		l.mu.Lock()
+		bucket.cancel()
+		l.mu.Unlock()
+		return 0, err
+	}
+
+	l.mu.Lock()
+	bucket.waits++
+	bucket.waitTime += delay
+	l.mu.Unlock()
+	return delay, nil
+}
+
+// State returns a snapshot of the limiter for every endpoint family
+func (l *RateLimiter) State() map[EndpointFamily]RateLimiterState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	state := make(map[EndpointFamily]RateLimiterState, len(l.buckets))
+	for family, bucket := range l.buckets {
+		bucket.refill(now)
+		state[family] = RateLimiterState{
+			Limit:     bucket.limit,
+			Available: max(bucket.tokens, 0),
+			Waits:     bucket.waits,
+			WaitTime:  bucket.waitTime,
+		}
+	}
+	return state
+}
+
+// sharedRateLimiters holds the default limiter of each bundle ID and environment
+var sharedRateLimiters sync.Map
+
+// sharedRateLimiter returns the process-wide limiter for the given bundle ID and environment
+func sharedRateLimiter(bundleID string, environment models.Environment) *RateLimiter {
+	key := bundleID + "/" + string(environment)
+	if limiter, ok := sharedRateLimiters.Load(key); ok {
+		return limiter.(*RateLimiter)
+	}
+	limiter, _ := sharedRateLimiters.LoadOrStore(key, NewRateLimiter(nil))
+	return limiter.(*RateLimiter)
+}
+
+// tokenBucket implements a token bucket whose balance may go negative to queue reservations
+type tokenBucket struct {
+	limit    RateLimit
+	tokens   float64
+	last     time.Time
+	waits    int64
+	waitTime time.Duration
+}
+
+// newTokenBucket creates a full token bucket for the given limit
+func newTokenBucket(limit RateLimit, now time.Time) *tokenBucket {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &tokenBucket{
+		limit:  limit,
+		tokens: float64(limit.Burst),
+		last:   now,
+	}
+}
+
+// refill adds the tokens accumulated since the last update
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.tokens+elapsed.Seconds()*b.limit.RequestsPerSecond, float64(b.limit.Burst))
+		b.last = now
+	}
+}
+
+// reserve takes one token and returns how long the caller must wait before using it
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	if b.limit.RequestsPerSecond <= 0 {
+		return 0
+	}
+
+	b.refill(now)
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.limit.RequestsPerSecond * float64(time.Second))
+}
+
+// cancel returns a reserved token that won't be used
+func (b *tokenBucket) cancel() {
+	b.tokens = min(b.tokens+1, float64(b.limit.Burst))
+}
+
+// RateLimiter returns the rate limiter used by the client, or nil if throttling is disabled
+func (c *AppStoreServerAPIClient) RateLimiter() *RateLimiter {
+	return c.rateLimiter
+}
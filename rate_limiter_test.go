@@ -0,0 +1,125 @@
+package appstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterWait(t *testing.T) {
+	t.Run("burst doesn't wait", func(t *testing.T) {
+		limiter := NewRateLimiter(map[EndpointFamily]RateLimit{EndpointFamilyLookup: {RequestsPerSecond: 1, Burst: 3}})
+		for i := range 3 {
+			if wait, err := limiter.Wait(context.Background(), EndpointFamilyLookup); err != nil || wait != 0 {
+				t.Fatalf("request %d waited %s (%v)", i, wait, err)
+			}
+		}
+		if available := limiter.State()[EndpointFamilyLookup].Available; available >= 1 {
+			t.Errorf("%f requests available after the burst", available)
+		}
+	})
+
+	t.Run("waits for quota", func(t *testing.T) {
+		limiter := NewRateLimiter(map[EndpointFamily]RateLimit{EndpointFamilyLookup: {RequestsPerSecond: 20, Burst: 1}})
+		if _, err := limiter.Wait(context.Background(), EndpointFamilyLookup); err != nil {
+			t.Fatal(err)
+		}
+		wait, err := limiter.Wait(context.Background(), EndpointFamilyLookup)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wait <= 0 || wait > 50*time.Millisecond {
+			t.Errorf("waited %s, want up to 50ms", wait)
+		}
+		if state := limiter.State()[EndpointFamilyLookup]; state.Waits != 1 || state.WaitTime != wait {
+			t.Errorf("state = %+v, want one wait of %s", state, wait)
+		}
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		limiter := NewRateLimiter(map[EndpointFamily]RateLimit{EndpointFamilyLookup: {RequestsPerSecond: 0.1, Burst: 1}})
+		if _, err := limiter.Wait(context.Background(), EndpointFamilyLookup); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+		start := time.Now()
+		if _, err := limiter.Wait(ctx, EndpointFamilyLookup); !errors.Is(err, context.Canceled) {
+			t.Fatalf("got %v, want context.Canceled", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("returned %s after the cancellation", elapsed)
+		}
+
+		// The canceled request gives its reservation back, so only the first request is charged
+		if state := limiter.State()[EndpointFamilyLookup]; state.Available < -0.1 || state.Waits != 0 {
+			t.Errorf("state = %+v after the cancellation", state)
+		}
+	})
+
+	t.Run("deadline too short", func(t *testing.T) {
+		limiter := NewRateLimiter(map[EndpointFamily]RateLimit{EndpointFamilyLookup: {RequestsPerSecond: 0.1, Burst: 1}})
+		if _, err := limiter.Wait(context.Background(), EndpointFamilyLookup); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		start := time.Now()
+		if _, err := limiter.Wait(ctx, EndpointFamilyLookup); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got %v, want context.DeadlineExceeded", err)
+		}
+		if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+			t.Errorf("waited %s for quota the deadline can't reach", elapsed)
+		}
+	})
+
+	t.Run("unknown family", func(t *testing.T) {
+		limiter := NewRateLimiter(nil)
+		if wait, err := limiter.Wait(context.Background(), EndpointFamily("unknown")); err != nil || wait != 0 {
+			t.Errorf("waited %s (%v)", wait, err)
+		}
+	})
+
+	t.Run("nil limiter", func(t *testing.T) {
+		var limiter *RateLimiter
+		if wait, err := limiter.Wait(context.Background(), EndpointFamilyLookup); err != nil || wait != 0 {
+			t.Errorf("waited %s (%v)", wait, err)
+		}
+	})
+}
+
+func TestNewRateLimiterOverrides(t *testing.T) {
+	override := RateLimit{RequestsPerSecond: 1, Burst: 2}
+	state := NewRateLimiter(map[EndpointFamily]RateLimit{EndpointFamilyHistory: override}).State()
+
+	if got := state[EndpointFamilyHistory].Limit; got != override {
+		t.Errorf("history limit = %+v, want %+v", got, override)
+	}
+	for family, limit := range ConservativeRateLimits() {
+		if family == EndpointFamilyHistory {
+			continue
+		}
+		if got := state[family].Limit; got != limit {
+			t.Errorf("%s limit = %+v, want the default %+v", family, got, limit)
+		}
+	}
+}
+
+func TestEndpointFamilies(t *testing.T) {
+	limits := ConservativeRateLimits()
+	for endpoint, family := range endpointFamilies {
+		if _, ok := limits[family]; !ok {
+			t.Errorf("%s is in family %q, which has no limit", endpoint, family)
+		}
+	}
+	// Apple limits each endpoint separately, so the history endpoints don't share a budget
+	if EndpointGetRefundHistory.Family() == EndpointGetTransactionHistory.Family() {
+		t.Error("Get Refund History shares the Get Transaction History quota")
+	}
+	if family := Endpoint("Unknown").Family(); family != "" {
+		t.Errorf("unknown endpoint is in family %q", family)
+	}
+}
@@ -0,0 +1,128 @@
+package appstore
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+
+	"github.com/DotNetAge/appstore/models"
+)
+
+// pngSignature is the first eight bytes of every PNG file
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+// RetentionMessagingClient manages the messages and images the App Store shows on the subscription cancellation
+// sheet, through the Retention Messaging API. It authenticates, retries, rate limits and reports errors like the
+// AppStoreServerAPIClient it is built on; uploaded messages and images start PENDING until Apple approves them.
+// https://developer.apple.com/documentation/retentionmessaging
+type RetentionMessagingClient struct {
+	client *AppStoreServerAPIClient
+}
+
+// NewRetentionMessagingClient creates a RetentionMessagingClient with the same credentials and options as an AppStoreServerAPIClient
+func NewRetentionMessagingClient(signingKey []byte, keyID, issuerID, bundleID string, environment models.Environment, options ...ClientOption) (*RetentionMessagingClient, error) {
+	client, err := NewAppStoreServerAPIClientWithOptions(signingKey, keyID, issuerID, bundleID, environment, options...)
+	if err != nil {
+		return nil, err
+	}
+	return client.RetentionMessaging(), nil
+}
+
+// RetentionMessaging returns a RetentionMessagingClient that shares the client's keys, rate limiter and interceptors
+func (c *AppStoreServerAPIClient) RetentionMessaging() *RetentionMessagingClient {
+	return &RetentionMessagingClient{client: c}
+}
+
+// UploadImage uploads a PNG image under a UUID you choose, for use in retention messages once it is approved.
+// https://developer.apple.com/documentation/retentionmessaging/upload-image
+func (c *RetentionMessagingClient) UploadImage(ctx context.Context, imageIdentifier string, image []byte) error {
+	path, err := retentionPath("/inApps/v1/messaging/image/", "imageIdentifier", imageIdentifier)
+	if err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(image, pngSignature) {
+		return &models.ValidationError{
+			Request:    "image",
+			Violations: []models.Violation{{Field: "image", Message: "must be a PNG image"}},
+		}
+	}
+	return c.client.makeRequest(ctx, EndpointUploadImage, path, "PUT", url.Values{}, rawBody{contentType: "image/png", data: image}, nil)
+}
+
+// DeleteImage deletes an uploaded image that no message uses.
+// https://developer.apple.com/documentation/retentionmessaging/delete-image
+func (c *RetentionMessagingClient) DeleteImage(ctx context.Context, imageIdentifier string) error {
+	path, err := retentionPath("/inApps/v1/messaging/image/", "imageIdentifier", imageIdentifier)
+	if err != nil {
+		return err
+	}
+	return c.client.makeRequest(ctx, EndpointDeleteImage, path, "DELETE", url.Values{}, nil, nil)
+}
+
+// GetImageList gets the identifiers and approval states of the uploaded images.
+// https://developer.apple.com/documentation/retentionmessaging/get-image-list
+func (c *RetentionMessagingClient) GetImageList(ctx context.Context) (*models.GetImageListResponse, error) {
+	var response models.GetImageListResponse
+	if err := c.client.makeRequest(ctx, EndpointGetImageList, "/inApps/v1/messaging/image/list", "GET", url.Values{}, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// UploadMessage uploads a localized message under a UUID you choose; it can be shown once it is approved.
+// https://developer.apple.com/documentation/retentionmessaging/upload-message
+func (c *RetentionMessagingClient) UploadMessage(ctx context.Context, messageIdentifier string, request *models.UploadMessageRequestBody) error {
+	path, err := retentionPath("/inApps/v1/messaging/message/", "messageIdentifier", messageIdentifier)
+	if err != nil {
+		return err
+	}
+	return c.client.makeRequest(ctx, EndpointUploadMessage, path, "PUT", url.Values{}, request, nil)
+}
+
+// DeleteMessage deletes an uploaded message.
+// https://developer.apple.com/documentation/retentionmessaging/delete-message
+func (c *RetentionMessagingClient) DeleteMessage(ctx context.Context, messageIdentifier string) error {
+	path, err := retentionPath("/inApps/v1/messaging/message/", "messageIdentifier", messageIdentifier)
+	if err != nil {
+		return err
+	}
+	return c.client.makeRequest(ctx, EndpointDeleteMessage, path, "DELETE", url.Values{}, nil, nil)
+}
+
+// GetMessageList gets the identifiers and approval states of the uploaded messages.
+// https://developer.apple.com/documentation/retentionmessaging/get-message-list
+func (c *RetentionMessagingClient) GetMessageList(ctx context.Context) (*models.GetMessageListResponse, error) {
+	var response models.GetMessageListResponse
+	if err := c.client.makeRequest(ctx, EndpointGetMessageList, "/inApps/v1/messaging/message/list", "GET", url.Values{}, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// ConfigureDefaultMessage sets the approved message shown for a product in a locale, such as "en-US", when your
+// server doesn't choose one in real time.
+// https://developer.apple.com/documentation/retentionmessaging/configure-default-message
+func (c *RetentionMessagingClient) ConfigureDefaultMessage(ctx context.Context, productID, locale string, request *models.DefaultConfigurationRequest) error {
+	path := "/inApps/v1/messaging/default/" + url.PathEscape(productID) + "/" + url.PathEscape(locale)
+	return c.client.makeRequest(ctx, EndpointConfigureDefaultMessage, path, "PUT", url.Values{}, request, nil)
+}
+
+// DeleteDefaultMessage removes the default message of a product in a locale.
+// https://developer.apple.com/documentation/retentionmessaging/delete-default-message
+func (c *RetentionMessagingClient) DeleteDefaultMessage(ctx context.Context, productID, locale string) error {
+	path := "/inApps/v1/messaging/default/" + url.PathEscape(productID) + "/" + url.PathEscape(locale)
+	return c.client.makeRequest(ctx, EndpointDeleteDefaultMessage, path, "DELETE", url.Values{}, nil, nil)
+}
+
+// retentionPath validates an image or message identifier and returns prefix followed by it
+func retentionPath(prefix, field, identifier string) (string, error) {
+	if err := models.ValidateUUID(field, identifier); err != nil {
+		return "", err
+	}
+	return prefix + identifier, nil
+}
+
+// RetentionMessaging returns a RetentionMessagingClient that shares the client's keys, rate limiter and interceptors
+func (c *AppStoreServerClient) RetentionMessaging() *RetentionMessagingClient {
+	return c.client.RetentionMessaging()
+}
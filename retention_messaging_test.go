@@ -0,0 +1,193 @@
+package appstore_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/DotNetAge/appstore"
+	"github.com/DotNetAge/appstore/appstoretest"
+	"github.com/DotNetAge/appstore/models"
+)
+
+const (
+	retentionImageID   = "7e3fb20b-4cdb-47cc-936d-99d65f608138"
+	retentionMessageID = "c5f1a5a4-8d0e-4b5e-9f3a-2b7c6d1e0f42"
+	retentionProduct   = "com.example.monthly"
+)
+
+// pngImage is the start of a PNG file
+var pngImage = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0, 0, 0, 13, 'I', 'H', 'D', 'R'}
+
+// newRetentionMessagingClient returns a fake App Store, a RetentionMessagingClient for it without retries and the
+// log of the client's requests
+func newRetentionMessagingClient(t *testing.T) (*appstoretest.Server, *appstore.RetentionMessagingClient, *requestLog) {
+	t.Helper()
+	server := appstoretest.NewServer()
+	t.Cleanup(server.Close)
+	log := &requestLog{}
+	client, err := appstore.NewRetentionMessagingClient(nil, appstoretest.DefaultKeyID, appstoretest.DefaultIssuerID, server.BundleID(), server.Environment(),
+		appstore.WithBaseURL(server.URL),
+		appstore.WithSigner(newSigningKeys(t, appstoretest.DefaultKeyID)[0].Signer),
+		appstore.WithHTTPClient(&http.Client{Transport: log}),
+		appstore.WithRateLimiter(nil),
+		appstore.WithRetryPolicy(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, client, log
+}
+
+func TestRetentionMessagingClient(t *testing.T) {
+	server, client, log := newRetentionMessagingClient(t)
+	ctx := context.Background()
+	message := &models.UploadMessageRequestBody{
+		Header: ptr("Stay with us"),
+		Body:   ptr("Get a month free when you keep your plan."),
+		Image:  &models.UploadMessageImage{ImageIdentifier: ptr(retentionImageID), AltText: ptr("A gift box")},
+	}
+	defaultMessage := &models.DefaultConfigurationRequest{MessageIdentifier: ptr(retentionMessageID)}
+
+	if err := client.UploadImage(ctx, retentionImageID, pngImage); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.UploadImage(ctx, retentionImageID, pngImage); !errors.Is(err, models.APIErrorImageAlreadyExists) {
+		t.Errorf("uploading the image again: error = %v", err)
+	}
+	images, err := client.GetImageList(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantImages := []models.GetImageListResponseItem{{ImageIdentifier: ptr(retentionImageID), ImageState: ptr(models.ImageStatePending)}}
+	if !reflect.DeepEqual(images.ImageIdentifiers, wantImages) {
+		t.Errorf("images = %+v", images.ImageIdentifiers)
+	}
+
+	// Messages may only use approved images, and only approved messages can be defaults
+	if err := client.UploadMessage(ctx, retentionMessageID, message); !errors.Is(err, models.APIErrorImageNotApproved) {
+		t.Errorf("uploading a message with a pending image: error = %v", err)
+	}
+	if err := server.SetImageState(retentionImageID, models.ImageStateApproved); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.UploadMessage(ctx, retentionMessageID, message); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.ConfigureDefaultMessage(ctx, retentionProduct, "en-US", defaultMessage); !errors.Is(err, models.APIErrorMessageNotApproved) {
+		t.Errorf("configuring a pending message: error = %v", err)
+	}
+	if err := server.SetMessageState(retentionMessageID, models.MessageStateApproved); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.ConfigureDefaultMessage(ctx, retentionProduct, "en-US", defaultMessage); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := server.DefaultMessage(retentionProduct, "en-US"); !ok || got != retentionMessageID {
+		t.Errorf("default message = %q, %v", got, ok)
+	}
+	messages, err := client.GetMessageList(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantMessages := []models.GetMessageListResponseItem{{MessageIdentifier: ptr(retentionMessageID), MessageState: ptr(models.MessageStateApproved)}}
+	if !reflect.DeepEqual(messages.MessageIdentifiers, wantMessages) {
+		t.Errorf("messages = %+v", messages.MessageIdentifiers)
+	}
+
+	if err := client.DeleteImage(ctx, retentionImageID); !errors.Is(err, models.APIErrorImageInUse) {
+		t.Errorf("deleting an image in use: error = %v", err)
+	}
+	if err := client.DeleteDefaultMessage(ctx, retentionProduct, "en-US"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.DeleteDefaultMessage(ctx, retentionProduct, "en-US"); !errors.Is(err, models.APIErrorMessageNotFound) {
+		t.Errorf("deleting a missing default message: error = %v", err)
+	}
+	if err := client.DeleteMessage(ctx, retentionMessageID); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.DeleteImage(ctx, retentionImageID); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"PUT /inApps/v1/messaging/image/" + retentionImageID,
+		"PUT /inApps/v1/messaging/image/" + retentionImageID,
+		"GET /inApps/v1/messaging/image/list",
+		"PUT /inApps/v1/messaging/message/" + retentionMessageID,
+		"PUT /inApps/v1/messaging/message/" + retentionMessageID,
+		"PUT /inApps/v1/messaging/default/" + retentionProduct + "/en-US",
+		"PUT /inApps/v1/messaging/default/" + retentionProduct + "/en-US",
+		"GET /inApps/v1/messaging/message/list",
+		"DELETE /inApps/v1/messaging/image/" + retentionImageID,
+		"DELETE /inApps/v1/messaging/default/" + retentionProduct + "/en-US",
+		"DELETE /inApps/v1/messaging/default/" + retentionProduct + "/en-US",
+		"DELETE /inApps/v1/messaging/message/" + retentionMessageID,
+		"DELETE /inApps/v1/messaging/image/" + retentionImageID,
+	}
+	if got := log.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("requests = %q, want %q", got, want)
+	}
+}
+
+func TestUploadImage(t *testing.T) {
+	server, requests := newInterceptorTestServer(t, &eventLog{})
+	client := newInterceptorTestClient(t, server).RetentionMessaging()
+
+	if err := client.UploadImage(context.Background(), retentionImageID, pngImage); err != nil {
+		t.Fatal(err)
+	}
+	received := requests()
+	if len(received) != 1 {
+		t.Fatalf("sent %d requests, want 1", len(received))
+	}
+	if got := received[0].header.Get("Content-Type"); got != "image/png" {
+		t.Errorf("Content-Type = %s, want image/png", got)
+	}
+	if !bytes.Equal(received[0].body, pngImage) {
+		t.Errorf("body = %x, want the image", received[0].body)
+	}
+}
+
+func TestRetentionMessagingClientRejectsInvalidRequests(t *testing.T) {
+	_, client, log := newRetentionMessagingClient(t)
+	ctx := context.Background()
+	message := &models.UploadMessageRequestBody{Header: ptr("Stay with us"), Body: ptr("Get a month free.")}
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{name: "JPEG image", call: func() error {
+			return client.UploadImage(ctx, retentionImageID, []byte{0xff, 0xd8, 0xff, 0xe0, 0, 0x10, 'J', 'F', 'I', 'F'})
+		}},
+		{name: "empty image", call: func() error { return client.UploadImage(ctx, retentionImageID, nil) }},
+		{name: "truncated PNG signature", call: func() error { return client.UploadImage(ctx, retentionImageID, pngImage[:7]) }},
+		{name: "malformed image identifier", call: func() error { return client.UploadImage(ctx, "gift-box", pngImage) }},
+		{name: "malformed image identifier to delete", call: func() error { return client.DeleteImage(ctx, "../list") }},
+		{name: "malformed message identifier", call: func() error { return client.UploadMessage(ctx, "welcome-back", message) }},
+		{name: "malformed message identifier to delete", call: func() error { return client.DeleteMessage(ctx, "../list") }},
+		{name: "message without body", call: func() error {
+			return client.UploadMessage(ctx, retentionMessageID, &models.UploadMessageRequestBody{Header: ptr("Stay with us")})
+		}},
+		{name: "no message", call: func() error { return client.UploadMessage(ctx, retentionMessageID, nil) }},
+		{name: "default without message", call: func() error {
+			return client.ConfigureDefaultMessage(ctx, retentionProduct, "en-US", &models.DefaultConfigurationRequest{})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			var validationErr *models.ValidationError
+			if !errors.As(err, &validationErr) || !errors.Is(err, models.ErrInvalidRequest) {
+				t.Errorf("error = %v, want a ValidationError", err)
+			}
+			if sent := log.take(); len(sent) != 0 {
+				t.Errorf("sent %q", sent)
+			}
+		})
+	}
+}
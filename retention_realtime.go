@@ -0,0 +1,157 @@
+package appstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/DotNetAge/appstore/models"
+)
+
+const (
+	// DefaultRealtimeDeadline is how long a RealtimeRetentionHandler takes to verify a request and decide before it
+	// answers with the default message; Apple stops waiting for your server shortly after
+	DefaultRealtimeDeadline = 700 * time.Millisecond
+	// maxRealtimeRequestSize bounds the request body read by a RealtimeRetentionHandler
+	maxRealtimeRequestSize = 64 << 10
+)
+
+// RetentionDecisionFunc chooses the retention message, alternate product or promotional offer to show a customer who
+// is cancelling a subscription. Returning nil, or an error, shows the default message of the product and locale.
+// ctx is cancelled when the handler's deadline passes.
+type RetentionDecisionFunc func(ctx context.Context, request *models.DecodedRealtimeRequestBody) (*models.RealtimeResponseBody, error)
+
+// RealtimeHandlerOption configures a RealtimeRetentionHandler
+type RealtimeHandlerOption func(*RealtimeRetentionHandler)
+
+// WithRealtimeDeadline sets how long the handler takes to verify a request and decide; the default is DefaultRealtimeDeadline
+func WithRealtimeDeadline(deadline time.Duration) RealtimeHandlerOption {
+	return func(h *RealtimeRetentionHandler) {
+		h.deadline = deadline
+	}
+}
+
+// WithRealtimeLogger sets the structured logger used to report rejected requests and failed decisions
+func WithRealtimeLogger(logger *slog.Logger) RealtimeHandlerOption {
+	return func(h *RealtimeRetentionHandler) {
+		h.logger = logger
+	}
+}
+
+// RealtimeRetentionHandler is the http.Handler behind the URL you register for real-time retention messages. It
+// verifies the signed request the App Store posts when a customer cancels a subscription, asks the decision function
+// what to show, and writes the RealtimeResponseBody.
+//
+// Requests that fail verification get 401 and malformed ones 400. When the decision function fails, returns an
+// invalid response or misses the deadline, the handler still answers 200 with an empty body in time, so the App
+// Store shows the default message instead of waiting.
+// https://developer.apple.com/documentation/retentionmessaging/get-retention-message
+type RealtimeRetentionHandler struct {
+	verifier *SignedDataVerifier
+	decide   RetentionDecisionFunc
+	deadline time.Duration
+	logger   *slog.Logger
+}
+
+// NewRealtimeRetentionHandler creates a RealtimeRetentionHandler that verifies requests with verifier and answers them with decide
+func NewRealtimeRetentionHandler(verifier *SignedDataVerifier, decide RetentionDecisionFunc, options ...RealtimeHandlerOption) (*RealtimeRetentionHandler, error) {
+	if verifier == nil {
+		return nil, fmt.Errorf("verifier is required")
+	}
+	if decide == nil {
+		return nil, fmt.Errorf("decision function is required")
+	}
+
+	h := &RealtimeRetentionHandler{
+		verifier: verifier,
+		decide:   decide,
+		deadline: DefaultRealtimeDeadline,
+	}
+	for _, option := range options {
+		option(h)
+	}
+	h.logger = loggerOrDiscard(h.logger)
+	return h, nil
+}
+
+// ServeHTTP implements http.Handler
+func (h *RealtimeRetentionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.deadline)
+	defer cancel()
+
+	var body models.RealtimeRequestBody
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRealtimeRequestSize)).Decode(&body); err != nil || body.SignedPayload == nil {
+		h.logger.WarnContext(ctx, "malformed realtime retention request", slog.Any("error", err))
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	request, err := h.verifier.VerifyAndDecodeRealtimeRequestContext(ctx, *body.SignedPayload)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	response, err := h.decideWithin(ctx, request)
+	if err == nil && response != nil {
+		err = response.Validate()
+	}
+	if err != nil {
+		h.logger.WarnContext(ctx, "realtime retention decision failed, showing the default message",
+			slog.Any("requestIdentifier", request.RequestIdentifier),
+			slog.Any("error", err))
+		response = nil
+	}
+	if response == nil {
+		response = &models.RealtimeResponseBody{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.WarnContext(ctx, "failed to write realtime retention response", slog.Any("error", err))
+	}
+}
+
+// decideWithin runs the decision function and returns its result, or ctx's error if the deadline passes first
+func (h *RealtimeRetentionHandler) decideWithin(ctx context.Context, request *models.DecodedRealtimeRequestBody) (*models.RealtimeResponseBody, error) {
+	type result struct {
+		response *models.RealtimeResponseBody
+		err      error
+	}
+	// Buffered, so a decision that finishes after the deadline doesn't block forever
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				done <- result{err: fmt.Errorf("decision function panicked: %v", recovered)}
+			}
+		}()
+		response, err := h.decide(ctx, request)
+		done <- result{response: response, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.response, res.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("decision missed the %s deadline: %w", h.deadline, ctx.Err())
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// RealtimeRetentionHandler creates a RealtimeRetentionHandler that verifies requests with the client's verifier
+func (c *AppStoreServerClient) RealtimeRetentionHandler(decide RetentionDecisionFunc, options ...RealtimeHandlerOption) (*RealtimeRetentionHandler, error) {
+	return NewRealtimeRetentionHandler(c.verifier, decide, options...)
+}
@@ -0,0 +1,195 @@
+package appstore_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DotNetAge/appstore"
+	"github.com/DotNetAge/appstore/appstoretest"
+	"github.com/DotNetAge/appstore/models"
+)
+
+const realtimeMessageID = "0f7b0b6c-2a7d-4c0e-9b1a-6f3e2d1c0b9a"
+
+// newRealtimeRequest returns a real-time retention request body signed by ca
+func newRealtimeRequest(t *testing.T, ca *appstoretest.CertificateAuthority) []byte {
+	t.Helper()
+	signed, err := ca.SignRealtimeRequest(models.DecodedRealtimeRequestBody{
+		OriginalTransactionId: ptr("2000000000000001"),
+		AppAppleId:            ptr(int64(1234567890)),
+		ProductId:             ptr("com.example.monthly"),
+		UserLocale:            ptr("en-US"),
+		RequestIdentifier:     ptr("5e8a1b2c-3d4f-4a6b-8c9d-0e1f2a3b4c5d"),
+		Environment:           ptr(models.EnvironmentSandbox),
+		SignedDate:            ptr(time.Now().UnixMilli()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(models.RealtimeRequestBody{SignedPayload: &signed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+// newRealtimeHandler returns a handler trusting ca that answers with decide
+func newRealtimeHandler(t *testing.T, ca *appstoretest.CertificateAuthority, decide appstore.RetentionDecisionFunc) *appstore.RealtimeRetentionHandler {
+	t.Helper()
+	verifier, err := ca.NewVerifier(models.EnvironmentSandbox, appstoretest.DefaultBundleID, ptr(int64(1234567890)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := appstore.NewRealtimeRetentionHandler(verifier, decide)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return handler
+}
+
+// showMessage is a decision that shows the message realtimeMessageID to subscribers of com.example.monthly
+func showMessage(ctx context.Context, request *models.DecodedRealtimeRequestBody) (*models.RealtimeResponseBody, error) {
+	if request.ProductId == nil || *request.ProductId != "com.example.monthly" {
+		return nil, errors.New("unexpected product")
+	}
+	return &models.RealtimeResponseBody{Message: &models.RealtimeMessage{MessageIdentifier: ptr(realtimeMessageID)}}, nil
+}
+
+func TestRealtimeRetentionHandler(t *testing.T) {
+	ca, err := appstoretest.NewCertificateAuthority()
+	if err != nil {
+		t.Fatal(err)
+	}
+	untrusted, err := appstoretest.NewCertificateAuthority()
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := `{"message":{"messageIdentifier":"` + realtimeMessageID + `"}}`
+
+	tests := []struct {
+		name       string
+		method     string
+		body       []byte
+		decide     appstore.RetentionDecisionFunc
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "valid request", body: newRealtimeRequest(t, ca), decide: showMessage, wantStatus: http.StatusOK, wantBody: message},
+		{name: "signed by another authority", body: newRealtimeRequest(t, untrusted), decide: showMessage, wantStatus: http.StatusUnauthorized},
+		{name: "malformed JSON", body: []byte(`{"signedPayload":`), decide: showMessage, wantStatus: http.StatusBadRequest},
+		{name: "missing signed payload", body: []byte(`{}`), decide: showMessage, wantStatus: http.StatusBadRequest},
+		{name: "GET", method: http.MethodGet, decide: showMessage, wantStatus: http.StatusMethodNotAllowed},
+		{
+			name: "no decision",
+			body: newRealtimeRequest(t, ca),
+			decide: func(ctx context.Context, request *models.DecodedRealtimeRequestBody) (*models.RealtimeResponseBody, error) {
+				return nil, nil
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `{}`,
+		},
+		{
+			name: "failed decision",
+			body: newRealtimeRequest(t, ca),
+			decide: func(ctx context.Context, request *models.DecodedRealtimeRequestBody) (*models.RealtimeResponseBody, error) {
+				return nil, errors.New("database unavailable")
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `{}`,
+		},
+		{
+			name: "invalid decision",
+			body: newRealtimeRequest(t, ca),
+			decide: func(ctx context.Context, request *models.DecodedRealtimeRequestBody) (*models.RealtimeResponseBody, error) {
+				return &models.RealtimeResponseBody{Message: &models.RealtimeMessage{MessageIdentifier: ptr("not-a-uuid")}}, nil
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `{}`,
+		},
+		{
+			name: "panicking decision",
+			body: newRealtimeRequest(t, ca),
+			decide: func(ctx context.Context, request *models.DecodedRealtimeRequestBody) (*models.RealtimeResponseBody, error) {
+				panic("decision bug")
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `{}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			recorder := httptest.NewRecorder()
+			newRealtimeHandler(t, ca, tt.decide).ServeHTTP(recorder, httptest.NewRequest(method, "/retention", bytes.NewReader(tt.body)))
+
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" {
+				if got := strings.TrimSpace(recorder.Body.String()); got != tt.wantBody {
+					t.Errorf("body = %s, want %s", got, tt.wantBody)
+				}
+				if got := recorder.Header().Get("Content-Type"); got != "application/json" {
+					t.Errorf("Content-Type = %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestRealtimeRetentionHandlerDeadline(t *testing.T) {
+	ca, err := appstoretest.NewCertificateAuthority()
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	defer close(release)
+	cancelled := make(chan struct{})
+	handler := newRealtimeHandler(t, ca, func(ctx context.Context, request *models.DecodedRealtimeRequestBody) (*models.RealtimeResponseBody, error) {
+		<-ctx.Done()
+		close(cancelled)
+		// Keep running past the deadline, like a decision stuck on a slow dependency
+		<-release
+		return showMessage(ctx, request)
+	})
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	start := time.Now()
+	resp, err := http.Post(server.URL, "application/json", bytes.NewReader(newRealtimeRequest(t, ca)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	elapsed := time.Since(start)
+
+	var body models.RealtimeResponseBody
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || !reflect.DeepEqual(body, models.RealtimeResponseBody{}) {
+		t.Errorf("got %d %+v, want 200 with an empty body", resp.StatusCode, body)
+	}
+	if elapsed > appstore.DefaultRealtimeDeadline+200*time.Millisecond {
+		t.Errorf("answered after %s, want within the %s deadline", elapsed, appstore.DefaultRealtimeDeadline)
+	}
+	if elapsed < appstore.DefaultRealtimeDeadline {
+		t.Errorf("answered after %s, before the %s deadline", elapsed, appstore.DefaultRealtimeDeadline)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("the decision's context wasn't cancelled at the deadline")
+	}
+}
@@ -1,17 +1,22 @@
 package appstore
 
 import (
+	"context"
 	"crypto"
 	"crypto/x509"
 	"encoding/asn1"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
 	"time"
 
+	"github.com/DotNetAge/appstore/internal/apple"
 	"github.com/DotNetAge/appstore/models"
 	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // VerificationStatus represents the status of a verification operation
@@ -34,6 +39,27 @@ const (
 	VerificationStatusInvalidEnvironment VerificationStatus = 6
 )
 
+// String returns the name of the verification status
+func (s VerificationStatus) String() string {
+	switch s {
+	case VerificationStatusOK:
+		return "OK"
+	case VerificationStatusVerificationFailure:
+		return "VerificationFailure"
+	case VerificationStatusInvalidAppIdentifier:
+		return "InvalidAppIdentifier"
+	case VerificationStatusInvalidCertificate:
+		return "InvalidCertificate"
+	case VerificationStatusInvalidChainLength:
+		return "InvalidChainLength"
+	case VerificationStatusInvalidChain:
+		return "InvalidChain"
+	case VerificationStatusInvalidEnvironment:
+		return "InvalidEnvironment"
+	}
+	return fmt.Sprintf("VerificationStatus(%d)", int(s))
+}
+
 // VerificationException represents an exception that occurs during verification
 type VerificationException struct {
 	Status VerificationStatus
@@ -55,10 +81,25 @@ type SignedDataVerifier struct {
 	bundleID           string
 	appAppleID         *int64
 	enableOnlineChecks bool
+	tracerProvider     trace.TracerProvider
+	tracer             trace.Tracer
+	metrics            *Metrics
+	logger             *slog.Logger
+}
+
+// VerifierOption is a function type for configuring a SignedDataVerifier
+type VerifierOption func(*SignedDataVerifier)
+
+// WithVerifierTracerProvider sets the OpenTelemetry tracer provider used to create verification spans.
+// The global tracer provider is used by default.
+func WithVerifierTracerProvider(provider trace.TracerProvider) VerifierOption {
+	return func(v *SignedDataVerifier) {
+		v.tracerProvider = provider
+	}
 }
 
 // NewSignedDataVerifier creates a new SignedDataVerifier
-func NewSignedDataVerifier(rootCertificates [][]byte, enableOnlineChecks bool, environment models.Environment, bundleID string, appAppleID *int64) (*SignedDataVerifier, error) {
+func NewSignedDataVerifier(rootCertificates [][]byte, enableOnlineChecks bool, environment models.Environment, bundleID string, appAppleID *int64, options ...VerifierOption) (*SignedDataVerifier, error) {
 	if environment == models.EnvironmentProduction && appAppleID == nil {
 		return nil, fmt.Errorf("appAppleID is required when the environment is Production")
 	}
@@ -68,20 +109,37 @@ func NewSignedDataVerifier(rootCertificates [][]byte, enableOnlineChecks bool, e
 		return nil, err
 	}
 
-	return &SignedDataVerifier{
+	verifier := &SignedDataVerifier{
 		chainVerifier:      chainVerifier,
 		environment:        environment,
 		bundleID:           bundleID,
 		appAppleID:         appAppleID,
 		enableOnlineChecks: enableOnlineChecks,
-	}, nil
+	}
+	for _, option := range options {
+		option(verifier)
+	}
+	verifier.tracer = newTracer(verifier.tracerProvider)
+	verifier.logger = loggerOrDiscard(verifier.logger)
+
+	return verifier, nil
 }
 
 // VerifyAndDecodeRenewalInfo verifies and decodes a signedRenewalInfo obtained from the App Store Server API
 // https://developer.apple.com/documentation/appstoreserverapi/jwsrenewalinfo
 func (v *SignedDataVerifier) VerifyAndDecodeRenewalInfo(signedRenewalInfo string) (*models.JWSRenewalInfoDecodedPayload, error) {
+	return v.VerifyAndDecodeRenewalInfoContext(context.Background(), signedRenewalInfo)
+}
+
+// VerifyAndDecodeRenewalInfoContext is like VerifyAndDecodeRenewalInfo but records its spans under the span carried by ctx
+func (v *SignedDataVerifier) VerifyAndDecodeRenewalInfoContext(ctx context.Context, signedRenewalInfo string) (_ *models.JWSRenewalInfoDecodedPayload, err error) {
+	ctx, span := v.tracer.Start(ctx, "SignedDataVerifier.VerifyAndDecodeRenewalInfo")
+	defer func() {
+		v.finishVerification(ctx, span, "renewal_info", signedRenewalInfo, err)
+	}()
+
 	// Decode the signed object
-	decoded, err := v.decodeSignedObject(signedRenewalInfo)
+	decoded, err := v.decodeSignedObject(ctx, signedRenewalInfo)
 	if err != nil {
 		return nil, err
 	}
@@ -105,8 +163,18 @@ func (v *SignedDataVerifier) VerifyAndDecodeRenewalInfo(signedRenewalInfo string
 // VerifyAndDecodeSignedTransaction verifies and decodes a signedTransaction obtained from the App Store Server API
 // https://developer.apple.com/documentation/appstoreserverapi/jwstransaction
 func (v *SignedDataVerifier) VerifyAndDecodeSignedTransaction(signedTransaction string) (*models.JWSTransactionDecodedPayload, error) {
+	return v.VerifyAndDecodeSignedTransactionContext(context.Background(), signedTransaction)
+}
+
+// VerifyAndDecodeSignedTransactionContext is like VerifyAndDecodeSignedTransaction but records its spans under the span carried by ctx
+func (v *SignedDataVerifier) VerifyAndDecodeSignedTransactionContext(ctx context.Context, signedTransaction string) (_ *models.JWSTransactionDecodedPayload, err error) {
+	ctx, span := v.tracer.Start(ctx, "SignedDataVerifier.VerifyAndDecodeSignedTransaction")
+	defer func() {
+		v.finishVerification(ctx, span, "transaction", signedTransaction, err)
+	}()
+
 	// Decode the signed object
-	decoded, err := v.decodeSignedObject(signedTransaction)
+	decoded, err := v.decodeSignedObject(ctx, signedTransaction)
 	if err != nil {
 		return nil, err
 	}
@@ -137,8 +205,18 @@ func (v *SignedDataVerifier) VerifyAndDecodeSignedTransaction(signedTransaction
 // VerifyAndDecodeNotification verifies and decodes an App Store Server Notification signedPayload
 // https://developer.apple.com/documentation/appstoreservernotifications/signedpayload
 func (v *SignedDataVerifier) VerifyAndDecodeNotification(signedPayload string) (*models.ResponseBodyV2DecodedPayload, error) {
+	return v.VerifyAndDecodeNotificationContext(context.Background(), signedPayload)
+}
+
+// VerifyAndDecodeNotificationContext is like VerifyAndDecodeNotification but records its spans under the span carried by ctx
+func (v *SignedDataVerifier) VerifyAndDecodeNotificationContext(ctx context.Context, signedPayload string) (_ *models.ResponseBodyV2DecodedPayload, err error) {
+	ctx, span := v.tracer.Start(ctx, "SignedDataVerifier.VerifyAndDecodeNotification")
+	defer func() {
+		v.finishVerification(ctx, span, "notification", signedPayload, err)
+	}()
+
 	// Decode the signed object
-	decoded, err := v.decodeSignedObject(signedPayload)
+	decoded, err := v.decodeSignedObject(ctx, signedPayload)
 	if err != nil {
 		return nil, err
 	}
@@ -192,8 +270,18 @@ func (v *SignedDataVerifier) VerifyAndDecodeNotification(signedPayload string) (
 // VerifyAndDecodeAppTransaction verifies and decodes a signed AppTransaction
 // https://developer.apple.com/documentation/storekit/apptransaction
 func (v *SignedDataVerifier) VerifyAndDecodeAppTransaction(signedAppTransaction string) (*models.AppTransaction, error) {
+	return v.VerifyAndDecodeAppTransactionContext(context.Background(), signedAppTransaction)
+}
+
+// VerifyAndDecodeAppTransactionContext is like VerifyAndDecodeAppTransaction but records its spans under the span carried by ctx
+func (v *SignedDataVerifier) VerifyAndDecodeAppTransactionContext(ctx context.Context, signedAppTransaction string) (_ *models.AppTransaction, err error) {
+	ctx, span := v.tracer.Start(ctx, "SignedDataVerifier.VerifyAndDecodeAppTransaction")
+	defer func() {
+		v.finishVerification(ctx, span, "app_transaction", signedAppTransaction, err)
+	}()
+
 	// Decode the signed object
-	decoded, err := v.decodeSignedObject(signedAppTransaction)
+	decoded, err := v.decodeSignedObject(ctx, signedAppTransaction)
 	if err != nil {
 		return nil, err
 	}
@@ -227,6 +315,68 @@ func (v *SignedDataVerifier) VerifyAndDecodeAppTransaction(signedAppTransaction
 	return &appTransaction, nil
 }
 
+// VerifyAndDecodeRealtimeRequest verifies and decodes the signedPayload of a real-time retention message request
+// https://developer.apple.com/documentation/retentionmessaging/decodedrealtimerequestbody
+func (v *SignedDataVerifier) VerifyAndDecodeRealtimeRequest(signedPayload string) (*models.DecodedRealtimeRequestBody, error) {
+	return v.VerifyAndDecodeRealtimeRequestContext(context.Background(), signedPayload)
+}
+
+// VerifyAndDecodeRealtimeRequestContext is like VerifyAndDecodeRealtimeRequest but records its spans under the span carried by ctx
+func (v *SignedDataVerifier) VerifyAndDecodeRealtimeRequestContext(ctx context.Context, signedPayload string) (_ *models.DecodedRealtimeRequestBody, err error) {
+	ctx, span := v.tracer.Start(ctx, "SignedDataVerifier.VerifyAndDecodeRealtimeRequest")
+	defer func() {
+		v.finishVerification(ctx, span, "realtime_request", signedPayload, err)
+	}()
+
+	// Decode the signed object
+	decoded, err := v.decodeSignedObject(ctx, signedPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal the decoded data into the payload struct
+	var payload models.DecodedRealtimeRequestBody
+	if err := json.Unmarshal(decoded, &payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal DecodedRealtimeRequestBody: %w", err)
+	}
+
+	// Verify the app Apple ID; the request carries no bundle ID
+	if v.environment == models.EnvironmentProduction && (payload.AppAppleId == nil || *payload.AppAppleId != *v.appAppleID) {
+		return nil, &VerificationException{
+			Status: VerificationStatusInvalidAppIdentifier,
+		}
+	}
+
+	// Verify the environment
+	if payload.Environment == nil || *payload.Environment != v.environment {
+		return nil, &VerificationException{
+			Status: VerificationStatusInvalidEnvironment,
+		}
+	}
+
+	return &payload, nil
+}
+
+// finishVerification reports the outcome of a verification to the span, the metrics and the logger
+func (v *SignedDataVerifier) finishVerification(ctx context.Context, span trace.Span, payloadType string, signedData string, err error) {
+	endSpan(span, err)
+	v.metrics.observeVerification(payloadType, err)
+
+	if err != nil {
+		attrs := []slog.Attr{
+			slog.String("type", payloadType),
+			slog.String("environment", string(v.environment)),
+			slog.Any("payload", redactedJWS(signedData)),
+			slog.Any("error", err),
+		}
+		var verificationErr *VerificationException
+		if errors.As(err, &verificationErr) {
+			attrs = append(attrs, slog.String("status", verificationErr.Status.String()))
+		}
+		v.logger.LogAttrs(ctx, slog.LevelWarn, "signed data verification failed", attrs...)
+	}
+}
+
 // verifyNotification verifies the notification's bundle ID, app Apple ID, and environment
 func (v *SignedDataVerifier) verifyNotification(bundleID string, appAppleID *int64, environment models.Environment) error {
 	// Verify the bundle ID
@@ -254,13 +404,14 @@ func (v *SignedDataVerifier) verifyNotification(bundleID string, appAppleID *int
 }
 
 // decodeSignedObject decodes a signed object from the App Store
-func (v *SignedDataVerifier) decodeSignedObject(signedObj string) ([]byte, error) {
-	// Parse the JWT without verification to get the headers and claims
-	parser := jwt.NewParser()
-	token, err := parser.Parse(signedObj, func(token *jwt.Token) (interface{}, error) {
-		// We'll verify the signature later
-		return nil, nil
-	})
+func (v *SignedDataVerifier) decodeSignedObject(ctx context.Context, signedObj string) ([]byte, error) {
+	// Parse the JWT without verification to read the x5c chain and the signed date, which are needed to find the
+	// signing key. Nothing from this unverified parse is returned: the payload comes from the second parse below,
+	// which checks the ES256 signature against the public key of the leaf certificate once the chain is verified.
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{"ES256"}))
+	_, parseSpan := v.tracer.Start(ctx, "SignedDataVerifier.ParseJWS")
+	token, _, err := parser.ParseUnverified(signedObj, jwt.MapClaims{})
+	endSpan(parseSpan, err)
 	if err != nil {
 		return nil, &VerificationException{
 			Status: VerificationStatusVerificationFailure,
@@ -334,15 +485,19 @@ func (v *SignedDataVerifier) decodeSignedObject(signedObj string) ([]byte, error
 	}
 
 	// Verify the certificate chain and get the signing key
+	_, chainSpan := v.tracer.Start(ctx, "SignedDataVerifier.VerifyChain")
 	signingKey, err := v.chainVerifier.verifyChain(certChain, v.enableOnlineChecks, effectiveDate)
+	endSpan(chainSpan, err)
 	if err != nil {
 		return nil, err
 	}
 
 	// Parse and verify the token with the signing key
+	_, signatureSpan := v.tracer.Start(ctx, "SignedDataVerifier.VerifySignature")
 	verifiedToken, err := parser.Parse(signedObj, func(token *jwt.Token) (interface{}, error) {
 		return signingKey, nil
 	})
+	endSpan(signatureSpan, err)
 	if err != nil {
 		return nil, &VerificationException{
 			Status: VerificationStatusVerificationFailure,
@@ -455,11 +610,11 @@ func (cv *chainVerifier) verifyChain(certificates []string, performOnlineChecks
 	}
 
 	// Check the OIDs
-	if err := cv.checkOID(parsedCerts[0], "1.2.840.113635.100.6.11.1"); err != nil {
+	if err := cv.checkOID(parsedCerts[0], apple.LeafCertificateOID); err != nil {
 		return nil, err
 	}
 
-	if err := cv.checkOID(parsedCerts[1], "1.2.840.113635.100.6.2.1"); err != nil {
+	if err := cv.checkOID(parsedCerts[1], apple.IntermediateCertificateOID); err != nil {
 		return nil, err
 	}
 
@@ -473,16 +628,7 @@ func (cv *chainVerifier) verifyChain(certificates []string, performOnlineChecks
 }
 
 // checkOID checks if the certificate has the specified OID
-func (cv *chainVerifier) checkOID(cert *x509.Certificate, oidStr string) error {
-	// Parse OID string manually
-	var oid asn1.ObjectIdentifier
-	if _, err := fmt.Sscanf(oidStr, "%d.%d.%d.%d.%d.%d.%d.%d", &oid[0], &oid[1], &oid[2], &oid[3], &oid[4], &oid[5], &oid[6], &oid[7]); err != nil {
-		return &VerificationException{
-			Status: VerificationStatusVerificationFailure,
-			Err:    fmt.Errorf("invalid OID %s: %w", oidStr, err),
-		}
-	}
-
+func (cv *chainVerifier) checkOID(cert *x509.Certificate, oid asn1.ObjectIdentifier) error {
 	for _, ext := range cert.Extensions {
 		if ext.Id.Equal(oid) {
 			return nil
@@ -491,6 +637,6 @@ func (cv *chainVerifier) checkOID(cert *x509.Certificate, oidStr string) error {
 
 	return &VerificationException{
 		Status: VerificationStatusVerificationFailure,
-		Err:    fmt.Errorf("certificate missing required OID %s", oidStr),
+		Err:    fmt.Errorf("certificate missing required OID %s", oid),
 	}
 }
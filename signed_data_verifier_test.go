@@ -0,0 +1,141 @@
+package appstore_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DotNetAge/appstore"
+	"github.com/DotNetAge/appstore/appstoretest"
+	"github.com/DotNetAge/appstore/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ptr returns a pointer to v
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// tamper sets a claim in the payload of a compact JWS and keeps the original signature
+func tamper(t *testing.T, signed, claim string, value interface{}) string {
+	t.Helper()
+	parts := strings.Split(signed, ".")
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := map[string]interface{}{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	claims[claim] = value
+	payload, err = json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+	return strings.Join(parts, ".")
+}
+
+// newTestVerifier returns a certificate authority and a Sandbox verifier for com.example that trusts it
+func newTestVerifier(t *testing.T) (*appstoretest.CertificateAuthority, *appstore.SignedDataVerifier) {
+	t.Helper()
+	ca, err := appstoretest.NewCertificateAuthority()
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := ca.NewVerifier(models.EnvironmentSandbox, "com.example", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ca, verifier
+}
+
+func TestVerifyAndDecodeSignedTransaction(t *testing.T) {
+	ca, verifier := newTestVerifier(t)
+	environment := models.EnvironmentSandbox
+	signed, err := ca.SignTransaction(models.JWSTransactionDecodedPayload{
+		TransactionId: ptr("1000"),
+		BundleId:      ptr("com.example"),
+		Environment:   &environment,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transaction, err := verifier.VerifyAndDecodeSignedTransaction(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *transaction.TransactionId != "1000" {
+		t.Errorf("transactionId = %s", *transaction.TransactionId)
+	}
+}
+
+func TestVerifierRejectsTamperedData(t *testing.T) {
+	ca, verifier := newTestVerifier(t)
+	environment := models.EnvironmentSandbox
+	signed, err := ca.SignTransaction(models.JWSTransactionDecodedPayload{
+		TransactionId: ptr("1000"),
+		BundleId:      ptr("com.example"),
+		Environment:   &environment,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A payload signed by another key, carrying the trusted certificate chain
+	forgedKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{"transactionId": "1000", "bundleId": "com.example", "environment": "Sandbox"})
+	chain := make([]string, 0, 3)
+	for _, der := range ca.Chain() {
+		chain = append(chain, base64.StdEncoding.EncodeToString(der))
+	}
+	forged.Header["x5c"] = chain
+	forgedSigned, err := forged.SignedString(forgedKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A payload signed by a certificate authority the verifier doesn't trust
+	other, err := appstoretest.NewCertificateAuthority()
+	if err != nil {
+		t.Fatal(err)
+	}
+	untrusted, err := other.SignTransaction(models.JWSTransactionDecodedPayload{BundleId: ptr("com.example"), Environment: &environment})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		signed string
+		status appstore.VerificationStatus
+	}{
+		{name: "modified claim", signed: tamper(t, signed, "transactionId", "2000"), status: appstore.VerificationStatusVerificationFailure},
+		{name: "modified signed date", signed: tamper(t, signed, "signedDate", 1), status: appstore.VerificationStatusVerificationFailure},
+		{name: "forged signature", signed: forgedSigned, status: appstore.VerificationStatusVerificationFailure},
+		{name: "untrusted root", signed: untrusted, status: appstore.VerificationStatusVerificationFailure},
+		{name: "not a JWS", signed: "not.a.jws", status: appstore.VerificationStatusVerificationFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := verifier.VerifyAndDecodeSignedTransaction(tt.signed)
+			var verificationErr *appstore.VerificationException
+			if !errors.As(err, &verificationErr) {
+				t.Fatalf("got %v, want a VerificationException", err)
+			}
+			if verificationErr.Status != tt.status {
+				t.Errorf("status = %s, want %s", verificationErr.Status, tt.status)
+			}
+		})
+	}
+}
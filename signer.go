@@ -0,0 +1,77 @@
+package appstore
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// WithSigner signs the bearer tokens with the given crypto.Signer instead of the signingKey bytes,
+// so the private key can stay in a KMS, an HSM or an agent. The signer must hold a P-256 ECDSA key.
+func WithSigner(signer crypto.Signer) ClientOption {
+	return func(opts *clientOptions) {
+		opts.signer = signer
+	}
+}
+
+// checkSigner ensures the signer holds a key that can produce ES256 signatures
+func checkSigner(signer crypto.Signer) error {
+	publicKey, ok := signer.Public().(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: signer holds a %T public key", ErrUnsupportedKeyType, signer.Public())
+	}
+	if publicKey.Curve != elliptic.P256() {
+		return fmt.Errorf("%w: signer uses %s", ErrUnsupportedCurve, publicKey.Curve.Params().Name)
+	}
+	return nil
+}
+
+// signES256 signs the SHA-256 digest of data and returns the ASN.1 DER-encoded ECDSA signature
+func signES256(signer crypto.Signer, data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+	signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+	return signature, nil
+}
+
+// signJWS signs the token with ES256 using the signer and returns its compact serialization
+func signJWS(token *jwt.Token, signer crypto.Signer) (string, error) {
+	signingString, err := token.SigningString()
+	if err != nil {
+		return "", err
+	}
+
+	der, err := signES256(signer, []byte(signingString))
+	if err != nil {
+		return "", err
+	}
+
+	// JWS uses the fixed-size R || S encoding rather than ASN.1 DER
+	var parsed struct {
+		R, S *big.Int
+	}
+	rest, err := asn1.Unmarshal(der, &parsed)
+	if err != nil {
+		return "", fmt.Errorf("signer returned an invalid ECDSA signature: %w", err)
+	}
+	if len(rest) != 0 {
+		return "", fmt.Errorf("signer returned an invalid ECDSA signature: %d trailing bytes", len(rest))
+	}
+	if parsed.R.Sign() <= 0 || parsed.S.Sign() <= 0 || parsed.R.BitLen() > 256 || parsed.S.BitLen() > 256 {
+		return "", fmt.Errorf("signer returned an invalid ECDSA signature: R and S must be positive and at most 256 bits")
+	}
+	signature := make([]byte, 64)
+	parsed.R.FillBytes(signature[:32])
+	parsed.S.FillBytes(signature[32:])
+
+	return signingString + "." + token.EncodeSegment(signature), nil
+}
@@ -0,0 +1,250 @@
+package appstore
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"io"
+	"math/big"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/DotNetAge/appstore/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testSigner is a software crypto.Signer standing in for a KMS or agent; it only exposes Public and Sign
+type testSigner struct {
+	key   *ecdsa.PrivateKey
+	signs atomic.Int32
+}
+
+// newTestSigner returns a testSigner holding a new P-256 key
+func newTestSigner(t testing.TB) *testSigner {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testSigner{key: key}
+}
+
+// Public implements crypto.Signer
+func (s *testSigner) Public() crypto.PublicKey {
+	return &s.key.PublicKey
+}
+
+// Sign implements crypto.Signer
+func (s *testSigner) Sign(random io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.signs.Add(1)
+	return s.key.Sign(random, digest, opts)
+}
+
+// parseES256 verifies a compact JWS with the public key and returns its claims
+func parseES256(t *testing.T, token string, publicKey *ecdsa.PublicKey) (*jwt.Token, jwt.MapClaims) {
+	t.Helper()
+	claims := jwt.MapClaims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return publicKey, nil
+	}, jwt.WithValidMethods([]string{"ES256"}))
+	if err != nil {
+		t.Fatalf("signature doesn't verify: %v", err)
+	}
+	return parsed, claims
+}
+
+func TestBearerTokenWithSigner(t *testing.T) {
+	signer := newTestSigner(t)
+	client, err := NewAppStoreServerAPIClientWithOptions(nil, "KEY_ID", "ISSUER_ID", "com.example", models.EnvironmentSandbox,
+		WithSigner(signer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, _, err := client.generateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, claims := parseES256(t, token, &signer.key.PublicKey)
+	if parsed.Header["kid"] != "KEY_ID" || claims["iss"] != "ISSUER_ID" {
+		t.Errorf("unexpected token %v %v", parsed.Header, claims)
+	}
+	if signer.signs.Load() != 1 {
+		t.Errorf("signer used %d times, want 1", signer.signs.Load())
+	}
+}
+
+func TestPromotionalOfferSignatureWithSigner(t *testing.T) {
+	signer := newTestSigner(t)
+	creator, err := NewPromotionalOfferSignatureCreatorWithSigner(signer, "KEY_ID", "com.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signature, err := creator.CreateSignature("com.example.monthly", "offer", "User", "9F2E1AAB-0D1C-4B37-8E43-D5A29E6F1C3B", 1700000000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Apple's payload format, with the application username and nonce lowercased
+	payload := strings.Join([]string{"com.example", "KEY_ID", "com.example.monthly", "offer", "user", "9f2e1aab-0d1c-4b37-8e43-d5a29e6f1c3b", "1700000000000"}, "\u2063")
+	digest := sha256.Sum256([]byte(payload))
+	if !ecdsa.VerifyASN1(&signer.key.PublicKey, digest[:], der) {
+		t.Fatal("the ASN.1 DER signature doesn't verify")
+	}
+}
+
+func TestSignJWSWithSigner(t *testing.T) {
+	signer := newTestSigner(t)
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{"sub": "test"})
+
+	signed, err := signJWS(token, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, claims := parseES256(t, signed, &signer.key.PublicKey); claims["sub"] != "test" {
+		t.Errorf("sub = %v", claims["sub"])
+	}
+	// JWS uses the 64-byte R || S encoding, not ASN.1 DER
+	signature, err := base64.RawURLEncoding.DecodeString(signed[strings.LastIndex(signed, ".")+1:])
+	if err != nil || len(signature) != 64 {
+		t.Errorf("signature is %d bytes, want 64", len(signature))
+	}
+}
+
+func TestAdvancedCommerceSignatureWithSigner(t *testing.T) {
+	signer := newTestSigner(t)
+	creator, err := NewAdvancedCommerceInAppSignatureCreatorWithSigner(signer, "KEY_ID", "ISSUER_ID", "com.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := creator.CreateSignature(&models.OneTimeChargeCreateRequest{
+		RequestInfo: &models.AdvancedCommerceRequestInfo{RequestReferenceId: ptr("11111111-1111-1111-1111-111111111111")},
+		Currency:    ptr("USD"),
+		Item:        &models.OneTimeChargeItem{SKU: ptr("sku"), Description: ptr("An item"), DisplayName: ptr("Item"), Price: ptr(int64(1990))},
+		TaxCode:     ptr("C003-00-1"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parseES256(t, signed, &signer.key.PublicKey)
+}
+
+func TestCheckSigner(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		signer crypto.Signer
+		want   error
+	}{
+		{name: "P-256", signer: newTestSigner(t)},
+		{name: "RSA", signer: rsaKey, want: ErrUnsupportedKeyType},
+		{name: "P-384", signer: p384Key, want: ErrUnsupportedCurve},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkSigner(tt.signer); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+			if _, err := NewPromotionalOfferSignatureCreatorWithSigner(tt.signer, "KEY_ID", "com.example"); !errors.Is(err, tt.want) {
+				t.Errorf("creator: got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// staticSigner is a crypto.Signer with a P-256 public key that returns a fixed signature, like a misbehaving KMS
+type staticSigner struct {
+	public    crypto.PublicKey
+	signature []byte
+}
+
+// Public implements crypto.Signer
+func (s *staticSigner) Public() crypto.PublicKey {
+	return s.public
+}
+
+// Sign implements crypto.Signer
+func (s *staticSigner) Sign(io.Reader, []byte, crypto.SignerOpts) ([]byte, error) {
+	return s.signature, nil
+}
+
+func TestSignJWSRejectsInvalidSignatures(t *testing.T) {
+	type ecdsaSignature struct {
+		R, S *big.Int
+	}
+	encode := func(r, s *big.Int) []byte {
+		der, err := asn1.Marshal(ecdsaSignature{R: r, S: s})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return der
+	}
+	valid := encode(big.NewInt(1), big.NewInt(2))
+	tooLong := new(big.Int).Lsh(big.NewInt(1), 256)
+
+	tests := []struct {
+		name      string
+		signature []byte
+		wantErr   bool
+	}{
+		{name: "valid", signature: valid},
+		{name: "not DER", signature: []byte("not a signature"), wantErr: true},
+		{name: "trailing bytes", signature: append(append([]byte{}, valid...), 0x00), wantErr: true},
+		{name: "R longer than 32 bytes", signature: encode(tooLong, big.NewInt(2)), wantErr: true},
+		{name: "S longer than 32 bytes", signature: encode(big.NewInt(1), tooLong), wantErr: true},
+		{name: "negative R", signature: encode(big.NewInt(-1), big.NewInt(2)), wantErr: true},
+		{name: "zero S", signature: encode(big.NewInt(1), big.NewInt(0)), wantErr: true},
+	}
+	public := &newTestSigner(t).key.PublicKey
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{"sub": "test"})
+			_, err := signJWS(token, &staticSigner{public: public, signature: tt.signature})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("signJWS error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMissingSigningKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		signingKey []byte
+	}{
+		{name: "nil"},
+		{name: "empty", signingKey: []byte{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewAppStoreServerAPIClientWithOptions(tt.signingKey, "KEY_ID", "ISSUER_ID", "com.example", models.EnvironmentSandbox)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := client.generateToken(); !errors.Is(err, ErrInvalidSigningKey) {
+				t.Errorf("generateToken error = %v, want ErrInvalidSigningKey", err)
+			}
+		})
+	}
+}
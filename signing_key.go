@@ -0,0 +1,119 @@
+package appstore
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var (
+	// ErrInvalidSigningKey is returned when signing key data can't be parsed as a P-256 PKCS#8 or SEC1 key.
+	// Keys of another type or curve match it as well as ErrUnsupportedKeyType or ErrUnsupportedCurve.
+	ErrInvalidSigningKey = errors.New("invalid signing key")
+	// ErrUnsupportedKeyType is returned when a signing key isn't an ECDSA key
+	ErrUnsupportedKeyType = errors.New("unsupported signing key type: expected an ECDSA key")
+	// ErrUnsupportedCurve is returned when an ECDSA signing key doesn't use the P-256 curve required by ES256
+	ErrUnsupportedCurve = errors.New("unsupported signing key curve: expected P-256")
+)
+
+// LoadSigningKey parses an App Store Connect signing key, such as the contents of the .p8 file Apple provides.
+// The key may be PKCS#8 ("PRIVATE KEY") or SEC1 ("EC PRIVATE KEY"), PEM or DER encoded, and must be a P-256 ECDSA key.
+func LoadSigningKey(data []byte) (*ecdsa.PrivateKey, error) {
+	// Only PEM is trimmed; the last byte of a DER key may happen to be a whitespace character
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("%w: no key data", ErrInvalidSigningKey)
+	}
+
+	if !bytes.HasPrefix(trimmed, []byte("-----BEGIN")) {
+		return parseSigningKeyDER(data)
+	}
+
+	// Skip blocks such as "EC PARAMETERS" that openssl writes before the key
+	for rest := trimmed; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, fmt.Errorf("%w: no PRIVATE KEY or EC PRIVATE KEY PEM block found", ErrInvalidSigningKey)
+		}
+
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("%w: failed to parse PKCS#8 private key: %v", ErrInvalidSigningKey, err)
+			}
+			return checkSigningKey(key)
+		case "EC PRIVATE KEY":
+			key, err := x509.ParseECPrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("%w: failed to parse EC private key: %v", ErrInvalidSigningKey, err)
+			}
+			return checkSigningKey(key)
+		case "RSA PRIVATE KEY", "OPENSSH PRIVATE KEY":
+			return nil, fmt.Errorf("%w: %w: got a %s PEM block", ErrInvalidSigningKey, ErrUnsupportedKeyType, block.Type)
+		}
+	}
+}
+
+// LoadSigningKeyFromFile reads and parses a signing key file, such as the .p8 file Apple provides
+func LoadSigningKeyFromFile(path string) (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key file: %w", err)
+	}
+	return LoadSigningKey(data)
+}
+
+// LoadSigningKeyFromEnv parses the signing key held by an environment variable.
+// The value may be a PEM key, or the base64 encoding of a PEM or DER key.
+func LoadSigningKeyFromEnv(name string) (*ecdsa.PrivateKey, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || strings.TrimSpace(value) == "" {
+		return nil, fmt.Errorf("%w: environment variable %s is not set", ErrInvalidSigningKey, name)
+	}
+
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "-----BEGIN") {
+		return LoadSigningKey([]byte(value))
+	}
+
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		if data, err = base64.RawStdEncoding.DecodeString(value); err != nil {
+			return nil, fmt.Errorf("%w: environment variable %s is neither PEM nor base64: %v", ErrInvalidSigningKey, name, err)
+		}
+	}
+	return LoadSigningKey(data)
+}
+
+// parseSigningKeyDER parses a DER-encoded PKCS#8 or SEC1 private key
+func parseSigningKeyDER(der []byte) (*ecdsa.PrivateKey, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		return checkSigningKey(key)
+	}
+	key, err := x509.ParseECPrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%w: data is neither a PKCS#8 nor a SEC1 DER private key", ErrInvalidSigningKey)
+	}
+	return checkSigningKey(key)
+}
+
+// checkSigningKey ensures the parsed key can sign ES256 tokens
+func checkSigningKey(key interface{}) (*ecdsa.PrivateKey, error) {
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %w: got %T", ErrInvalidSigningKey, ErrUnsupportedKeyType, key)
+	}
+	if ecKey.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("%w: %w: got %s", ErrInvalidSigningKey, ErrUnsupportedCurve, ecKey.Curve.Params().Name)
+	}
+	return ecKey, nil
+}
@@ -0,0 +1,169 @@
+package appstore
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadSigningKey(t *testing.T) {
+	key, pkcs8PEM := newTestSigningKey(t)
+	pkcs8DER, _ := pem.Decode(pkcs8PEM)
+	sec1DER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sec1PEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1DER})
+	// openssl ecparam -genkey writes the curve parameters before the key
+	params := pem.EncodeToMemory(&pem.Block{Type: "EC PARAMETERS", Bytes: []byte{0x06, 0x08, 0x2a, 0x86, 0x48, 0xce, 0x3d, 0x03, 0x01, 0x07}})
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaDER, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384DER, err := x509.MarshalPKCS8PrivateKey(p384Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A DER key whose last byte is a whitespace character must not be trimmed
+	var whitespaceKey *ecdsa.PrivateKey
+	var whitespaceDER []byte
+	for whitespaceKey == nil {
+		candidate, _ := newTestSigningKey(t)
+		der, err := x509.MarshalPKCS8PrivateKey(candidate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if last := der[len(der)-1]; last == ' ' || last == '\n' || last == '\t' || last == '\r' || last == '\v' || last == '\f' {
+			whitespaceKey, whitespaceDER = candidate, der
+		}
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    *ecdsa.PrivateKey
+		wantErr []error
+	}{
+		{name: "PKCS#8 PEM", data: pkcs8PEM},
+		{name: "SEC1 PEM", data: sec1PEM},
+		{name: "PKCS#8 DER", data: pkcs8DER.Bytes},
+		{name: "SEC1 DER", data: sec1DER},
+		{name: "EC PARAMETERS block first", data: append(append([]byte{}, params...), sec1PEM...)},
+		{name: "surrounding whitespace", data: append(append([]byte("\n  "), pkcs8PEM...), '\n')},
+		{name: "DER ending in a whitespace byte", data: whitespaceDER, want: whitespaceKey},
+		{name: "empty", data: []byte("  \n"), wantErr: []error{ErrInvalidSigningKey}},
+		{name: "no key block", data: params, wantErr: []error{ErrInvalidSigningKey}},
+		{name: "garbage DER", data: []byte{0x30, 0x03, 0x02, 0x01, 0x01}, wantErr: []error{ErrInvalidSigningKey}},
+		{name: "RSA PKCS#8", data: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: rsaDER}), wantErr: []error{ErrInvalidSigningKey, ErrUnsupportedKeyType}},
+		{name: "RSA PKCS#1", data: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}), wantErr: []error{ErrInvalidSigningKey, ErrUnsupportedKeyType}},
+		{name: "P-384", data: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: p384DER}), wantErr: []error{ErrInvalidSigningKey, ErrUnsupportedCurve}},
+		{name: "P-384 DER", data: p384DER, wantErr: []error{ErrInvalidSigningKey, ErrUnsupportedCurve}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadSigningKey(tt.data)
+			if tt.wantErr != nil {
+				for _, want := range tt.wantErr {
+					if !errors.Is(err, want) {
+						t.Errorf("error = %v, want %v", err, want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := key
+			if tt.want != nil {
+				want = tt.want
+			}
+			if !got.Equal(want) {
+				t.Error("parsed a different key")
+			}
+		})
+	}
+}
+
+func TestLoadSigningKeyFromFile(t *testing.T) {
+	key, pkcs8PEM := newTestSigningKey(t)
+	path := filepath.Join(t.TempDir(), "AuthKey_KEY_ID.p8")
+	if err := os.WriteFile(path, pkcs8PEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadSigningKeyFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(key) {
+		t.Error("parsed a different key")
+	}
+
+	if _, err := LoadSigningKeyFromFile(filepath.Join(t.TempDir(), "missing.p8")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing file: error = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestLoadSigningKeyFromEnv(t *testing.T) {
+	key, pkcs8PEM := newTestSigningKey(t)
+	der, _ := pem.Decode(pkcs8PEM)
+
+	tests := []struct {
+		name    string
+		value   *string
+		wantErr bool
+	}{
+		{name: "PEM", value: ptr(string(pkcs8PEM))},
+		{name: "base64 PEM", value: ptr(base64.StdEncoding.EncodeToString(pkcs8PEM))},
+		{name: "base64 DER", value: ptr(base64.StdEncoding.EncodeToString(der.Bytes))},
+		{name: "unpadded base64 DER", value: ptr(base64.RawStdEncoding.EncodeToString(der.Bytes))},
+		{name: "not set", wantErr: true},
+		{name: "blank", value: ptr("  "), wantErr: true},
+		{name: "neither PEM nor base64", value: ptr("not a key!"), wantErr: true},
+	}
+	const name = "APPSTORE_TEST_SIGNING_KEY"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.value != nil {
+				t.Setenv(name, *tt.value)
+			} else {
+				// Register a restore with t.Setenv before unsetting the variable
+				t.Setenv(name, "")
+				os.Unsetenv(name)
+			}
+
+			got, err := LoadSigningKeyFromEnv(name)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSigningKey) {
+					t.Errorf("error = %v, want ErrInvalidSigningKey", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(key) {
+				t.Error("parsed a different key")
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package appstore
+
+import (
+	"context"
+	"errors"
+
+	"github.com/DotNetAge/appstore/models"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope name of the spans created by this package
+const tracerName = "github.com/DotNetAge/appstore"
+
+// WithTracerProvider sets the OpenTelemetry tracer provider used to create spans for API calls and signed data verification.
+// The global tracer provider is used by default.
+func WithTracerProvider(provider trace.TracerProvider) ClientOption {
+	return func(opts *clientOptions) {
+		opts.tracerProvider = provider
+	}
+}
+
+// newTracer returns the package tracer from the given provider, falling back to the global provider
+func newTracer(provider trace.TracerProvider) trace.Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return provider.Tracer(tracerName)
+}
+
+// tracingInterceptor creates a client span around every App Store Server API call
+type tracingInterceptor struct {
+	tracer      trace.Tracer
+	environment models.Environment
+}
+
+// Intercept implements Interceptor
+func (t *tracingInterceptor) Intercept(ctx context.Context, call *Call, next Invoker) error {
+	ctx, span := t.tracer.Start(ctx, "AppStoreServerAPI "+string(call.Endpoint),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("appstore.endpoint", string(call.Endpoint)),
+			attribute.String("appstore.environment", string(t.environment)),
+			attribute.String("http.request.method", call.Method),
+			attribute.String("url.path", call.Path),
+		),
+	)
+	defer span.End()
+
+	err := next(ctx, call)
+
+	span.SetAttributes(attribute.Int("appstore.attempts", call.Attempts))
+	if call.StatusCode != 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", call.StatusCode))
+	}
+	if err != nil {
+		var apiErr *models.APIException
+		if errors.As(err, &apiErr) && apiErr.RawAPIError != nil {
+			span.SetAttributes(
+				attribute.Int("appstore.api_error.code", int(apiErr.APIError)),
+				attribute.String("appstore.api_error.name", apiErr.APIError.String()),
+			)
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// endSpan records err, if any, on the span and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		var verificationErr *VerificationException
+		if errors.As(err, &verificationErr) {
+			span.SetAttributes(attribute.Int("appstore.verification.status", int(verificationErr.Status)))
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
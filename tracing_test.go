@@ -0,0 +1,286 @@
+package appstore_test
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/DotNetAge/appstore"
+	"github.com/DotNetAge/appstore/appstoretest"
+	"github.com/DotNetAge/appstore/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// spanRecorder keeps the spans ended by a recording tracer provider, so the tests don't need the OpenTelemetry SDK
+type spanRecorder struct {
+	mu    sync.Mutex
+	ended []*recordedSpan
+}
+
+// Ended returns the spans ended so far, in order
+func (r *spanRecorder) Ended() []*recordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*recordedSpan(nil), r.ended...)
+}
+
+// recordingProvider is a tracer provider whose spans are recorded in a spanRecorder when they end
+type recordingProvider struct {
+	noop.TracerProvider
+	recorder *spanRecorder
+}
+
+func (p recordingProvider) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return recordingTracer{recorder: p.recorder}
+}
+
+type recordingTracer struct {
+	noop.Tracer
+	recorder *spanRecorder
+}
+
+func (t recordingTracer) Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	parent := trace.SpanContextFromContext(ctx)
+	config := trace.NewSpanStartConfig(options...)
+	ids := trace.SpanContextConfig{TraceID: parent.TraceID(), TraceFlags: trace.FlagsSampled}
+	if !ids.TraceID.IsValid() {
+		rand.Read(ids.TraceID[:])
+	}
+	rand.Read(ids.SpanID[:])
+	span := &recordedSpan{
+		recorder:    t.recorder,
+		name:        name,
+		parent:      parent,
+		spanContext: trace.NewSpanContext(ids),
+		attributes:  config.Attributes(),
+	}
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+// spanStatus is the status a span was given
+type spanStatus struct {
+	Code        codes.Code
+	Description string
+}
+
+// recordedSpan is a span of a recording tracer provider
+type recordedSpan struct {
+	noop.Span
+	recorder *spanRecorder
+
+	mu          sync.Mutex
+	name        string
+	parent      trace.SpanContext
+	spanContext trace.SpanContext
+	attributes  []attribute.KeyValue
+	status      spanStatus
+	ended       bool
+}
+
+func (s *recordedSpan) SpanContext() trace.SpanContext { return s.spanContext }
+
+func (s *recordedSpan) IsRecording() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.ended
+}
+
+func (s *recordedSpan) SetAttributes(attributes ...attribute.KeyValue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes = append(s.attributes, attributes...)
+}
+
+func (s *recordedSpan) SetStatus(code codes.Code, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = spanStatus{Code: code, Description: description}
+}
+
+func (s *recordedSpan) RecordError(error, ...trace.EventOption) {}
+
+func (s *recordedSpan) End(...trace.SpanEndOption) {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.mu.Unlock()
+
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.recorder.ended = append(s.recorder.ended, s)
+}
+
+func (s *recordedSpan) Name() string              { return s.name }
+func (s *recordedSpan) Parent() trace.SpanContext { return s.parent }
+
+func (s *recordedSpan) Attributes() []attribute.KeyValue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]attribute.KeyValue(nil), s.attributes...)
+}
+
+func (s *recordedSpan) Status() spanStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// newRecordingProvider returns a tracer provider that records ended spans in memory
+func newRecordingProvider() (trace.TracerProvider, *spanRecorder) {
+	recorder := &spanRecorder{}
+	return recordingProvider{recorder: recorder}, recorder
+}
+
+// spanAttributes returns the attributes of a span by key
+func spanAttributes(span *recordedSpan) map[attribute.Key]attribute.Value {
+	attributes := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attributes[kv.Key] = kv.Value
+	}
+	return attributes
+}
+
+// findSpan returns the ended span with the given name
+func findSpan(t *testing.T, recorder *spanRecorder, name string) *recordedSpan {
+	t.Helper()
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			return span
+		}
+	}
+	t.Fatalf("no span named %q", name)
+	return nil
+}
+
+func TestAPICallSpan(t *testing.T) {
+	server := appstoretest.NewServer()
+	defer server.Close()
+	transaction := server.AddTransaction("customer", models.JWSTransactionDecodedPayload{})
+
+	tests := []struct {
+		name          string
+		transactionID string
+		status        int64
+		apiError      models.APIError
+	}{
+		{name: "success", transactionID: *transaction.TransactionId, status: 200},
+		{name: "not found", transactionID: "999999", status: 404, apiError: models.APIErrorTransactionIDNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, recorder := newRecordingProvider()
+			client, err := server.NewClient(appstore.WithTracerProvider(provider))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = client.GetTransactionInfo(context.Background(), tt.transactionID)
+			if (err != nil) != (tt.apiError != 0) {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			span := findSpan(t, recorder, "AppStoreServerAPI GetTransactionInfo")
+			attributes := spanAttributes(span)
+			if got := attributes["appstore.endpoint"].AsString(); got != string(appstore.EndpointGetTransactionInfo) {
+				t.Errorf("endpoint = %q", got)
+			}
+			if got := attributes["appstore.environment"].AsString(); got != string(models.EnvironmentSandbox) {
+				t.Errorf("environment = %q", got)
+			}
+			if got := attributes["http.response.status_code"].AsInt64(); got != tt.status {
+				t.Errorf("status = %d, want %d", got, tt.status)
+			}
+
+			code, hasCode := attributes["appstore.api_error.code"]
+			if tt.apiError == 0 {
+				if hasCode {
+					t.Errorf("unexpected API error attribute %v", code)
+				}
+				if span.Status().Code == codes.Error {
+					t.Error("a successful call has an error status")
+				}
+				return
+			}
+			if code.AsInt64() != int64(tt.apiError) {
+				t.Errorf("API error code = %d, want %d", code.AsInt64(), tt.apiError)
+			}
+			if name := attributes["appstore.api_error.name"].AsString(); name != tt.apiError.String() {
+				t.Errorf("API error name = %q, want %q", name, tt.apiError.String())
+			}
+			if span.Status().Code != codes.Error {
+				t.Error("a failed call doesn't have an error status")
+			}
+		})
+	}
+}
+
+func TestVerificationSpans(t *testing.T) {
+	ca, err := appstoretest.NewCertificateAuthority()
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, recorder := newRecordingProvider()
+	verifier, err := ca.NewVerifier(models.EnvironmentSandbox, "com.example", nil, appstore.WithVerifierTracerProvider(provider))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	environment := models.EnvironmentSandbox
+	signed, err := ca.SignTransaction(models.JWSTransactionDecodedPayload{BundleId: ptr("com.example"), Environment: &environment})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.VerifyAndDecodeSignedTransaction(signed); err != nil {
+		t.Fatal(err)
+	}
+
+	parent := findSpan(t, recorder, "SignedDataVerifier.VerifyAndDecodeSignedTransaction")
+	for _, name := range []string{"SignedDataVerifier.ParseJWS", "SignedDataVerifier.VerifyChain", "SignedDataVerifier.VerifySignature"} {
+		child := findSpan(t, recorder, name)
+		if child.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("%s is not a child of the verification span", name)
+		}
+		if child.Status().Code == codes.Error {
+			t.Errorf("%s has an error status", name)
+		}
+	}
+}
+
+func TestVerificationSpansRecordFailure(t *testing.T) {
+	ca, err := appstoretest.NewCertificateAuthority()
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, recorder := newRecordingProvider()
+	verifier, err := ca.NewVerifier(models.EnvironmentSandbox, "com.example", nil, appstore.WithVerifierTracerProvider(provider))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	environment := models.EnvironmentSandbox
+	signed, err := ca.SignTransaction(models.JWSTransactionDecodedPayload{BundleId: ptr("com.example"), Environment: &environment})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = verifier.VerifyAndDecodeSignedTransaction(tamper(t, signed, "bundleId", "com.attacker"))
+	var verificationErr *appstore.VerificationException
+	if !errors.As(err, &verificationErr) {
+		t.Fatalf("got %v, want a VerificationException", err)
+	}
+
+	if span := findSpan(t, recorder, "SignedDataVerifier.VerifySignature"); span.Status().Code != codes.Error {
+		t.Error("the failed signature check doesn't have an error status")
+	}
+	span := findSpan(t, recorder, "SignedDataVerifier.VerifyAndDecodeSignedTransaction")
+	if got := spanAttributes(span)["appstore.verification.status"].AsInt64(); got != int64(appstore.VerificationStatusVerificationFailure) {
+		t.Errorf("verification status = %d", got)
+	}
+}